@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"time"
@@ -16,9 +15,13 @@ import (
 	"github.com/go-chi/render"
 	"github.com/riandyrn/otelchi"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
 
 	"go-distributed-tracing/pkg/config"
+	"go-distributed-tracing/pkg/log"
 	pkg_mongodb "go-distributed-tracing/pkg/mongodb"
+	pkg_sqldb "go-distributed-tracing/pkg/sqldb"
 	pkg_tracing "go-distributed-tracing/pkg/tracing"
 	handlers "go-distributed-tracing/todo/delivery/http"
 	repository "go-distributed-tracing/todo/repository"
@@ -27,7 +30,7 @@ import (
 	response "go-distributed-tracing/utils/response"
 )
 
-func Routes() *chi.Mux {
+func Routes(tp *trace.TracerProvider, mp metric.MeterProvider, idempotency func(http.Handler) http.Handler) *chi.Mux {
 	// Sentry
 	InitializeSentry()
 
@@ -35,11 +38,20 @@ func Routes() *chi.Mux {
 	sentryHandler := sentryhttp.New(sentryhttp.Options{})
 
 	router := chi.NewRouter()
-	router.Use(otelchi.Middleware(os.Getenv("APP_NAME"), otelchi.WithChiRoutes(router)))
+	// Route patterns are resolved before the span starts so the sampler can apply per-route rules
+	router.Use(otelchi.Middleware(
+		os.Getenv("APP_NAME"),
+		otelchi.WithChiRoutes(router),
+		otelchi.WithTracerProvider(tp),
+	))
 	router.Use(
+		pkg_tracing.TraceResponseHeaders, // Return traceparent and X-Trace-Id to clients
+		pkg_tracing.HTTPMetrics(mp),      // Record RED metrics of every route
 		sentryHandler.Handle,
 		render.SetContentType(render.ContentTypeJSON), // Set content-Type headers as application/json
-		middleware.Logger,                             // Log API request calls
+		log.RequestLogger,                             // Log API request calls with their trace context
+		handlers.Actor,                                // Record the X-Actor header in the todo history
+		idempotency,                                   // Replay the writes retried with an Idempotency-Key
 		// middleware.DefaultCompress, // Compress results, mostly gzipping assets and json
 		middleware.RedirectSlashes, // Redirect slashes to no slash URL versions
 		middleware.Recoverer,       // Recover from panics without crashing server
@@ -62,7 +74,11 @@ func InitializeSentry() {
 // PrintAllRoutes - printing all routes
 func PrintAllRoutes(router *chi.Mux) {
 	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
-		logrus.Printf("%s %s\n", method, route) // Walk and print out all routes
+		// Walk and print out all routes
+		logrus.WithFields(logrus.Fields{
+			"http.method": method,
+			"http.route":  route,
+		}).Info("route registered")
 		return nil
 	}
 	if err := chi.Walk(router, walkFunc); err != nil {
@@ -70,6 +86,67 @@ func PrintAllRoutes(router *chi.Mux) {
 	}
 }
 
+// Repositories - the repositories of the database selected by DB_DRIVER
+type Repositories struct {
+	Todo        repository.TodoRepository
+	Idempotency repository.IdempotencyRepository
+	Webhook     repository.WebhookRepository
+}
+
+// NewRepositories - create the repositories of the database selected by DB_DRIVER (mongodb, postgres, sqlite or
+// memory), and the func closing their connection
+func NewRepositories() (*Repositories, func()) {
+	switch os.Getenv("DB_DRIVER") {
+	case "memory":
+		return &Repositories{
+			Todo:        repository.NewMemoryTodoRepository(),
+			Idempotency: repository.NewMemoryIdempotencyRepository(),
+			Webhook:     repository.NewMemoryWebhookRepository(),
+		}, func() {}
+	case "postgres", "sqlite":
+		db, err := pkg_sqldb.InitSQLDB(os.Getenv("DB_DRIVER"), os.Getenv("DB_URL"), os.Getenv("DB_NAME"))
+		if err != nil {
+			logrus.Fatalf("connecting to %s: %v", os.Getenv("DB_DRIVER"), err)
+		}
+		logrus.Println("Database connected")
+
+		if err := repository.MigrateSQL(context.Background(), db); err != nil {
+			logrus.Fatalf("migrating %s: %v", os.Getenv("DB_DRIVER"), err)
+		}
+
+		return &Repositories{
+			Todo:        repository.NewSQLTodoRepository(db),
+			Idempotency: repository.NewSQLIdempotencyRepository(db),
+			Webhook:     repository.NewSQLWebhookRepository(db),
+		}, func() {
+			if err := db.Close(); err != nil {
+				logrus.Errorf("closing database: %v", err)
+			}
+		}
+	case "mongodb", "":
+		// Init MongoDB
+		_, cancel, client := pkg_mongodb.InitMongoDB()
+
+		if err := repository.MigrateMongo(context.Background(), client); err != nil {
+			logrus.Fatalf("creating mongodb indexes: %v", err)
+		}
+
+		// The todo writes store their outbox events in the same transaction
+		if err := repository.CheckMongoTransactions(context.Background(), client); err != nil {
+			logrus.Fatalf("checking mongodb: %v", err)
+		}
+
+		return &Repositories{
+			Todo:        repository.NewMongoTodoRepository(client),
+			Idempotency: repository.NewMongoIdempotencyRepository(client),
+			Webhook:     repository.NewMongoWebhookRepository(client),
+		}, cancel
+	}
+
+	logrus.Fatalf("unknown DB_DRIVER %q", os.Getenv("DB_DRIVER"))
+	return nil, nil
+}
+
 func main() {
 	utils.InitializeValidator()
 
@@ -79,18 +156,38 @@ func main() {
 		utils.CaptureError(errors.New("error loading .env file"))
 	}
 
+	// JSON logs carrying the trace context
+	log.Initialize()
+
 	tp := pkg_tracing.InitializeTracing()
 	defer func() {
 		if err := tp.Shutdown(context.Background()); err != nil {
-			log.Printf("Error shutting down tracer provider: %v", err)
+			logrus.Errorf("Error shutting down tracer provider: %v", err)
+		}
+	}()
+
+	mp, metricsHandler := pkg_tracing.InitializeMetrics()
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			logrus.Errorf("Error shutting down meter provider: %v", err)
 		}
 	}()
 
-	// Init MongoDB
-	_, cancel, client := pkg_mongodb.InitMongoDB()
-	defer cancel()
+	// Repository
+	repos, closeRepo := NewRepositories()
+	defer closeRepo()
+
+	// Idempotency keys
+	idempotencyTTL, err := handlers.IdempotencyTTLFromEnv()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	idempotencyLock, err := handlers.IdempotencyLockFromEnv()
+	if err != nil {
+		logrus.Fatal(err)
+	}
 
-	router := Routes()
+	router := Routes(tp, mp, handlers.Idempotency(repos.Idempotency, idempotencyTTL, idempotencyLock))
 
 	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		render.JSON(w, r, response.H{
@@ -100,16 +197,49 @@ func main() {
 		})
 	})
 
-	// Repository
-	todoRepo := repository.NewMongoTodoRepository(client)
+	// Prometheus metrics
+	router.Handle("/metrics", metricsHandler)
 
 	// Service
-	todoService := services.NewTodoService(todoRepo)
+	webhookConfig, err := services.WebhookConfigFromEnv()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	webhookService := services.NewWebhookService(repos.Webhook, webhookConfig)
+
+	todoService, err := services.NewInstrumentedTodoService(
+		services.NewTodoService(repos.Todo),
+		mp.Meter("TodoService"),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	// Trash purge job
+	purgeConfig, err := services.PurgeConfigFromEnv()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	go services.RunPurgeJob(context.Background(), todoService, purgeConfig)
+
+	// Outbox relay job, the webhooks get the events of the todo writes
+	outboxConfig, err := services.OutboxConfigFromEnv()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	outboxRelay := services.NewOutboxRelay(repos.Todo, outboxConfig, webhookService)
+	go services.RunOutboxJob(context.Background(), outboxRelay, outboxConfig)
+
+	// Webhook delivery job
+	go services.RunWebhookJob(context.Background(), webhookService, webhookConfig)
 
 	// Handler
 	todoHandler := handlers.NewTodoHTTPHandler(router, tp, todoService)
 	todoHandler.RegisterRoutes()
 
+	webhookHandler := handlers.NewWebhookHTTPHandler(router, tp, webhookService)
+	webhookHandler.RegisterRoutes()
+
 	// Print
 	PrintAllRoutes(router)
 
@@ -0,0 +1,86 @@
+package jsonpatch_test
+
+import (
+	"testing"
+
+	"go-distributed-tracing/pkg/jsonpatch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		result, err := jsonpatch.MergePatch(
+			[]byte(`{"a":"b","c":{"d":"e","f":"g"},"tags":["x"]}`),
+			[]byte(`{"a":"z","c":{"f":null},"tags":["y","z"]}`),
+		)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":"z","c":{"d":"e"},"tags":["y","z"]}`, string(result))
+	})
+
+	t.Run("success-replace-non-object", func(t *testing.T) {
+		result, err := jsonpatch.MergePatch([]byte(`{"a":"b"}`), []byte(`{"a":{"b":null,"c":1}}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":{"c":1}}`, string(result))
+	})
+
+	t.Run("error-invalid-patch", func(t *testing.T) {
+		_, err := jsonpatch.MergePatch([]byte(`{}`), []byte(`{`))
+		assert.ErrorIs(t, err, jsonpatch.ErrInvalidPatch)
+	})
+}
+
+func TestApply(t *testing.T) {
+	doc := []byte(`{"title":"a","tags":["x","y"],"nested":{"a/b":1,"m~n":2}}`)
+
+	tests := []struct {
+		name   string
+		patch  string
+		result string
+	}{
+		{"add", `[{"op":"add","path":"/description","value":"d"}]`, `{"title":"a","description":"d","tags":["x","y"],"nested":{"a/b":1,"m~n":2}}`},
+		{"add-array", `[{"op":"add","path":"/tags/1","value":"z"}]`, `{"title":"a","tags":["x","z","y"],"nested":{"a/b":1,"m~n":2}}`},
+		{"add-array-end", `[{"op":"add","path":"/tags/-","value":"z"}]`, `{"title":"a","tags":["x","y","z"],"nested":{"a/b":1,"m~n":2}}`},
+		{"remove", `[{"op":"remove","path":"/tags/0"}]`, `{"title":"a","tags":["y"],"nested":{"a/b":1,"m~n":2}}`},
+		{"replace-escaped", `[{"op":"replace","path":"/nested/a~1b","value":3},{"op":"replace","path":"/nested/m~0n","value":null}]`, `{"title":"a","tags":["x","y"],"nested":{"a/b":3,"m~n":null}}`},
+		{"move", `[{"op":"move","from":"/title","path":"/description"}]`, `{"description":"a","tags":["x","y"],"nested":{"a/b":1,"m~n":2}}`},
+		{"copy", `[{"op":"copy","from":"/tags","path":"/labels"},{"op":"add","path":"/labels/-","value":"z"}]`, `{"title":"a","tags":["x","y"],"labels":["x","y","z"],"nested":{"a/b":1,"m~n":2}}`},
+		{"test", `[{"op":"test","path":"/tags","value":["x","y"]},{"op":"replace","path":"/title","value":"b"}]`, `{"title":"b","tags":["x","y"],"nested":{"a/b":1,"m~n":2}}`},
+	}
+	for _, tc := range tests {
+		t.Run("success-"+tc.name, func(t *testing.T) {
+			result, err := jsonpatch.Apply(doc, []byte(tc.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.result, string(result))
+		})
+	}
+
+	t.Run("error-test-failed", func(t *testing.T) {
+		_, err := jsonpatch.Apply(doc, []byte(`[{"op":"replace","path":"/title","value":"b"},{"op":"test","path":"/title","value":"a"}]`))
+		assert.ErrorIs(t, err, jsonpatch.ErrTestFailed)
+	})
+
+	errors := []struct {
+		name  string
+		patch string
+		err   error
+	}{
+		{"invalid-json", `{"op":"add"}`, jsonpatch.ErrInvalidPatch},
+		{"unknown-op", `[{"op":"upsert","path":"/title","value":"b"}]`, jsonpatch.ErrInvalidPatch},
+		{"missing-value", `[{"op":"add","path":"/title"}]`, jsonpatch.ErrInvalidPatch},
+		{"invalid-pointer", `[{"op":"remove","path":"title"}]`, jsonpatch.ErrInvalidPatch},
+		{"move-into-child", `[{"op":"move","from":"/nested","path":"/nested/child"}]`, jsonpatch.ErrInvalidPatch},
+		{"remove-missing", `[{"op":"remove","path":"/description"}]`, jsonpatch.ErrPath},
+		{"replace-missing", `[{"op":"replace","path":"/description","value":"d"}]`, jsonpatch.ErrPath},
+		{"add-missing-parent", `[{"op":"add","path":"/missing/title","value":"d"}]`, jsonpatch.ErrPath},
+		{"array-out-of-range", `[{"op":"add","path":"/tags/3","value":"z"}]`, jsonpatch.ErrPath},
+		{"array-leading-zero", `[{"op":"remove","path":"/tags/01"}]`, jsonpatch.ErrPath},
+	}
+	for _, tc := range errors {
+		t.Run("error-"+tc.name, func(t *testing.T) {
+			_, err := jsonpatch.Apply(doc, []byte(tc.patch))
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Log fields added by TraceHook
+const (
+	TraceIDKey     = "trace_id"
+	SpanIDKey      = "span_id"
+	ServiceNameKey = "service.name"
+)
+
+// TraceHook - logrus hook adding the service name and the trace context of the entry
+type TraceHook struct {
+	ServiceName string
+}
+
+// Levels - implements logrus.Hook
+func (h *TraceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - implements logrus.Hook
+func (h *TraceHook) Fire(entry *logrus.Entry) error {
+	if h.ServiceName != "" {
+		entry.Data[ServiceNameKey] = h.ServiceName
+	}
+
+	if entry.Context == nil {
+		return nil
+	}
+
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if spanContext.IsValid() {
+		entry.Data[TraceIDKey] = spanContext.TraceID().String()
+		entry.Data[SpanIDKey] = spanContext.SpanID().String()
+	}
+
+	return nil
+}
+
+// Configure - make logger emit JSON carrying the trace context
+func Configure(logger *logrus.Logger, serviceName string) {
+	logger.SetFormatter(&logrus.JSONFormatter{
+		TimestampFormat: time.RFC3339Nano,
+	})
+	logger.AddHook(&TraceHook{ServiceName: serviceName})
+}
+
+// Initialize - configure the standard logrus logger
+func Initialize() {
+	Configure(logrus.StandardLogger(), os.Getenv("APP_NAME"))
+}
+
+// FromContext - logger entry that carries the trace context of ctx
+func FromContext(ctx context.Context) *logrus.Entry {
+	return logrus.WithContext(ctx)
+}
+
+// RequestLogger - chi middleware logging every request with its trace context
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+
+		entry := FromContext(r.Context()).WithFields(logrus.Fields{
+			"http.method":      r.Method,
+			"http.route":       route,
+			"http.target":      r.URL.RequestURI(),
+			"http.status_code": status,
+			"http.user_agent":  r.UserAgent(),
+			"net.peer.addr":    r.RemoteAddr,
+			"bytes":            ww.BytesWritten(),
+			"duration_ms":      float64(time.Since(start)) / float64(time.Millisecond),
+		})
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Error("request completed")
+		case status >= http.StatusBadRequest:
+			entry.Warn("request completed")
+		default:
+			entry.Info("request completed")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-distributed-tracing/pkg/log"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// decode - decode the last JSON log line written to buf
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+
+	entry := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &entry))
+
+	return entry
+}
+
+func TestTraceHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	log.Configure(logger, "todo-api")
+
+	t.Run("with span", func(t *testing.T) {
+		ctx, span := trace.NewTracerProvider().Tracer("log_test").Start(context.Background(), "log.test")
+		defer span.End()
+
+		logger.WithContext(ctx).Error("failed")
+
+		entry := decode(t, &buf)
+		assert.Equal(t, "failed", entry["msg"])
+		assert.Equal(t, "error", entry["level"])
+		assert.Equal(t, "todo-api", entry[log.ServiceNameKey])
+		assert.Equal(t, span.SpanContext().TraceID().String(), entry[log.TraceIDKey])
+		assert.Equal(t, span.SpanContext().SpanID().String(), entry[log.SpanIDKey])
+	})
+
+	t.Run("without span", func(t *testing.T) {
+		logger.WithContext(context.Background()).Info("plain")
+
+		entry := decode(t, &buf)
+		assert.Equal(t, "todo-api", entry[log.ServiceNameKey])
+		assert.NotContains(t, entry, log.TraceIDKey)
+		assert.NotContains(t, entry, log.SpanIDKey)
+	})
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	log.Configure(logrus.StandardLogger(), "todo-api")
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := trace.NewTracerProvider().Tracer("log_test").Start(r.Context(), "GET /todo/{id}")
+			defer span.End()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	router.Use(log.RequestLogger)
+	router.Get("/todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Info("handler")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/todo/1", nil))
+
+	entry := decode(t, &buf)
+	assert.Equal(t, "request completed", entry["msg"])
+	assert.Equal(t, "warning", entry["level"])
+	assert.Equal(t, "/todo/{id}", entry["http.route"])
+	assert.Equal(t, float64(http.StatusNotFound), entry["http.status_code"])
+	assert.NotEmpty(t, entry[log.TraceIDKey])
+}
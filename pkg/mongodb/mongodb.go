@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/metric/global"
 )
 
 // InitMongoDB - initialize mongo
@@ -21,7 +22,13 @@ func InitMongoDB() (context.Context, func(), *mongo.Client) {
 	opts := options.Client()
 
 	// Mongo OpenTelemetry instrumentation
-	opts.Monitor = otelmongo.NewMonitor()
+	monitor, err := NewMonitor(global.Meter("go-distributed-tracing/mongodb"))
+	if err != nil {
+		// Without the metrics the commands are still traced
+		utils.CaptureError(err)
+		monitor = otelmongo.NewMonitor()
+	}
+	opts.Monitor = monitor
 	opts.ApplyURI(os.Getenv("DB_URL"))
 
 	client, err := mongo.NewClient(opts)
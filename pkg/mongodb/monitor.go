@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+type commandKey struct {
+	connectionID string
+	requestID    int64
+}
+
+type commandMonitor struct {
+	tracing  *event.CommandMonitor
+	metrics  *pkg_tracing.OperationMetrics
+	commands sync.Map
+}
+
+// NewMonitor - command monitor creating otelmongo spans and recording count, error count and latency of every command
+func NewMonitor(meter metric.Meter) (*event.CommandMonitor, error) {
+	metrics, err := pkg_tracing.NewOperationMetrics(meter, "db.mongodb")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &commandMonitor{
+		tracing: otelmongo.NewMonitor(),
+		metrics: metrics,
+	}
+
+	return &event.CommandMonitor{
+		Started:   m.Started,
+		Succeeded: m.Succeeded,
+		Failed:    m.Failed,
+	}, nil
+}
+
+// Started - remember command attributes until it finishes
+func (m *commandMonitor) Started(ctx context.Context, evt *event.CommandStartedEvent) {
+	m.tracing.Started(ctx, evt)
+
+	attrs := []attribute.KeyValue{
+		semconv.DBOperationKey.String(evt.CommandName),
+	}
+	if collection := commandCollection(evt); collection != "" {
+		attrs = append(attrs, semconv.DBMongoDBCollectionKey.String(collection))
+	}
+
+	m.commands.Store(commandKey{connectionID: evt.ConnectionID, requestID: evt.RequestID}, attrs)
+}
+
+// Succeeded - record a successful command
+func (m *commandMonitor) Succeeded(ctx context.Context, evt *event.CommandSucceededEvent) {
+	m.tracing.Succeeded(ctx, evt)
+	m.finished(ctx, &evt.CommandFinishedEvent, nil)
+}
+
+// Failed - record a failed command
+func (m *commandMonitor) Failed(ctx context.Context, evt *event.CommandFailedEvent) {
+	m.tracing.Failed(ctx, evt)
+	m.finished(ctx, &evt.CommandFinishedEvent, errors.New(evt.Failure))
+}
+
+func (m *commandMonitor) finished(ctx context.Context, evt *event.CommandFinishedEvent, err error) {
+	key := commandKey{connectionID: evt.ConnectionID, requestID: evt.RequestID}
+
+	attrs := []attribute.KeyValue{semconv.DBOperationKey.String(evt.CommandName)}
+	if value, ok := m.commands.LoadAndDelete(key); ok {
+		attrs = value.([]attribute.KeyValue)
+	}
+
+	start := time.Now().Add(-time.Duration(evt.DurationNanos))
+	m.metrics.Record(ctx, start, err, attrs...)
+}
+
+// commandCollection - collection name of CRUD commands, e.g. {"find": "todo", ...}
+func commandCollection(evt *event.CommandStartedEvent) string {
+	elem, err := evt.Command.IndexErr(0)
+	if err != nil || elem.Key() != evt.CommandName {
+		return ""
+	}
+
+	value := elem.Value()
+	if value.Type != bsontype.String {
+		return ""
+	}
+
+	return value.StringValue()
+}
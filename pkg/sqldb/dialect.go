@@ -0,0 +1,87 @@
+package sqldb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Dialect - the differences between the supported SQL databases
+type Dialect struct {
+	// DriverName - database/sql driver name
+	DriverName string
+	// System - db.system attribute of the spans
+	System attribute.KeyValue
+	// ILike - case-insensitive LIKE operator, SQLite LIKE only folds ASCII
+	ILike string
+	// Timestamp - column type of timestamps with time zone
+	Timestamp string
+	// Binary - collation clause comparing text byte by byte, SQLite does by default
+	Binary string
+	// ForUpdate - clause locking the selected rows until the transaction ends, SQLite locks the whole database
+	ForUpdate string
+	// numbered - placeholders are $1, $2, ... instead of ?
+	numbered bool
+}
+
+var (
+	// Postgres - PostgreSQL through lib/pq
+	Postgres = Dialect{
+		DriverName: "postgres",
+		System:     semconv.DBSystemPostgreSQL,
+		ILike:      "ILIKE",
+		Timestamp:  "TIMESTAMPTZ",
+		Binary:     ` COLLATE "C"`,
+		ForUpdate:  " FOR UPDATE",
+		numbered:   true,
+	}
+
+	// SQLite - SQLite through mattn/go-sqlite3
+	SQLite = Dialect{
+		DriverName: "sqlite3",
+		System:     semconv.DBSystemSqlite,
+		ILike:      "LIKE",
+		Timestamp:  "TIMESTAMP",
+	}
+)
+
+// DialectFor - dialect of a DB_DRIVER value, "postgres" or "sqlite"
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case "postgres", "postgresql":
+		return Postgres, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	}
+
+	return Dialect{}, fmt.Errorf("unknown SQL driver %q", driver)
+}
+
+// Rebind - rewrite ? placeholders into the dialect placeholders
+func (d Dialect) Rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return b.String()
+}
+
+// EscapeLike - escape the LIKE wildcards of value, use with ESCAPE '\'
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
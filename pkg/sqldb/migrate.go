@@ -0,0 +1,96 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Migration - one schema change, applied once and in Version order
+type Migration struct {
+	Version     int
+	Description string
+	// Statements - the DDL of the change in the given dialect
+	Statements func(dialect Dialect) []string
+}
+
+// Migrate - apply the migrations missing from the schema_migrations table,
+// each one in its own transaction together with its schema_migrations row
+func (db *DB) Migrate(ctx context.Context, migrations []Migration) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at %s NOT NULL
+	)`, db.Dialect.Timestamp))
+	if err != nil {
+		return err
+	}
+
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Version < pending[j].Version
+	})
+
+	for _, migration := range pending {
+		if err := db.migrate(ctx, migration); err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Description, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) appliedMigrations(ctx context.Context) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+func (db *DB) migrate(ctx context.Context, migration Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range migration.Statements(db.Dialect) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	// A concurrent migrator fails here on the primary key and rolls back
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)",
+		migration.Version, migration.Description, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
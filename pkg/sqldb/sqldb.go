@@ -0,0 +1,266 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Queryer - the statements shared by DB and Tx
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// DB - database/sql handle creating a span and recording metrics for every statement,
+// the same way otelmongo and the mongodb command monitor do for MongoDB
+type DB struct {
+	Dialect Dialect
+	db      *sql.DB
+	name    string
+	tracer  trace.Tracer
+	metrics *pkg_tracing.OperationMetrics
+}
+
+// Tx - transaction of a DB, traced like the DB
+type Tx struct {
+	db *DB
+	tx *sql.Tx
+}
+
+// Open - open a database, name is the db.name attribute of the spans
+func Open(dialect Dialect, dsn string, name string, meter metric.Meter) (*DB, error) {
+	metrics, err := pkg_tracing.NewOperationMetrics(meter, "db.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(dialect.DriverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, serialize instead of failing with "database is locked"
+	if dialect.DriverName == SQLite.DriverName {
+		db.SetMaxOpenConns(1)
+	}
+
+	return &DB{
+		Dialect: dialect,
+		db:      db,
+		name:    name,
+		tracer:  otel.Tracer("go-distributed-tracing/sqldb"),
+		metrics: metrics,
+	}, nil
+}
+
+// InitSQLDB - open and ping the database of DB_DRIVER and DB_URL
+func InitSQLDB(driver string, dsn string, name string) (*DB, error) {
+	dialect, err := DialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := Open(dialect, dsn, name, global.Meter("go-distributed-tracing/sqldb"))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Close - close the database
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+// ExecContext - execute a statement without rows
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.exec(ctx, db.db, query, args)
+}
+
+// QueryContext - execute a statement returning rows
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.query(ctx, db.db, query, args)
+}
+
+// QueryRowContext - execute a statement returning at most one row
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.queryRow(ctx, db.db, query, args)
+}
+
+// BeginTx - start a transaction
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{db: db, tx: tx}, nil
+}
+
+// ExecContext - execute a statement without rows
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.db.exec(ctx, tx.tx, query, args)
+}
+
+// QueryContext - execute a statement returning rows
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.db.query(ctx, tx.tx, query, args)
+}
+
+// QueryRowContext - execute a statement returning at most one row
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.db.queryRow(ctx, tx.tx, query, args)
+}
+
+// Commit - commit the transaction
+func (tx *Tx) Commit() error {
+	return tx.tx.Commit()
+}
+
+// Rollback - abort the transaction
+func (tx *Tx) Rollback() error {
+	return tx.tx.Rollback()
+}
+
+// utcArgs - timestamps in UTC, SQLite compares them as text so they must share one offset
+func utcArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case time.Time:
+			args[i] = value.UTC()
+		case sql.NullTime:
+			value.Time = value.Time.UTC()
+			args[i] = value
+		}
+	}
+
+	return args
+}
+
+func (db *DB) exec(ctx context.Context, q Queryer, query string, args []interface{}) (sql.Result, error) {
+	query = db.Dialect.Rebind(query)
+	args = utcArgs(args)
+	ctx, finish := db.start(ctx, query)
+	res, err := q.ExecContext(ctx, query, args...)
+	finish(err)
+
+	return res, err
+}
+
+func (db *DB) query(ctx context.Context, q Queryer, query string, args []interface{}) (*sql.Rows, error) {
+	query = db.Dialect.Rebind(query)
+	args = utcArgs(args)
+	ctx, finish := db.start(ctx, query)
+	rows, err := q.QueryContext(ctx, query, args...)
+	finish(err)
+
+	return rows, err
+}
+
+func (db *DB) queryRow(ctx context.Context, q Queryer, query string, args []interface{}) *sql.Row {
+	query = db.Dialect.Rebind(query)
+	args = utcArgs(args)
+	ctx, finish := db.start(ctx, query)
+	row := q.QueryRowContext(ctx, query, args...)
+
+	// No rows is an answer, not a failure
+	err := row.Err()
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	finish(err)
+
+	return row
+}
+
+// start - start the span of a statement, named <table>.<operation> like the otelmongo spans
+func (db *DB) start(ctx context.Context, query string) (context.Context, func(err error)) {
+	operation, table := describe(query)
+
+	name := operation
+	attrs := []attribute.KeyValue{
+		db.Dialect.System,
+		semconv.DBOperationKey.String(operation),
+	}
+	if table != "" {
+		name = table + "." + operation
+		attrs = append(attrs, semconv.DBSQLTableKey.String(table))
+	}
+
+	start := time.Now()
+	ctx, span := db.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(
+			semconv.DBNameKey.String(db.name),
+			semconv.DBStatementKey.String(query),
+		),
+	)
+
+	return ctx, func(err error) {
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+		}
+		span.End()
+
+		db.metrics.Record(ctx, start, err, attrs...)
+	}
+}
+
+var tablePattern = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|TABLE(?:\s+IF\s+(?:NOT\s+)?EXISTS)?)\s+"?(\w+)`)
+
+// describe - operation and table of a statement, e.g. ("select", "todo")
+func describe(query string) (string, string) {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	operation := strings.ToLower(fields[0])
+	if match := tablePattern.FindStringSubmatch(query); match != nil {
+		return operation, match[1]
+	}
+
+	return operation, ""
+}
+
+// IsUniqueViolation - err is a primary key or unique constraint violation
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+
+	return false
+}
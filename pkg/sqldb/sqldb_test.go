@@ -0,0 +1,144 @@
+package sqldb_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	pkg_sqldb "go-distributed-tracing/pkg/sqldb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func openSQLite(t *testing.T) *pkg_sqldb.DB {
+	t.Helper()
+
+	db, err := pkg_sqldb.Open(pkg_sqldb.SQLite, filepath.Join(t.TempDir(), "test.db"), "test", global.Meter("test"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+func TestDialect(t *testing.T) {
+	t.Run("DialectFor", func(t *testing.T) {
+		dialect, err := pkg_sqldb.DialectFor("postgres")
+		assert.NoError(t, err)
+		assert.Equal(t, "postgres", dialect.DriverName)
+
+		dialect, err = pkg_sqldb.DialectFor("sqlite")
+		assert.NoError(t, err)
+		assert.Equal(t, "sqlite3", dialect.DriverName)
+
+		_, err = pkg_sqldb.DialectFor("oracle")
+		assert.Error(t, err)
+	})
+
+	t.Run("Rebind", func(t *testing.T) {
+		query := "SELECT * FROM todo WHERE id = ? AND title LIKE ?"
+		assert.Equal(t, "SELECT * FROM todo WHERE id = $1 AND title LIKE $2", pkg_sqldb.Postgres.Rebind(query))
+		assert.Equal(t, query, pkg_sqldb.SQLite.Rebind(query))
+	})
+
+	t.Run("EscapeLike", func(t *testing.T) {
+		assert.Equal(t, `100\% done\_ok\\`, pkg_sqldb.EscapeLike(`100% done_ok\`))
+	})
+}
+
+func TestDBSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(trace.NewTracerProvider(trace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(oteltrace.NewNoopTracerProvider())
+	})
+
+	db := openSQLite(t)
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "CREATE TABLE todo (id TEXT PRIMARY KEY)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO todo (id) VALUES (?)", "1")
+	require.NoError(t, err)
+
+	var id string
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT id FROM todo WHERE id = ?", "1").Scan(&id))
+
+	_, err = db.ExecContext(ctx, "INSERT INTO todo (id) VALUES (?)", "1")
+	require.Error(t, err)
+	assert.True(t, pkg_sqldb.IsUniqueViolation(err))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+		assert.Equal(t, oteltrace.SpanKindClient, span.SpanKind())
+		assert.Contains(t, span.Attributes(), semconv.DBSystemSqlite)
+		assert.Contains(t, span.Attributes(), semconv.DBSQLTableKey.String("todo"))
+		assert.Contains(t, span.Attributes(), semconv.DBNameKey.String("test"))
+	}
+	assert.Equal(t, []string{"todo.create", "todo.insert", "todo.select", "todo.insert"}, names)
+
+	assert.Contains(t, spans[2].Attributes(), attribute.String("db.statement", "SELECT id FROM todo WHERE id = ?"))
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+}
+
+func TestMigrate(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+
+	migrations := []pkg_sqldb.Migration{
+		{
+			Version:     2,
+			Description: "add title",
+			Statements: func(dialect pkg_sqldb.Dialect) []string {
+				return []string{"ALTER TABLE todo ADD COLUMN title TEXT"}
+			},
+		},
+		{
+			Version:     1,
+			Description: "create todo",
+			Statements: func(dialect pkg_sqldb.Dialect) []string {
+				return []string{"CREATE TABLE todo (id TEXT PRIMARY KEY, created_at " + dialect.Timestamp + ")"}
+			},
+		},
+	}
+
+	// Applied in version order, then skipped
+	require.NoError(t, db.Migrate(ctx, migrations))
+	require.NoError(t, db.Migrate(ctx, migrations))
+
+	_, err := db.ExecContext(ctx, "INSERT INTO todo (id, title) VALUES (?, ?)", "1", "title")
+	assert.NoError(t, err)
+
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		err := db.Migrate(ctx, append(migrations, pkg_sqldb.Migration{
+			Version:     3,
+			Description: "broken",
+			Statements: func(dialect pkg_sqldb.Dialect) []string {
+				return []string{"CREATE TABLE done (id TEXT)", "NOT SQL"}
+			},
+		}))
+		assert.ErrorContains(t, err, "migration 3 broken")
+
+		var versions int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&versions))
+		assert.Equal(t, 2, versions)
+
+		_, err = db.ExecContext(ctx, "SELECT * FROM done")
+		assert.Error(t, err)
+	})
+}
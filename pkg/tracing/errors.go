@@ -0,0 +1,61 @@
+package pkg_tracing
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorTypeKey - span attribute holding the error classification
+const ErrorTypeKey = attribute.Key("error.type")
+
+// Error classifications attached to spans
+const (
+	ErrorClassValidation = "validation"
+	ErrorClassBadRequest = "bad_request"
+	ErrorClassNotFound   = "not_found"
+	ErrorClassConflict   = "conflict"
+	ErrorClassInternal   = "internal"
+)
+
+// classifiedError - error that knows its own classification, e.g. domain errors
+type classifiedError interface {
+	error
+	ErrorClass() string
+}
+
+// ClassifyError - classification of an error returned by a service or repository
+func ClassifyError(err error) string {
+	var classified classifiedError
+	var validationErrors validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &classified):
+		return classified.ErrorClass()
+	case errors.As(err, &validationErrors):
+		return ErrorClassValidation
+	}
+
+	return ErrorClassInternal
+}
+
+// RecordError - record err on span, mark the span as failed and attach its classification
+func RecordError(span trace.Span, err error) {
+	recordError(span, err, ClassifyError(err))
+}
+
+// RecordHTTPError - record err on a handler span together with the response status code
+func RecordHTTPError(span trace.Span, err error, statusCode int, class string) {
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(statusCode))
+	recordError(span, err, class)
+}
+
+func recordError(span trace.Span, err error, class string) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(ErrorTypeKey.String(class))
+}
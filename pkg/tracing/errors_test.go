@@ -0,0 +1,74 @@
+package pkg_tracing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/utils"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// attributeValue - value of key in the span attributes
+func attributeValue(span trace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+type classified struct{}
+
+func (classified) Error() string      { return "conflict" }
+func (classified) ErrorClass() string { return pkg_tracing.ErrorClassConflict }
+
+func TestClassifyError(t *testing.T) {
+	validationErr := utils.ValidateStruct(&struct {
+		Title string `validate:"required"`
+	}{})
+
+	assert.Equal(t, pkg_tracing.ErrorClassValidation, pkg_tracing.ClassifyError(validationErr))
+	assert.Equal(t, pkg_tracing.ErrorClassConflict, pkg_tracing.ClassifyError(classified{}))
+	assert.Equal(t, pkg_tracing.ErrorClassConflict, pkg_tracing.ClassifyError(fmt.Errorf("wrapped: %w", classified{})))
+	assert.Equal(t, pkg_tracing.ErrorClassInternal, pkg_tracing.ClassifyError(errors.New("connection refused")))
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := trace.NewTracerProvider(trace.WithSpanProcessor(recorder)).Tracer("errors_test")
+
+	t.Run("service span", func(t *testing.T) {
+		_, span := tracer.Start(context.Background(), "TodoService.GetByID")
+		pkg_tracing.RecordError(span, fmt.Errorf("find: %w", classified{}))
+		span.End()
+
+		ended := recorder.Ended()[len(recorder.Ended())-1]
+		assert.Equal(t, codes.Error, ended.Status().Code)
+		assert.Equal(t, "find: conflict", ended.Status().Description)
+		assert.Equal(t, pkg_tracing.ErrorClassConflict, attributeValue(ended, pkg_tracing.ErrorTypeKey).AsString())
+		assert.Len(t, ended.Events(), 1)
+	})
+
+	t.Run("handler span", func(t *testing.T) {
+		_, span := tracer.Start(context.Background(), "todoHandler.Create")
+		pkg_tracing.RecordHTTPError(span, errors.New("EOF"), http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+		span.End()
+
+		ended := recorder.Ended()[len(recorder.Ended())-1]
+		assert.Equal(t, codes.Error, ended.Status().Code)
+		assert.Equal(t, int64(http.StatusBadRequest), attributeValue(ended, semconv.HTTPStatusCodeKey).AsInt64())
+		assert.Equal(t, pkg_tracing.ErrorClassBadRequest, attributeValue(ended, pkg_tracing.ErrorTypeKey).AsString())
+	})
+}
@@ -0,0 +1,151 @@
+package pkg_tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Span exporters that can be selected with TRACER_EXPORTER
+const (
+	ExporterOTLPGRPC    = "otlp-grpc"
+	ExporterOTLPHTTP    = "otlp-http"
+	ExporterJaeger      = "jaeger"
+	ExporterJaegerAgent = "jaeger-agent"
+	ExporterStdout      = "stdout"
+	ExporterNone        = "none"
+)
+
+// ExporterConfig - span exporter configuration
+type ExporterConfig struct {
+	// Name is one of the Exporter* constants, empty means jaeger
+	Name string
+	// Endpoint is the collector address, either host:port or a full URL
+	Endpoint string
+	// Insecure disables TLS for the OTLP exporters
+	Insecure bool
+	// Headers are sent with every OTLP export request
+	Headers map[string]string
+	// AgentHost and AgentPort address the Jaeger agent (UDP)
+	AgentHost string
+	AgentPort string
+	// Writer receives the stdout exporter output, defaults to os.Stdout
+	Writer io.Writer
+}
+
+// ExporterConfigFromEnv - read span exporter configuration from environment
+func ExporterConfigFromEnv() ExporterConfig {
+	return ExporterConfig{
+		Name:      os.Getenv("TRACER_EXPORTER"),
+		Endpoint:  os.Getenv("TRACER_PROVIDER_URL"),
+		Insecure:  os.Getenv("TRACER_INSECURE") == "true",
+		Headers:   parseHeaders(os.Getenv("TRACER_HEADERS")),
+		AgentHost: os.Getenv("TRACER_AGENT_HOST"),
+		AgentPort: os.Getenv("TRACER_AGENT_PORT"),
+	}
+}
+
+// NewExporter - create the span exporter selected by config.
+// It returns a nil exporter when tracing export is disabled ("none").
+func NewExporter(ctx context.Context, cfg ExporterConfig) (trace.SpanExporter, error) {
+	switch strings.ToLower(cfg.Name) {
+	case ExporterOTLPGRPC:
+		endpoint, _, insecure := splitEndpoint(cfg.Endpoint, cfg.Insecure)
+		opts := []otlptracegrpc.Option{}
+		if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterOTLPHTTP:
+		endpoint, path, insecure := splitEndpoint(cfg.Endpoint, cfg.Insecure)
+		opts := []otlptracehttp.Option{}
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+		}
+		if path != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(path))
+		}
+		if insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterJaeger, "":
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.Endpoint)))
+	case ExporterJaegerAgent:
+		opts := []jaeger.AgentEndpointOption{}
+		if cfg.AgentHost != "" {
+			opts = append(opts, jaeger.WithAgentHost(cfg.AgentHost))
+		}
+		if cfg.AgentPort != "" {
+			opts = append(opts, jaeger.WithAgentPort(cfg.AgentPort))
+		}
+
+		return jaeger.New(jaeger.WithAgentEndpoint(opts...))
+	case ExporterStdout:
+		writer := cfg.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+
+		return stdouttrace.New(stdouttrace.WithWriter(writer), stdouttrace.WithPrettyPrint())
+	case ExporterNone:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown tracer exporter %q", cfg.Name)
+}
+
+// splitEndpoint - split endpoint into host:port and url path.
+// An http:// scheme turns the connection into an insecure one.
+func splitEndpoint(endpoint string, insecure bool) (string, string, bool) {
+	if !strings.Contains(endpoint, "://") {
+		return endpoint, "", insecure
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint, "", insecure
+	}
+
+	path := u.Path
+	if path == "/" {
+		path = ""
+	}
+
+	return u.Host, path, insecure || u.Scheme == "http"
+}
+
+// parseHeaders - parse "key=value,key2=value2" into a map
+func parseHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+
+	return headers
+}
@@ -0,0 +1,191 @@
+package pkg_tracing_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// exportSpan - send one span named "exporter.test" through exp
+func exportSpan(t *testing.T, exp trace.SpanExporter) {
+	tp := trace.NewTracerProvider(trace.WithSyncer(exp))
+	_, span := tp.Tracer("exporter_test").Start(context.Background(), "exporter.test")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, tp.Shutdown(ctx))
+}
+
+type traceReceiver struct {
+	collectortrace.UnimplementedTraceServiceServer
+	requests chan *collectortrace.ExportTraceServiceRequest
+}
+
+func (r *traceReceiver) Export(ctx context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	r.requests <- req
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+func spanName(req *collectortrace.ExportTraceServiceRequest) string {
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				return span.GetName()
+			}
+		}
+	}
+
+	return ""
+}
+
+func TestNewExporter(t *testing.T) {
+	t.Run("otlp grpc", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		receiver := &traceReceiver{requests: make(chan *collectortrace.ExportTraceServiceRequest, 1)}
+		server := grpc.NewServer()
+		collectortrace.RegisterTraceServiceServer(server, receiver)
+		go server.Serve(listener)
+		defer server.Stop()
+
+		exp, err := pkg_tracing.NewExporter(context.Background(), pkg_tracing.ExporterConfig{
+			Name:     pkg_tracing.ExporterOTLPGRPC,
+			Endpoint: listener.Addr().String(),
+			Insecure: true,
+		})
+		require.NoError(t, err)
+		exportSpan(t, exp)
+
+		select {
+		case req := <-receiver.requests:
+			assert.Equal(t, "exporter.test", spanName(req))
+		case <-time.After(5 * time.Second):
+			t.Fatal("no spans received")
+		}
+	})
+
+	t.Run("otlp http", func(t *testing.T) {
+		requests := make(chan *collectortrace.ExportTraceServiceRequest, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/custom/traces", r.URL.Path)
+			assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+
+			body, _ := io.ReadAll(r.Body)
+			req := &collectortrace.ExportTraceServiceRequest{}
+			assert.NoError(t, proto.Unmarshal(body, req))
+			requests <- req
+
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		exp, err := pkg_tracing.NewExporter(context.Background(), pkg_tracing.ExporterConfig{
+			Name:     pkg_tracing.ExporterOTLPHTTP,
+			Endpoint: server.URL + "/custom/traces",
+			Headers:  map[string]string{"X-Api-Key": "secret"},
+		})
+		require.NoError(t, err)
+		exportSpan(t, exp)
+
+		select {
+		case req := <-requests:
+			assert.Equal(t, "exporter.test", spanName(req))
+		case <-time.After(5 * time.Second):
+			t.Fatal("no spans received")
+		}
+	})
+
+	t.Run("jaeger collector", func(t *testing.T) {
+		received := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received <- string(body)
+		}))
+		defer server.Close()
+
+		exp, err := pkg_tracing.NewExporter(context.Background(), pkg_tracing.ExporterConfig{
+			Name:     pkg_tracing.ExporterJaeger,
+			Endpoint: server.URL + "/api/traces",
+		})
+		require.NoError(t, err)
+		exportSpan(t, exp)
+
+		select {
+		case body := <-received:
+			assert.Contains(t, body, "exporter.test")
+		case <-time.After(5 * time.Second):
+			t.Fatal("no spans received")
+		}
+	})
+
+	t.Run("jaeger agent", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		host, port, err := net.SplitHostPort(conn.LocalAddr().String())
+		require.NoError(t, err)
+
+		exp, err := pkg_tracing.NewExporter(context.Background(), pkg_tracing.ExporterConfig{
+			Name:      pkg_tracing.ExporterJaegerAgent,
+			AgentHost: host,
+			AgentPort: port,
+		})
+		require.NoError(t, err)
+		exportSpan(t, exp)
+
+		buf := make([]byte, 65000)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Contains(t, string(buf[:n]), "exporter.test")
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		exp, err := pkg_tracing.NewExporter(context.Background(), pkg_tracing.ExporterConfig{
+			Name:   pkg_tracing.ExporterStdout,
+			Writer: &buf,
+		})
+		require.NoError(t, err)
+		exportSpan(t, exp)
+
+		assert.Contains(t, buf.String(), `"Name": "exporter.test"`)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		exp, err := pkg_tracing.NewExporter(context.Background(), pkg_tracing.ExporterConfig{
+			Name: pkg_tracing.ExporterNone,
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, exp)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		exp, err := pkg_tracing.NewExporter(context.Background(), pkg_tracing.ExporterConfig{
+			Name: "zipkin",
+		})
+
+		assert.Error(t, err)
+		assert.Nil(t, exp)
+	})
+}
@@ -0,0 +1,179 @@
+package pkg_tracing
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// MeterProvider - create meter provider exporting to the Prometheus registry
+func MeterProvider(registerer prometheus.Registerer) (*sdkmetric.MeterProvider, error) {
+	res, err := newResource()
+	if err != nil {
+		return nil, err
+	}
+
+	// Metric names are already namespaced, scope labels would only add cardinality
+	exp, err := otelprometheus.New(
+		otelprometheus.WithRegisterer(registerer),
+		otelprometheus.WithoutScopeInfo(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(exp),
+	), nil
+}
+
+// InitializeMetrics - create the meter provider and the Prometheus /metrics handler
+func InitializeMetrics() (*sdkmetric.MeterProvider, http.Handler) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	mp, err := MeterProvider(registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Register our MeterProvider as the global so instrumentation
+	// created without an explicit provider reports to it.
+	global.SetMeterProvider(mp)
+
+	return mp, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// OperationMetrics - call count, error count and latency of named operations
+type OperationMetrics struct {
+	calls    syncint64.Counter
+	errors   syncint64.Counter
+	duration syncfloat64.Histogram
+}
+
+// NewOperationMetrics - create operation instruments named <prefix>.calls, <prefix>.errors and <prefix>.duration
+func NewOperationMetrics(meter metric.Meter, prefix string) (*OperationMetrics, error) {
+	calls, err := meter.SyncInt64().Counter(
+		prefix+".calls",
+		instrument.WithDescription("Number of operations"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	errors, err := meter.SyncInt64().Counter(
+		prefix+".errors",
+		instrument.WithDescription("Number of failed operations"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := meter.SyncFloat64().Histogram(
+		prefix+".duration",
+		instrument.WithDescription("Duration of operations"),
+		instrument.WithUnit(unit.Milliseconds),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OperationMetrics{
+		calls:    calls,
+		errors:   errors,
+		duration: duration,
+	}, nil
+}
+
+// Record - record one operation that started at start and finished with err
+func (m *OperationMetrics) Record(ctx context.Context, start time.Time, err error, attrs ...attribute.KeyValue) {
+	m.calls.Add(ctx, 1, attrs...)
+	if err != nil {
+		m.errors.Add(ctx, 1, attrs...)
+	}
+	m.duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs...)
+}
+
+// HTTPMetrics - chi middleware recording request count, error count and latency of every route
+func HTTPMetrics(mp metric.MeterProvider) func(next http.Handler) http.Handler {
+	meter := mp.Meter("go-distributed-tracing/http")
+
+	requests, err := meter.SyncInt64().Counter(
+		"http.server.request_count",
+		instrument.WithDescription("Number of HTTP requests"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	errors, err := meter.SyncInt64().Counter(
+		"http.server.error_count",
+		instrument.WithDescription("Number of HTTP requests answered with a 5xx status"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	duration, err := meter.SyncFloat64().Histogram(
+		"http.server.duration",
+		instrument.WithDescription("Duration of HTTP requests"),
+		instrument.WithUnit(unit.Milliseconds),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			// Unmatched requests share one label to keep cardinality bounded
+			route := "unknown"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			attrs := []attribute.KeyValue{
+				semconv.HTTPMethodKey.String(r.Method),
+				semconv.HTTPRouteKey.String(route),
+				semconv.HTTPStatusCodeKey.Int(status),
+			}
+
+			ctx := r.Context()
+			requests.Add(ctx, 1, attrs...)
+			if status >= http.StatusInternalServerError {
+				errors.Add(ctx, 1, attrs...)
+			}
+			duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs...)
+		})
+	}
+}
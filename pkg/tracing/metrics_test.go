@@ -0,0 +1,80 @@
+package pkg_tracing_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// scrape - read the Prometheus exposition of registry
+func scrape(t *testing.T, registry *prometheus.Registry) string {
+	rr := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	t.Setenv("APP_ID", "1")
+
+	registry := prometheus.NewRegistry()
+	mp, err := pkg_tracing.MeterProvider(registry)
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(pkg_tracing.HTTPMetrics(mp))
+	router.Get("/todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/todo/1", "/todo/2", "/todo/fail", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, registry)
+	assert.Contains(t, body, `http_server_request_count_total{http_method="GET",http_route="/todo/{id}",http_status_code="200"} 2`)
+	assert.Contains(t, body, `http_server_request_count_total{http_method="GET",http_route="/todo/{id}",http_status_code="500"} 1`)
+	assert.Contains(t, body, `http_server_request_count_total{http_method="GET",http_route="unknown",http_status_code="404"} 1`)
+	assert.Contains(t, body, `http_server_error_count_total{http_method="GET",http_route="/todo/{id}",http_status_code="500"} 1`)
+	assert.Contains(t, body, `http_server_duration_milliseconds_count{http_method="GET",http_route="/todo/{id}",http_status_code="200"} 2`)
+}
+
+func TestOperationMetrics(t *testing.T) {
+	t.Setenv("APP_ID", "1")
+
+	registry := prometheus.NewRegistry()
+	mp, err := pkg_tracing.MeterProvider(registry)
+	require.NoError(t, err)
+
+	metrics, err := pkg_tracing.NewOperationMetrics(mp.Meter("test"), "todo_service")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	metrics.Record(ctx, time.Now(), nil, attribute.String("operation", "GetAll"))
+	metrics.Record(ctx, time.Now(), errors.New("error"), attribute.String("operation", "GetAll"))
+
+	body := scrape(t, registry)
+	assert.Contains(t, body, `todo_service_calls_total{operation="GetAll"} 2`)
+	assert.Contains(t, body, `todo_service_errors_total{operation="GetAll"} 1`)
+	assert.Contains(t, body, `todo_service_duration_milliseconds_count{operation="GetAll"} 2`)
+}
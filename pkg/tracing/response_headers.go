@@ -0,0 +1,27 @@
+package pkg_tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDHeader - response header carrying the trace id of the request
+const TraceIDHeader = "X-Trace-Id"
+
+// TraceResponseHeaders - middleware writing traceparent and X-Trace-Id on every response.
+// It must run after otelchi.Middleware so the request context carries the server span.
+func TraceResponseHeaders(next http.Handler) http.Handler {
+	propagator := propagation.TraceContext{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanContext := trace.SpanContextFromContext(r.Context())
+		if spanContext.IsValid() {
+			propagator.Inject(r.Context(), propagation.HeaderCarrier(w.Header()))
+			w.Header().Set(TraceIDHeader, spanContext.TraceID().String())
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
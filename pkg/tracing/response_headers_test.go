@@ -0,0 +1,68 @@
+package pkg_tracing_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	response "go-distributed-tracing/utils/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/riandyrn/otelchi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTraceResponseHeaders(t *testing.T) {
+	tp := trace.NewTracerProvider()
+
+	router := chi.NewRouter()
+	router.Use(otelchi.Middleware(
+		"test",
+		otelchi.WithChiRoutes(router),
+		otelchi.WithTracerProvider(tp),
+		otelchi.WithPropagators(propagation.TraceContext{}),
+	))
+	router.Use(pkg_tracing.TraceResponseHeaders)
+	router.Get("/todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		response.ResponseNotFound(w, r, "Item not found")
+	})
+
+	t.Run("with trace", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/todo/1", nil))
+
+		traceID := rr.Header().Get(pkg_tracing.TraceIDHeader)
+		assert.Len(t, traceID, 32)
+		assert.Contains(t, rr.Header().Get("traceparent"), traceID)
+
+		body := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, traceID, body["trace_id"])
+	})
+
+	t.Run("continues incoming trace", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/todo/1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rr.Header().Get(pkg_tracing.TraceIDHeader))
+	})
+
+	t.Run("without trace", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler := pkg_tracing.TraceResponseHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response.ResponseNotFound(w, r, "Item not found")
+		}))
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/todo/1", nil))
+
+		assert.Empty(t, rr.Header().Get(pkg_tracing.TraceIDHeader))
+		assert.NotContains(t, rr.Body.String(), "trace_id")
+	})
+}
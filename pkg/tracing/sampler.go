@@ -0,0 +1,187 @@
+package pkg_tracing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Samplers that can be selected with TRACER_SAMPLER
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// SamplerConfig - sampling configuration
+type SamplerConfig struct {
+	// Name is one of the Sampler* constants, empty means parentbased_always_on
+	Name string
+	// Ratio is used by the traceidratio samplers
+	Ratio float64
+	// Routes override the sampler for root spans of matching chi routes
+	Routes []RouteRule
+}
+
+// RouteRule - sampling ratio for requests matching method and chi route pattern
+type RouteRule struct {
+	// Method is the HTTP method, "*" matches every method
+	Method string
+	// Route is the chi route pattern, e.g. /todo/{id}
+	Route string
+	Ratio float64
+}
+
+// SamplerConfigFromEnv - read sampling configuration from environment
+func SamplerConfigFromEnv() (SamplerConfig, error) {
+	cfg := SamplerConfig{
+		Name:  os.Getenv("TRACER_SAMPLER"),
+		Ratio: 1,
+	}
+
+	if value := os.Getenv("TRACER_SAMPLER_ARG"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TRACER_SAMPLER_ARG %q: %w", value, err)
+		}
+		cfg.Ratio = ratio
+	}
+
+	routes, err := ParseRouteRules(os.Getenv("TRACER_SAMPLER_ROUTES"))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Routes = routes
+
+	return cfg, nil
+}
+
+// ParseRouteRules - parse "POST /todo=1,GET /todo=0.01" into route rules
+func ParseRouteRules(value string) ([]RouteRule, error) {
+	rules := []RouteRule{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		target, ratioValue, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sampling rule %q, expected \"METHOD /route=ratio\"", item)
+		}
+
+		fields := strings.Fields(target)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid sampling rule %q, expected \"METHOD /route=ratio\"", item)
+		}
+
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(ratioValue), 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid sampling ratio in rule %q", item)
+		}
+
+		rules = append(rules, RouteRule{
+			Method: strings.ToUpper(fields[0]),
+			Route:  fields[1],
+			Ratio:  ratio,
+		})
+	}
+
+	return rules, nil
+}
+
+// NewSampler - create the sampler selected by config.
+// When route rules are configured the sampler is always parent based,
+// so spans created inside a traced chi route follow the route decision.
+func NewSampler(cfg SamplerConfig) (trace.Sampler, error) {
+	var root trace.Sampler
+	parentBased := true
+
+	switch strings.ToLower(cfg.Name) {
+	case SamplerParentBasedAlwaysOn, "":
+		root = trace.AlwaysSample()
+	case SamplerParentBasedAlwaysOff:
+		root = trace.NeverSample()
+	case SamplerParentBasedTraceIDRatio:
+		root = trace.TraceIDRatioBased(cfg.Ratio)
+	case SamplerAlwaysOn:
+		root, parentBased = trace.AlwaysSample(), false
+	case SamplerAlwaysOff:
+		root, parentBased = trace.NeverSample(), false
+	case SamplerTraceIDRatio:
+		root, parentBased = trace.TraceIDRatioBased(cfg.Ratio), false
+	default:
+		return nil, fmt.Errorf("unknown tracer sampler %q", cfg.Name)
+	}
+
+	if len(cfg.Routes) > 0 {
+		return trace.ParentBased(RouteSampler(cfg.Routes, root)), nil
+	}
+
+	if parentBased {
+		return trace.ParentBased(root), nil
+	}
+
+	return root, nil
+}
+
+type routeSampler struct {
+	rules    []RouteRule
+	samplers []trace.Sampler
+	fallback trace.Sampler
+}
+
+// RouteSampler - sample spans by the http.method and http.route attributes set by otelchi.
+// Spans that match no rule are sampled by fallback.
+func RouteSampler(rules []RouteRule, fallback trace.Sampler) trace.Sampler {
+	samplers := make([]trace.Sampler, len(rules))
+	for i, rule := range rules {
+		samplers[i] = trace.TraceIDRatioBased(rule.Ratio)
+	}
+
+	return &routeSampler{
+		rules:    rules,
+		samplers: samplers,
+		fallback: fallback,
+	}
+}
+
+// ShouldSample - implements trace.Sampler
+func (s *routeSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	method, route := "", ""
+	for _, attr := range p.Attributes {
+		switch attr.Key {
+		case semconv.HTTPMethodKey:
+			method = attr.Value.AsString()
+		case semconv.HTTPRouteKey:
+			route = attr.Value.AsString()
+		}
+	}
+
+	if route != "" {
+		for i, rule := range s.rules {
+			if rule.Route == route && (rule.Method == "*" || strings.EqualFold(rule.Method, method)) {
+				return s.samplers[i].ShouldSample(p)
+			}
+		}
+	}
+
+	return s.fallback.ShouldSample(p)
+}
+
+// Description - implements trace.Sampler
+func (s *routeSampler) Description() string {
+	rules := make([]string, len(s.rules))
+	for i, rule := range s.rules {
+		rules[i] = fmt.Sprintf("%s %s=%g", rule.Method, rule.Route, rule.Ratio)
+	}
+
+	return fmt.Sprintf("RouteSampler{%s}{%s}", strings.Join(rules, ","), s.fallback.Description())
+}
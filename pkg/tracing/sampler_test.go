@@ -0,0 +1,131 @@
+package pkg_tracing_test
+
+import (
+	"context"
+	"testing"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// startRouteSpan - start a server span carrying the attributes otelchi sets
+func startRouteSpan(tp *trace.TracerProvider, method string, route string) (context.Context, oteltrace.Span) {
+	return tp.Tracer("sampler_test").Start(
+		context.Background(),
+		route,
+		oteltrace.WithAttributes(
+			semconv.HTTPMethodKey.String(method),
+			semconv.HTTPRouteKey.String(route),
+		),
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+	)
+}
+
+func TestParseRouteRules(t *testing.T) {
+	t.Run("valid rules", func(t *testing.T) {
+		rules, err := pkg_tracing.ParseRouteRules("POST /todo=1, get /todo=0.01,* /todo/{id}=0")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []pkg_tracing.RouteRule{
+			{Method: "POST", Route: "/todo", Ratio: 1},
+			{Method: "GET", Route: "/todo", Ratio: 0.01},
+			{Method: "*", Route: "/todo/{id}", Ratio: 0},
+		}, rules)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		rules, err := pkg_tracing.ParseRouteRules("")
+
+		assert.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, value := range []string{"POST /todo", "/todo=1", "POST /todo=abc", "POST /todo=2"} {
+			_, err := pkg_tracing.ParseRouteRules(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestNewSampler(t *testing.T) {
+	t.Run("always on and always off", func(t *testing.T) {
+		on, err := pkg_tracing.NewSampler(pkg_tracing.SamplerConfig{Name: pkg_tracing.SamplerAlwaysOn})
+		require.NoError(t, err)
+		off, err := pkg_tracing.NewSampler(pkg_tracing.SamplerConfig{Name: pkg_tracing.SamplerAlwaysOff})
+		require.NoError(t, err)
+
+		_, span := startRouteSpan(trace.NewTracerProvider(trace.WithSampler(on)), "GET", "/todo")
+		assert.True(t, span.SpanContext().IsSampled())
+
+		_, span = startRouteSpan(trace.NewTracerProvider(trace.WithSampler(off)), "GET", "/todo")
+		assert.False(t, span.SpanContext().IsSampled())
+	})
+
+	t.Run("parent based ratio", func(t *testing.T) {
+		sampler, err := pkg_tracing.NewSampler(pkg_tracing.SamplerConfig{
+			Name:  pkg_tracing.SamplerParentBasedTraceIDRatio,
+			Ratio: 0,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, sampler.Description(), "ParentBased")
+
+		_, span := startRouteSpan(trace.NewTracerProvider(trace.WithSampler(sampler)), "GET", "/todo")
+		assert.False(t, span.SpanContext().IsSampled())
+	})
+
+	t.Run("route rules", func(t *testing.T) {
+		sampler, err := pkg_tracing.NewSampler(pkg_tracing.SamplerConfig{
+			Name:  pkg_tracing.SamplerParentBasedTraceIDRatio,
+			Ratio: 0,
+			Routes: []pkg_tracing.RouteRule{
+				{Method: "POST", Route: "/todo", Ratio: 1},
+				{Method: "GET", Route: "/todo", Ratio: 0},
+			},
+		})
+		require.NoError(t, err)
+		tp := trace.NewTracerProvider(trace.WithSampler(sampler))
+
+		ctx, span := startRouteSpan(tp, "POST", "/todo")
+		assert.True(t, span.SpanContext().IsSampled())
+
+		// Child spans follow the route decision
+		_, child := tp.Tracer("sampler_test").Start(ctx, "todoHandler.Create")
+		assert.True(t, child.SpanContext().IsSampled())
+
+		ctx, span = startRouteSpan(tp, "GET", "/todo")
+		assert.False(t, span.SpanContext().IsSampled())
+
+		_, child = tp.Tracer("sampler_test").Start(ctx, "todoHandler.GetAll")
+		assert.False(t, child.SpanContext().IsSampled())
+	})
+
+	t.Run("route rules fallback", func(t *testing.T) {
+		sampler, err := pkg_tracing.NewSampler(pkg_tracing.SamplerConfig{
+			Name: pkg_tracing.SamplerAlwaysOn,
+			Routes: []pkg_tracing.RouteRule{
+				{Method: "*", Route: "/todo", Ratio: 0},
+			},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, sampler.Description(), "ParentBased", "route rules reach the child spans")
+		tp := trace.NewTracerProvider(trace.WithSampler(sampler))
+
+		_, span := startRouteSpan(tp, "DELETE", "/todo")
+		assert.False(t, span.SpanContext().IsSampled())
+
+		_, span = startRouteSpan(tp, "GET", "/todo/{id}")
+		assert.True(t, span.SpanContext().IsSampled())
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := pkg_tracing.NewSampler(pkg_tracing.SamplerConfig{Name: "sometimes"})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,320 @@
+package pkg_tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// TailSamplingConfig - tail sampling configuration
+type TailSamplingConfig struct {
+	Enabled bool
+	// DecisionWait is how long a trace is buffered when its local root span never ends
+	DecisionWait time.Duration
+	// LatencyThreshold keeps traces that run longer than it, zero disables the policy
+	LatencyThreshold time.Duration
+	// Routes keeps traces that touch one of these chi route patterns
+	Routes []string
+	// Ratio is the share of the remaining traces that is kept
+	Ratio float64
+	// MaxTraces bounds the number of buffered traces, the oldest is decided first
+	MaxTraces int
+	// MaxSpansPerTrace bounds the number of buffered spans of a single trace
+	MaxSpansPerTrace int
+}
+
+// TailSamplingConfigFromEnv - read tail sampling configuration from environment
+func TailSamplingConfigFromEnv() (TailSamplingConfig, error) {
+	cfg := TailSamplingConfig{
+		Enabled:          os.Getenv("TRACER_TAIL_SAMPLING") == "true",
+		DecisionWait:     10 * time.Second,
+		Ratio:            0.1,
+		MaxTraces:        10000,
+		MaxSpansPerTrace: 1000,
+	}
+
+	var err error
+	if value := os.Getenv("TRACER_TAIL_DECISION_WAIT"); value != "" {
+		if cfg.DecisionWait, err = time.ParseDuration(value); err != nil {
+			return cfg, fmt.Errorf("invalid TRACER_TAIL_DECISION_WAIT %q: %w", value, err)
+		}
+	}
+	if value := os.Getenv("TRACER_TAIL_LATENCY_THRESHOLD"); value != "" {
+		if cfg.LatencyThreshold, err = time.ParseDuration(value); err != nil {
+			return cfg, fmt.Errorf("invalid TRACER_TAIL_LATENCY_THRESHOLD %q: %w", value, err)
+		}
+	}
+	if value := os.Getenv("TRACER_TAIL_RATIO"); value != "" {
+		if cfg.Ratio, err = strconv.ParseFloat(value, 64); err != nil {
+			return cfg, fmt.Errorf("invalid TRACER_TAIL_RATIO %q: %w", value, err)
+		}
+		if cfg.Ratio < 0 || cfg.Ratio > 1 {
+			return cfg, fmt.Errorf("invalid TRACER_TAIL_RATIO %q: must be between 0 and 1", value)
+		}
+	}
+	if value := os.Getenv("TRACER_TAIL_MAX_TRACES"); value != "" {
+		if cfg.MaxTraces, err = strconv.Atoi(value); err != nil {
+			return cfg, fmt.Errorf("invalid TRACER_TAIL_MAX_TRACES %q: %w", value, err)
+		}
+	}
+	if value := os.Getenv("TRACER_TAIL_MAX_SPANS"); value != "" {
+		if cfg.MaxSpansPerTrace, err = strconv.Atoi(value); err != nil {
+			return cfg, fmt.Errorf("invalid TRACER_TAIL_MAX_SPANS %q: %w", value, err)
+		}
+	}
+	for _, route := range strings.Split(os.Getenv("TRACER_TAIL_ROUTES"), ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			cfg.Routes = append(cfg.Routes, route)
+		}
+	}
+
+	return cfg, nil
+}
+
+// tailTrace - spans of a single trace waiting for a decision
+type tailTrace struct {
+	spans     []trace.ReadOnlySpan
+	firstSeen time.Time
+	start     time.Time
+	end       time.Time
+	hasError  bool
+	hasRoute  bool
+}
+
+// TailSamplingProcessor - span processor that buffers whole traces and
+// forwards the kept ones to the next processor once the trace completes
+type TailSamplingProcessor struct {
+	next   trace.SpanProcessor
+	cfg    TailSamplingConfig
+	ratio  trace.Sampler
+	routes map[string]bool
+
+	mu      sync.Mutex
+	traces  map[oteltrace.TraceID]*tailTrace
+	order   []oteltrace.TraceID
+	decided map[oteltrace.TraceID]bool
+	// decidedOrder bounds the decided cache used for late spans
+	decidedOrder []oteltrace.TraceID
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTailSamplingProcessor - create tail sampling processor forwarding kept spans to next
+func NewTailSamplingProcessor(next trace.SpanProcessor, cfg TailSamplingConfig) *TailSamplingProcessor {
+	if cfg.DecisionWait <= 0 {
+		cfg.DecisionWait = 10 * time.Second
+	}
+	if cfg.MaxTraces <= 0 {
+		cfg.MaxTraces = 10000
+	}
+	if cfg.MaxSpansPerTrace <= 0 {
+		cfg.MaxSpansPerTrace = 1000
+	}
+
+	routes := map[string]bool{}
+	for _, route := range cfg.Routes {
+		routes[route] = true
+	}
+
+	p := &TailSamplingProcessor{
+		next:    next,
+		cfg:     cfg,
+		ratio:   trace.TraceIDRatioBased(cfg.Ratio),
+		routes:  routes,
+		traces:  map[oteltrace.TraceID]*tailTrace{},
+		decided: map[oteltrace.TraceID]bool{},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go p.run()
+
+	return p
+}
+
+// OnStart - implements trace.SpanProcessor
+func (p *TailSamplingProcessor) OnStart(parent context.Context, s trace.ReadWriteSpan) {}
+
+// OnEnd - implements trace.SpanProcessor
+func (p *TailSamplingProcessor) OnEnd(s trace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+
+	traceID := s.SpanContext().TraceID()
+
+	p.mu.Lock()
+	// Late span of an already decided trace
+	if keep, ok := p.decided[traceID]; ok {
+		p.mu.Unlock()
+		if keep {
+			p.next.OnEnd(s)
+		}
+		return
+	}
+
+	t, ok := p.traces[traceID]
+	if !ok {
+		t = &tailTrace{firstSeen: time.Now(), start: s.StartTime(), end: s.EndTime()}
+		p.traces[traceID] = t
+		p.order = append(p.order, traceID)
+	}
+	p.add(t, s)
+
+	var ready [][]trace.ReadOnlySpan
+	// The local root span ending completes the trace
+	if !s.Parent().IsValid() || s.Parent().IsRemote() {
+		ready = append(ready, p.decide(traceID))
+	}
+	// Keep memory bounded by deciding the oldest traces early
+	for len(p.traces) > p.cfg.MaxTraces {
+		ready = append(ready, p.decide(p.order[0]))
+	}
+	p.mu.Unlock()
+
+	p.forward(ready)
+}
+
+// Shutdown - decide every buffered trace and shut down the next processor
+func (p *TailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	<-p.stopped
+
+	p.flush()
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush - decide every buffered trace and flush the next processor
+func (p *TailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	p.flush()
+	return p.next.ForceFlush(ctx)
+}
+
+// add - append span to the trace buffer and update the policy inputs
+func (p *TailSamplingProcessor) add(t *tailTrace, s trace.ReadOnlySpan) {
+	if len(t.spans) < p.cfg.MaxSpansPerTrace {
+		t.spans = append(t.spans, s)
+	}
+
+	if s.StartTime().Before(t.start) {
+		t.start = s.StartTime()
+	}
+	if s.EndTime().After(t.end) {
+		t.end = s.EndTime()
+	}
+
+	if s.Status().Code == codes.Error {
+		t.hasError = true
+	}
+	for _, attr := range s.Attributes() {
+		switch {
+		case attr.Key == "error" && attr.Value.Type() == attribute.BOOL && attr.Value.AsBool():
+			t.hasError = true
+		case attr.Key == semconv.HTTPRouteKey && p.routes[attr.Value.AsString()]:
+			t.hasRoute = true
+		}
+	}
+}
+
+// decide - remove the trace from the buffer and return its spans when it is kept.
+// Must be called with p.mu held.
+func (p *TailSamplingProcessor) decide(traceID oteltrace.TraceID) []trace.ReadOnlySpan {
+	t := p.traces[traceID]
+	delete(p.traces, traceID)
+	for i, id := range p.order {
+		if id == traceID {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+
+	keep := p.keep(traceID, t)
+	p.decided[traceID] = keep
+	p.decidedOrder = append(p.decidedOrder, traceID)
+	for len(p.decidedOrder) > p.cfg.MaxTraces {
+		delete(p.decided, p.decidedOrder[0])
+		p.decidedOrder = p.decidedOrder[1:]
+	}
+
+	if !keep {
+		return nil
+	}
+
+	return t.spans
+}
+
+// keep - evaluate the sampling policies for a completed trace
+func (p *TailSamplingProcessor) keep(traceID oteltrace.TraceID, t *tailTrace) bool {
+	if t.hasError || t.hasRoute {
+		return true
+	}
+
+	if p.cfg.LatencyThreshold > 0 && t.end.Sub(t.start) > p.cfg.LatencyThreshold {
+		return true
+	}
+
+	result := p.ratio.ShouldSample(trace.SamplingParameters{TraceID: traceID})
+	return result.Decision == trace.RecordAndSample
+}
+
+// forward - hand the kept spans to the next processor
+func (p *TailSamplingProcessor) forward(ready [][]trace.ReadOnlySpan) {
+	for _, spans := range ready {
+		for _, s := range spans {
+			p.next.OnEnd(s)
+		}
+	}
+}
+
+// flush - decide every buffered trace
+func (p *TailSamplingProcessor) flush() {
+	p.mu.Lock()
+	var ready [][]trace.ReadOnlySpan
+	for len(p.order) > 0 {
+		ready = append(ready, p.decide(p.order[0]))
+	}
+	p.mu.Unlock()
+
+	p.forward(ready)
+}
+
+// run - decide traces whose local root did not end within the decision wait
+func (p *TailSamplingProcessor) run() {
+	defer close(p.stopped)
+
+	interval := p.cfg.DecisionWait / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+			var ready [][]trace.ReadOnlySpan
+			for len(p.order) > 0 && now.Sub(p.traces[p.order[0]].firstSeen) >= p.cfg.DecisionWait {
+				ready = append(ready, p.decide(p.order[0]))
+			}
+			p.mu.Unlock()
+
+			p.forward(ready)
+		}
+	}
+}
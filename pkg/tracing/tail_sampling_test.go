@@ -0,0 +1,174 @@
+package pkg_tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func newTailSampling(cfg pkg_tracing.TailSamplingConfig) (*trace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(
+		trace.WithSpanProcessor(pkg_tracing.NewTailSamplingProcessor(recorder, cfg)),
+	)
+
+	return tp, recorder
+}
+
+func TestTailSamplingConfigFromEnv(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("TRACER_TAIL_RATIO", "")
+
+		cfg, err := pkg_tracing.TailSamplingConfigFromEnv()
+
+		require.NoError(t, err)
+		assert.Equal(t, 0.1, cfg.Ratio)
+	})
+
+	t.Run("ratio", func(t *testing.T) {
+		t.Setenv("TRACER_TAIL_RATIO", "0.5")
+
+		cfg, err := pkg_tracing.TailSamplingConfigFromEnv()
+
+		require.NoError(t, err)
+		assert.Equal(t, 0.5, cfg.Ratio)
+	})
+
+	t.Run("invalid ratio", func(t *testing.T) {
+		for _, value := range []string{"abc", "-0.1", "1.5"} {
+			t.Setenv("TRACER_TAIL_RATIO", value)
+
+			_, err := pkg_tracing.TailSamplingConfigFromEnv()
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestTailSamplingProcessor(t *testing.T) {
+	t.Run("keeps error traces", func(t *testing.T) {
+		tp, recorder := newTailSampling(pkg_tracing.TailSamplingConfig{Ratio: 0})
+		tracer := tp.Tracer("tail_test")
+
+		ctx, root := tracer.Start(context.Background(), "GET /todo")
+		_, child := tracer.Start(ctx, "todoHandler.GetAll")
+		child.SetStatus(codes.Error, "error")
+		child.End()
+		assert.Empty(t, recorder.Ended())
+		root.End()
+
+		assert.Len(t, recorder.Ended(), 2)
+	})
+
+	t.Run("drops the rest by ratio", func(t *testing.T) {
+		tp, recorder := newTailSampling(pkg_tracing.TailSamplingConfig{Ratio: 0})
+		tracer := tp.Tracer("tail_test")
+
+		ctx, root := tracer.Start(context.Background(), "GET /todo")
+		_, child := tracer.Start(ctx, "todoHandler.GetAll")
+		child.End()
+		root.End()
+
+		assert.Empty(t, recorder.Ended())
+
+		tp, recorder = newTailSampling(pkg_tracing.TailSamplingConfig{Ratio: 1})
+		_, root = tp.Tracer("tail_test").Start(context.Background(), "GET /todo")
+		root.End()
+
+		assert.Len(t, recorder.Ended(), 1)
+	})
+
+	t.Run("keeps slow traces", func(t *testing.T) {
+		tp, recorder := newTailSampling(pkg_tracing.TailSamplingConfig{LatencyThreshold: time.Second})
+		tracer := tp.Tracer("tail_test")
+
+		start := time.Now()
+		_, fast := tracer.Start(context.Background(), "GET /todo", oteltrace.WithTimestamp(start))
+		fast.End(oteltrace.WithTimestamp(start.Add(10 * time.Millisecond)))
+		assert.Empty(t, recorder.Ended())
+
+		_, slow := tracer.Start(context.Background(), "GET /todo", oteltrace.WithTimestamp(start))
+		slow.End(oteltrace.WithTimestamp(start.Add(2 * time.Second)))
+		assert.Len(t, recorder.Ended(), 1)
+	})
+
+	t.Run("keeps chosen routes", func(t *testing.T) {
+		tp, recorder := newTailSampling(pkg_tracing.TailSamplingConfig{Routes: []string{"/todo/{id}"}})
+		tracer := tp.Tracer("tail_test")
+
+		_, span := tracer.Start(context.Background(), "/todo", oteltrace.WithAttributes(semconv.HTTPRouteKey.String("/todo")))
+		span.End()
+		assert.Empty(t, recorder.Ended())
+
+		_, span = tracer.Start(context.Background(), "/todo/{id}", oteltrace.WithAttributes(semconv.HTTPRouteKey.String("/todo/{id}")))
+		span.End()
+		assert.Len(t, recorder.Ended(), 1)
+	})
+
+	t.Run("decides after decision wait and forwards late spans", func(t *testing.T) {
+		tp, recorder := newTailSampling(pkg_tracing.TailSamplingConfig{DecisionWait: 20 * time.Millisecond})
+		tracer := tp.Tracer("tail_test")
+
+		ctx, root := tracer.Start(context.Background(), "GET /todo")
+		_, child := tracer.Start(ctx, "todoHandler.GetAll")
+		child.RecordError(errors.New("error"))
+		child.SetStatus(codes.Error, "error")
+		child.End()
+
+		assert.Eventually(t, func() bool {
+			return len(recorder.Ended()) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		root.End()
+		assert.Len(t, recorder.Ended(), 2)
+	})
+
+	t.Run("bounds memory", func(t *testing.T) {
+		tp, recorder := newTailSampling(pkg_tracing.TailSamplingConfig{
+			Ratio:            1,
+			MaxTraces:        1,
+			MaxSpansPerTrace: 2,
+		})
+		tracer := tp.Tracer("tail_test")
+
+		ctx, first := tracer.Start(context.Background(), "first")
+		for i := 0; i < 5; i++ {
+			_, child := tracer.Start(ctx, "child")
+			child.End()
+		}
+		assert.Empty(t, recorder.Ended())
+
+		// A second buffered trace pushes the first one out
+		ctx, second := tracer.Start(context.Background(), "second")
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+		assert.Len(t, recorder.Ended(), 2)
+
+		first.End()
+		second.End()
+		assert.Len(t, recorder.Ended(), 5)
+	})
+
+	t.Run("shutdown flushes buffered traces", func(t *testing.T) {
+		tp, recorder := newTailSampling(pkg_tracing.TailSamplingConfig{Ratio: 1})
+		tracer := tp.Tracer("tail_test")
+
+		ctx, root := tracer.Start(context.Background(), "GET /todo")
+		_, child := tracer.Start(ctx, "todoHandler.GetAll")
+		child.End()
+
+		assert.NoError(t, tp.Shutdown(context.Background()))
+		assert.Len(t, recorder.Ended(), 1)
+		root.End()
+	})
+}
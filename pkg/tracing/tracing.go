@@ -1,48 +1,103 @@
 package pkg_tracing
 
 import (
+	"context"
 	"log"
 	"os"
 	"strconv"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/jaeger"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 )
 
-func tracerProvider(url string) (*trace.TracerProvider, error) {
+// Config - tracer provider configuration
+type Config struct {
+	Exporter     ExporterConfig
+	Sampler      SamplerConfig
+	TailSampling TailSamplingConfig
+}
+
+// ConfigFromEnv - read tracer provider configuration from environment
+func ConfigFromEnv() (Config, error) {
+	sampler, err := SamplerConfigFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+
+	tailSampling, err := TailSamplingConfigFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Config{
+		Exporter:     ExporterConfigFromEnv(),
+		Sampler:      sampler,
+		TailSampling: tailSampling,
+	}, nil
+}
+
+// newResource - information about this application shared by traces and metrics
+func newResource() (*resource.Resource, error) {
 	appID, err := strconv.ParseInt(os.Getenv("APP_ID"), 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create the Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
+	return resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(os.Getenv("APP_NAME")),
+		attribute.String("environment", os.Getenv("ENV")),
+		attribute.Int64("ID", appID),
+	), nil
+}
+
+func tracerProvider(ctx context.Context, cfg Config) (*trace.TracerProvider, error) {
+	res, err := newResource()
 	if err != nil {
 		return nil, err
 	}
 
-	tp := trace.NewTracerProvider(
-		// Always be sure to batch in production.
-		trace.WithBatcher(exp),
+	// Create the exporter selected by configuration
+	exp, err := NewExporter(ctx, cfg.Exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	sampler, err := NewSampler(cfg.Sampler)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []trace.TracerProviderOption{
+		trace.WithSampler(sampler),
 		// Record information about this application in a Resource.
-		trace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(os.Getenv("APP_NAME")),
-			attribute.String("environment", os.Getenv("ENV")),
-			attribute.Int64("ID", appID),
-		)),
-	)
-
-	return tp, nil
+		trace.WithResource(res),
+	}
+	switch {
+	case exp != nil && cfg.TailSampling.Enabled:
+		// Buffer whole traces and batch only the kept ones
+		opts = append(opts, trace.WithSpanProcessor(
+			NewTailSamplingProcessor(trace.NewBatchSpanProcessor(exp), cfg.TailSampling),
+		))
+	case exp != nil:
+		// Always be sure to batch in production.
+		opts = append(opts, trace.WithBatcher(exp))
+	}
+
+	return trace.NewTracerProvider(opts...), nil
 }
 
 func InitializeTracing() *trace.TracerProvider {
-	tp, err := tracerProvider(os.Getenv("TRACER_PROVIDER_URL"))
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tp, err := tracerProvider(context.Background(), cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"go-distributed-tracing/todo/models"
+)
+
+// ActorHeader - request header naming who makes the request, recorded in the todo history
+const ActorHeader = "X-Actor"
+
+// maxActorLength - longer actors are cut, the header is not trusted
+const maxActorLength = 100
+
+// Actor - middleware carrying the ActorHeader of the request in its context, see models.ActorFromContext
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := strings.TrimSpace(r.Header.Get(ActorHeader))
+		if len(actor) > maxActorLength {
+			actor = strings.ToValidUTF8(actor[:maxActorLength], "")
+		}
+		if actor != "" {
+			r = r.WithContext(models.WithActor(r.Context(), actor))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,41 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	handlers "go-distributed-tracing/todo/delivery/http"
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActor(t *testing.T) {
+	serve := func(header string) string {
+		var actor string
+		handler := handlers.Actor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor = models.ActorFromContext(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/todo", nil)
+		if header != "" {
+			req.Header.Set(handlers.ActorHeader, header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		return actor
+	}
+
+	t.Run("when the header names the actor", func(t *testing.T) {
+		assert.Equal(t, "alice", serve(" alice "))
+	})
+	t.Run("when anonymous without the header", func(t *testing.T) {
+		assert.Equal(t, models.AnonymousActor, serve(""))
+		assert.Equal(t, models.AnonymousActor, serve("   "))
+	})
+	t.Run("when cut a long actor", func(t *testing.T) {
+		assert.Len(t, serve(strings.Repeat("a", 500)), 100)
+	})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+	response "go-distributed-tracing/utils/response"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// bulkResponse - results of a bulk request, in the order of the operations
+type bulkResponse struct {
+	Atomic    bool        `json:"atomic"`
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+	Results   []*bulkItem `json:"results"`
+}
+
+// bulkItem - result of an operation of a bulk request, with the status and the error it would get on its own
+type bulkItem struct {
+	Index  int                    `json:"index"`
+	Op     models.BulkOp          `json:"op"`
+	ID     string                 `json:"id,omitempty"`
+	Status int                    `json:"status"`
+	Todo   *models.Todo           `json:"todo,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+	Errors map[string]interface{} `json:"errors,omitempty"`
+}
+
+// newBulkItem - the result of operation i, errors are answered like responseServiceError does
+func newBulkItem(ctx context.Context, i int, result *models.BulkResult) *bulkItem {
+	item := &bulkItem{Index: i, Op: result.Op, ID: result.ID, Todo: result.Todo, Status: http.StatusOK}
+	if result.Op == models.BulkCreate {
+		item.Status = http.StatusCreated
+	}
+
+	var validationErrors validator.ValidationErrors
+	err := result.Err
+	switch {
+	case err == nil:
+	case errors.Is(err, models.ErrAborted):
+		item.Status, item.Error = http.StatusFailedDependency, "Not written, another operation of the atomic bulk failed"
+	default:
+		item.Status, _, item.Error = statusForError(err)
+		if errors.As(err, &validationErrors) {
+			item.Errors = utils.ValidatonError(validationErrors).Errors
+		}
+		if item.Status == http.StatusInternalServerError {
+			utils.CaptureErrorContext(ctx, err)
+		}
+	}
+
+	return item
+}
+
+// Bulk - create, update and delete instances http handler. Answers 207 Multi-Status when an operation failed.
+func (handler *todoHandler) Bulk(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.Bulk")
+	defer span.End()
+
+	data := &models.BulkRequest{}
+	if err := render.Bind(r, data); err != nil {
+		// The body is missing or isn't JSON
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+
+			response.ResponseBodyError(w, r, err)
+			return
+		}
+
+		pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassValidation)
+
+		response.ResponseErrorValidation(w, r, err)
+		return
+	}
+
+	results, err := handler.todoService.Bulk(ctx, data.Operations, data.Atomic)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	body := &bulkResponse{Atomic: data.Atomic, Results: []*bulkItem{}}
+	for i, result := range results {
+		body.Results = append(body.Results, newBulkItem(ctx, i, result))
+	}
+	body.Failed = models.Failed(results)
+	body.Succeeded = len(results) - body.Failed
+
+	span.SetAttributes(
+		attribute.Int("todo.bulk.size", len(results)),
+		attribute.Int("todo.bulk.failed", body.Failed),
+	)
+
+	if body.Failed > 0 {
+		response.ResponseMultiStatus(w, r, &response.ResponseSuccess{Data: body})
+		return
+	}
+
+	response.ResponseOK(w, r, &response.ResponseSuccess{Data: body})
+}
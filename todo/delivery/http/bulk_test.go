@@ -0,0 +1,202 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	handlers "go-distributed-tracing/todo/delivery/http"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+
+	mockServices "go-distributed-tracing/todo/mocks/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTodoBulk(t *testing.T) {
+	newRouter := func(mockService *mockServices.TodoService) *chi.Mux {
+		router := chi.NewRouter()
+		handlers.NewTodoHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+		return router
+	}
+	body := `{"atomic": true, "operations": [{"op": "create", "todo": {"title": "a", "description": "b"}}, {"op": "delete", "id": "1", "version": 2}]}`
+
+	t.Run(WhenError400EOF, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.TodoService)
+
+		for _, body := range []string{"", "{"} {
+			req, err := http.NewRequest(http.MethodPost, "/todo/bulk", bytes.NewBufferString(body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			newRouter(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), "Check your body request")
+		}
+	})
+	t.Run(WhenError400Validation, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.TodoService)
+
+		for _, body := range []string{`{"operations": []}`, `{"operations": [null]}`} {
+			req, err := http.NewRequest(http.MethodPost, "/todo/bulk", bytes.NewBufferString(body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			newRouter(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+			assert.Contains(t, rr.Body.String(), `"operations`, body)
+		}
+		mockService.AssertNotCalled(t, "Bulk", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run(WhenError500Service, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.TodoService)
+		mockService.On("Bulk", mock.Anything, mock.Anything, true).Return(nil, ErrDefault)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/bulk", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.TodoService)
+		mockService.On("Bulk", mock.Anything, mock.MatchedBy(func(operations []*models.BulkOperation) bool {
+			return len(operations) == 2 && operations[0].Todo.Title == "a" && operations[1].ID == "1" && operations[1].Version == 2
+		}), true).Return([]*models.BulkResult{
+			{Op: models.BulkCreate, ID: "2", Todo: &models.Todo{Title: "a"}},
+			{Op: models.BulkDelete, ID: "1"},
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/bulk", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"atomic":true,"succeeded":2,"failed":0`)
+		assert.Contains(t, rr.Body.String(), `{"index":0,"op":"create","id":"2","status":201,"todo":{`)
+		assert.Contains(t, rr.Body.String(), `{"index":1,"op":"delete","id":"1","status":200}`)
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 207 multi-status (an operation failed)", func(t *testing.T) {
+		utils.InitializeValidator()
+		var validationErrors validator.ValidationErrors
+		assert.ErrorAs(t, utils.ValidateStruct(&models.BulkOperation{Op: models.BulkCreate}), &validationErrors)
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Bulk", mock.Anything, mock.Anything, false).Return([]*models.BulkResult{
+			{Op: models.BulkCreate, Err: models.NewError(models.ErrValidation, "TodoService.Bulk", "", validationErrors)},
+			{Op: models.BulkUpdate, ID: "1", Err: models.NewError(models.ErrPreconditionFailed, "TodoService.Bulk", "1", nil)},
+			{Op: models.BulkUpdate, ID: "2", Err: ErrInvalidTransition},
+			{Op: models.BulkDelete, ID: "3", Err: models.NewError(models.ErrAborted, "TodoService.Bulk", "3", nil)},
+			{Op: models.BulkDelete, ID: "4", Err: ErrDefault},
+			{Op: models.BulkDelete, ID: "5"},
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/bulk", bytes.NewBufferString(`{"operations": [{}, {}, {}, {}, {}, {}]}`))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+
+		var res struct {
+			Success bool `json:"success"`
+			Data    struct {
+				Succeeded int `json:"succeeded"`
+				Failed    int `json:"failed"`
+				Results   []struct {
+					Status int                    `json:"status"`
+					Error  string                 `json:"error"`
+					Errors map[string]interface{} `json:"errors"`
+				} `json:"results"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.False(t, res.Success)
+		assert.Equal(t, 1, res.Data.Succeeded)
+		assert.Equal(t, 5, res.Data.Failed)
+
+		var statuses []int
+		for _, result := range res.Data.Results {
+			statuses = append(statuses, result.Status)
+		}
+		assert.Equal(t, []int{
+			http.StatusBadRequest, http.StatusPreconditionFailed, http.StatusConflict, http.StatusFailedDependency,
+			http.StatusInternalServerError, http.StatusOK,
+		}, statuses)
+		assert.Contains(t, res.Data.Results[0].Errors, "todo")
+		assert.Equal(t, "cannot change status from archived to done", res.Data.Results[2].Error)
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when answer an operation like its own request", func(t *testing.T) {
+		utils.InitializeValidator()
+		errs := []error{
+			ErrNotFound, ErrInvalidID, ErrInvalidTransition, ErrConflict, ErrDefault,
+			models.NewError(models.ErrPreconditionFailed, "TodoService.Bulk", "1", nil),
+			models.NewError(models.ErrValidation, "TodoService.Bulk", "1", errors.New("operation is required")),
+		}
+
+		for _, serviceErr := range errs {
+			mockService := new(mockServices.TodoService)
+			mockService.On("GetByID", mock.Anything, "1").Return(nil, serviceErr)
+			mockService.On("Bulk", mock.Anything, mock.Anything, false).Return([]*models.BulkResult{
+				{Op: models.BulkDelete, ID: "1", Err: serviceErr},
+			}, nil)
+			router := newRouter(mockService)
+
+			req, err := http.NewRequest(http.MethodGet, "/todo/1", nil)
+			assert.NoError(t, err)
+			single := httptest.NewRecorder()
+			router.ServeHTTP(single, req)
+
+			req, err = http.NewRequest(http.MethodPost, "/todo/bulk", bytes.NewBufferString(`{"operations": [{}]}`))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			bulk := httptest.NewRecorder()
+			router.ServeHTTP(bulk, req)
+
+			var one struct {
+				Message string `json:"message"`
+			}
+			var res struct {
+				Data struct {
+					Results []struct {
+						Status int    `json:"status"`
+						Error  string `json:"error"`
+					} `json:"results"`
+				} `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(single.Body.Bytes(), &one))
+			assert.NoError(t, json.Unmarshal(bulk.Body.Bytes(), &res))
+			if assert.Len(t, res.Data.Results, 1) {
+				assert.Equal(t, single.Code, res.Data.Results[0].Status, serviceErr.Error())
+				assert.Equal(t, one.Message, res.Data.Results[0].Error, serviceErr.Error())
+			}
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-distributed-tracing/pkg/log"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/utils"
+	response "go-distributed-tracing/utils/response"
+)
+
+// IdempotencyKeyHeader - request header making a write safe to retry, see Idempotency
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader - response header of the responses replayed for an idempotency key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// Span attributes of the requests sent with an idempotency key
+const (
+	IdempotencyKeyKey      = attribute.Key("http.idempotency_key")
+	IdempotencyReplayedKey = attribute.Key("http.idempotency_replayed")
+)
+
+// MaxIdempotentBodySize - larger bodies sent with an IdempotencyKeyHeader answer 413 Request Entity Too Large
+const MaxIdempotentBodySize = 1 << 20
+
+// idempotentHeaders - response headers stored and replayed with the status and the body
+var idempotentHeaders = []string{"Content-Type", "Etag", "Location"}
+
+// errIdempotencyKey - the idempotency key is too long
+var errIdempotencyKey = fmt.Errorf("Idempotency-Key is longer than %d characters", models.MaxIdempotencyKeyLength)
+
+// errIdempotencyMismatch - the idempotency key was sent with another request
+var errIdempotencyMismatch = errors.New("Idempotency-Key was used for another request")
+
+// errIdempotentBodySize - the body sent with the idempotency key is too large
+var errIdempotentBodySize = fmt.Errorf("body sent with an Idempotency-Key is larger than %d bytes", MaxIdempotentBodySize)
+
+// errIdempotencyInProgress - the first request of the idempotency key is still running
+var errIdempotencyInProgress = errors.New("request of the Idempotency-Key is in progress")
+
+// IdempotencyTTLFromEnv - read how long idempotency keys are kept from IDEMPOTENCY_TTL, 24h by default
+func IdempotencyTTLFromEnv() (time.Duration, error) {
+	return durationFromEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+}
+
+// IdempotencyLockFromEnv - read how long the first request of an idempotency key holds it from IDEMPOTENCY_LOCK,
+// 1m by default
+func IdempotencyLockFromEnv() (time.Duration, error) {
+	return durationFromEnv("IDEMPOTENCY_LOCK", time.Minute)
+}
+
+// durationFromEnv - read the positive duration of the env variable name, fallback when it is not set
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be positive", name, value)
+	}
+
+	return duration, nil
+}
+
+// isWrite - requests of method change todos
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// Idempotency - middleware keeping the response of the writes sent with an IdempotencyKeyHeader for ttl, the
+// same request sent again with the key gets it replayed. Another request with the key answers 422 Unprocessable
+// Entity, and 409 Conflict while the first one is running, for lock at most, a retry takes the key over after
+// that. Server errors are not kept so they can be retried.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration, lock time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isWrite(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			if len(key) > models.MaxIdempotencyKeyLength {
+				pkg_tracing.RecordHTTPError(span, errIdempotencyKey, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+				response.ResponseBadRequest(w, r, errIdempotencyKey.Error())
+				return
+			}
+			span.SetAttributes(IdempotencyKeyKey.String(key))
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxIdempotentBodySize))
+			if err != nil && len(body) == MaxIdempotentBodySize {
+				pkg_tracing.RecordHTTPError(span, errIdempotentBodySize, http.StatusRequestEntityTooLarge, pkg_tracing.ErrorClassBadRequest)
+				response.ResponseRequestEntityTooLarge(w, r, errIdempotentBodySize.Error())
+				return
+			}
+			if err != nil {
+				pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+				response.ResponseBodyError(w, r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := utils.GetTimeNow()
+			record := &models.IdempotencyRecord{
+				Key:         key,
+				Fingerprint: models.Fingerprint(r.Method, r.URL.RequestURI(), body),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(ttl),
+				LockedUntil: now.Add(lock),
+			}
+			stored, err := repo.Reserve(r.Context(), record)
+			if err != nil {
+				pkg_tracing.RecordHTTPError(span, err, http.StatusInternalServerError, pkg_tracing.ErrorClassInternal)
+				response.ResponseError(w, r, err)
+				return
+			}
+			if stored != nil {
+				replay(w, r, span, record, stored)
+				return
+			}
+			span.SetAttributes(IdempotencyReplayedKey.Bool(false))
+
+			var buffer bytes.Buffer
+			recorder := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			recorder.Tee(&buffer)
+
+			next.ServeHTTP(recorder, r)
+
+			// The response is kept even when the client is gone, it is the one retrying
+			ctx := trace.ContextWithSpan(context.Background(), span)
+			record.Status = recorder.Status()
+			if record.Status == 0 {
+				record.Status = http.StatusOK
+			}
+
+			if record.Status >= http.StatusInternalServerError {
+				if err := repo.Release(ctx, record); err != nil {
+					log.FromContext(ctx).WithError(err).Error("releasing idempotency key")
+				}
+				return
+			}
+
+			record.Header = map[string]string{}
+			for _, name := range idempotentHeaders {
+				if value := recorder.Header().Get(name); value != "" {
+					record.Header[name] = value
+				}
+			}
+			record.Body = buffer.Bytes()
+
+			if err := repo.Complete(ctx, record); err != nil {
+				log.FromContext(ctx).WithError(err).Error("storing idempotent response")
+			}
+		})
+	}
+}
+
+// replay - answer the response stored for the idempotency key of record
+func replay(w http.ResponseWriter, r *http.Request, span trace.Span, record *models.IdempotencyRecord, stored *models.IdempotencyRecord) {
+	if stored.Fingerprint != record.Fingerprint {
+		pkg_tracing.RecordHTTPError(span, errIdempotencyMismatch, http.StatusUnprocessableEntity, pkg_tracing.ErrorClassConflict)
+		response.ResponseUnprocessableEntity(w, r, "Idempotency-Key was already used for another request")
+		return
+	}
+
+	if !stored.Done() {
+		pkg_tracing.RecordHTTPError(span, errIdempotencyInProgress, http.StatusConflict, pkg_tracing.ErrorClassConflict)
+		response.ResponseConflict(w, r, "A request with this Idempotency-Key is in progress, retry later")
+		return
+	}
+
+	span.SetAttributes(
+		IdempotencyReplayedKey.Bool(true),
+		semconv.HTTPStatusCodeKey.Int(stored.Status),
+	)
+
+	for name, value := range stored.Header {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
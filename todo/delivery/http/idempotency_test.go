@@ -0,0 +1,203 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	handlers "go-distributed-tracing/todo/delivery/http"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+
+	mockRepository "go-distributed-tracing/todo/mocks/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestIdempotency(t *testing.T) {
+	// create - handler creating a todo per call, failing while status is a server error
+	type create struct {
+		calls  int
+		status int
+	}
+	newHandler := func(repo repository.IdempotencyRepository, c *create) http.Handler {
+		return handlers.Idempotency(repo, time.Hour, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.calls++
+			body, _ := io.ReadAll(r.Body)
+
+			if c.status >= http.StatusInternalServerError {
+				w.WriteHeader(c.status)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Etag", `"1"`)
+			w.Header().Set("X-Other", "other")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"call":` + strconv.Itoa(c.calls) + `,"body":` + string(body) + `}`))
+		}))
+	}
+	send := func(handler http.Handler, method string, key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/todo", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(handlers.IdempotencyKeyHeader, key)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("when replay the response of a key", func(t *testing.T) {
+		c := &create{}
+		handler := newHandler(repository.NewMemoryIdempotencyRepository(), c)
+
+		first := send(handler, http.MethodPost, "a", `{"title":"a"}`)
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get(handlers.IdempotentReplayedHeader))
+
+		replayed := send(handler, http.MethodPost, "a", `{"title":"a"}`)
+		assert.Equal(t, http.StatusCreated, replayed.Code)
+		assert.Equal(t, first.Body.String(), replayed.Body.String())
+		assert.Equal(t, "true", replayed.Header().Get(handlers.IdempotentReplayedHeader))
+		assert.Equal(t, "application/json", replayed.Header().Get("Content-Type"))
+		assert.Equal(t, `"1"`, replayed.Header().Get("Etag"))
+		assert.Empty(t, replayed.Header().Get("X-Other"))
+		assert.Equal(t, 1, c.calls)
+
+		// Another key is another request
+		assert.Equal(t, http.StatusCreated, send(handler, http.MethodPost, "b", `{"title":"a"}`).Code)
+		assert.Equal(t, 2, c.calls)
+	})
+	t.Run("when return 422 unprocessable entity (key used for another request)", func(t *testing.T) {
+		c := &create{}
+		handler := newHandler(repository.NewMemoryIdempotencyRepository(), c)
+
+		send(handler, http.MethodPost, "a", `{"title":"a"}`)
+		rr := send(handler, http.MethodPost, "a", `{"title":"b"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Idempotency-Key was already used for another request")
+		assert.Equal(t, 1, c.calls)
+	})
+	t.Run(WhenError409Conflict+" (first request in progress)", func(t *testing.T) {
+		c := &create{}
+		mockRepo := new(mockRepository.IdempotencyRepository)
+		mockRepo.On("Reserve", mock.Anything, mock.Anything).Return(func(ctx context.Context, record *models.IdempotencyRecord) *models.IdempotencyRecord {
+			return &models.IdempotencyRecord{Key: record.Key, Fingerprint: record.Fingerprint}
+		}, nil)
+
+		rr := send(newHandler(mockRepo, c), http.MethodPost, "a", `{"title":"a"}`)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, 0, c.calls)
+		mockRepo.AssertExpectations(t)
+	})
+	t.Run("when take over a key its first request no longer holds", func(t *testing.T) {
+		c := &create{}
+		repo := repository.NewMemoryIdempotencyRepository()
+		now := time.Now()
+		_, err := repo.Reserve(context.Background(), &models.IdempotencyRecord{
+			Key:         "a",
+			Fingerprint: models.Fingerprint(http.MethodPost, "/todo", []byte(`{"title":"a"}`)),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(time.Hour),
+			LockedUntil: now.Add(-time.Second),
+		})
+		assert.NoError(t, err)
+
+		rr := send(newHandler(repo, c), http.MethodPost, "a", `{"title":"a"}`)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, c.calls)
+	})
+	t.Run("when retry a server error", func(t *testing.T) {
+		c := &create{status: http.StatusInternalServerError}
+		handler := newHandler(repository.NewMemoryIdempotencyRepository(), c)
+
+		assert.Equal(t, http.StatusInternalServerError, send(handler, http.MethodPost, "a", `{"title":"a"}`).Code)
+
+		c.status = 0
+		assert.Equal(t, http.StatusCreated, send(handler, http.MethodPost, "a", `{"title":"a"}`).Code)
+		assert.Equal(t, 2, c.calls)
+	})
+	t.Run(WhenError500Service, func(t *testing.T) {
+		c := &create{}
+		mockRepo := new(mockRepository.IdempotencyRepository)
+		mockRepo.On("Reserve", mock.Anything, mock.Anything).Return(nil, ErrDefault)
+
+		rr := send(newHandler(mockRepo, c), http.MethodPost, "a", `{"title":"a"}`)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, 0, c.calls)
+		mockRepo.AssertExpectations(t)
+	})
+	t.Run("when return 400 bad request (key too long)", func(t *testing.T) {
+		c := &create{}
+		mockRepo := new(mockRepository.IdempotencyRepository)
+
+		rr := send(newHandler(mockRepo, c), http.MethodPost, strings.Repeat("a", models.MaxIdempotencyKeyLength+1), `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+	})
+	t.Run("when return 413 request entity too large", func(t *testing.T) {
+		c := &create{}
+		mockRepo := new(mockRepository.IdempotencyRepository)
+
+		rr := send(newHandler(mockRepo, c), http.MethodPost, "a", strings.Repeat("a", handlers.MaxIdempotentBodySize+1))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, 0, c.calls)
+		mockRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+	})
+	t.Run("when ignore requests without a key or reads", func(t *testing.T) {
+		c := &create{}
+		mockRepo := new(mockRepository.IdempotencyRepository)
+		handler := newHandler(mockRepo, c)
+
+		send(handler, http.MethodPost, "", `{"title":"a"}`)
+		send(handler, http.MethodPost, "", `{"title":"a"}`)
+		send(handler, http.MethodGet, "a", "")
+
+		assert.Equal(t, 3, c.calls)
+		mockRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+	})
+	t.Run("when record the replay on the span", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		tracer := trace.NewTracerProvider(trace.WithSpanProcessor(recorder)).Tracer("idempotency_test")
+		handler := newHandler(repository.NewMemoryIdempotencyRepository(), &create{})
+
+		for i := 0; i < 2; i++ {
+			ctx, span := tracer.Start(context.Background(), "POST /todo")
+			req := httptest.NewRequest(http.MethodPost, "/todo", bytes.NewBufferString(`{"title":"a"}`)).WithContext(ctx)
+			req.Header.Set(handlers.IdempotencyKeyHeader, "a")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			span.End()
+		}
+
+		var replayed []bool
+		for _, span := range recorder.Ended() {
+			if span.Name() != "POST /todo" {
+				continue
+			}
+			assert.Contains(t, span.Attributes(), handlers.IdempotencyKeyKey.String("a"))
+			for _, attr := range span.Attributes() {
+				if attr.Key == handlers.IdempotencyReplayedKey {
+					replayed = append(replayed, attr.Value.AsBool())
+				}
+			}
+		}
+		assert.Equal(t, []bool{false, true}, replayed)
+	})
+}
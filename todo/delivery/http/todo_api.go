@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
 	"go-distributed-tracing/todo/models"
 	"go-distributed-tracing/todo/services"
 	"go-distributed-tracing/utils"
@@ -11,19 +17,20 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/sdk/trace"
+	"github.com/go-playground/validator/v10"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // todoHandler represent the http handler
 type todoHandler struct {
 	router      *chi.Mux
-	tp          *trace.TracerProvider
+	tp          *sdktrace.TracerProvider
 	todoService services.TodoService
 }
 
 // NewTodoHTTPHandler - make http handler
-func NewTodoHTTPHandler(router *chi.Mux, tp *trace.TracerProvider, service services.TodoService) *todoHandler {
+func NewTodoHTTPHandler(router *chi.Mux, tp *sdktrace.TracerProvider, service services.TodoService) *todoHandler {
 	return &todoHandler{
 		router:      router,
 		tp:          tp,
@@ -33,10 +40,146 @@ func NewTodoHTTPHandler(router *chi.Mux, tp *trace.TracerProvider, service servi
 
 func (handler *todoHandler) RegisterRoutes() {
 	handler.router.Get("/todo", handler.GetAll)
+	handler.router.Get("/todo/trash", handler.GetTrash)
 	handler.router.Get("/todo/{id}", handler.GetByID)
 	handler.router.Post("/todo", handler.Create)
+	handler.router.Post("/todo/bulk", handler.Bulk)
 	handler.router.Put("/todo/{id}", handler.Update)
+	handler.router.Patch("/todo/{id}", handler.Patch)
+	handler.router.Post("/todo/{id}/complete", handler.Complete)
+	handler.router.Post("/todo/{id}/reopen", handler.Reopen)
 	handler.router.Delete("/todo/{id}", handler.Delete)
+	handler.router.Post("/todo/{id}/restore", handler.Restore)
+	handler.router.Get("/todo/{id}/history", handler.History)
+	handler.router.Post("/todo/{id}/revert/{rev}", handler.Revert)
+}
+
+// errCursorSort - the cursor was made for another sort than the requested one
+var errCursorSort = errors.New("cursor was made for another sort")
+
+// errScoreSort - the score only exists when searching
+var errScoreSort = errors.New("sort by score without a search")
+
+// errPatchType - the body of a patch is neither a merge patch nor a JSON patch
+var errPatchType = errors.New("unsupported patch content type")
+
+// errRevision - the revision of a revert is not a number
+var errRevision = errors.New("invalid revision")
+
+// errIfMatch - If-Match is not the ETag of a todo
+var errIfMatch = errors.New("If-Match is not a single todo ETag")
+
+// etag - entity tag of todo, its version
+func etag(todo *models.Todo) string {
+	return `"` + strconv.FormatInt(todo.Version, 10) + `"`
+}
+
+// ifMatchVersion - the version If-Match requires, 0 when any version matches. Only a single strong ETag is
+// supported.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errIfMatch
+	}
+
+	version, err := strconv.ParseInt(value[1:len(value)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errIfMatch
+	}
+
+	return version, nil
+}
+
+// notModified - If-None-Match has tag, compared weakly
+func notModified(r *http.Request, tag string) bool {
+	value := r.Header.Get("If-None-Match")
+	if strings.TrimSpace(value) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(value, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// preconditionFailedMessage - message of the writes whose version doesn't match the todo
+const preconditionFailedMessage = "Item was changed, get it again for its current version"
+
+// responsePreconditionFailed - answer a write whose If-Match doesn't match the todo
+func responsePreconditionFailed(w http.ResponseWriter, r *http.Request, span trace.Span, err error) {
+	pkg_tracing.RecordHTTPError(span, err, http.StatusPreconditionFailed, pkg_tracing.ErrorClassConflict)
+	response.ResponsePreconditionFailed(w, r, preconditionFailedMessage)
+}
+
+// withSpan - r carrying the handler span, so the error logged for r is joined to it
+func withSpan(r *http.Request, span trace.Span) *http.Request {
+	return r.WithContext(trace.ContextWithSpan(r.Context(), span))
+}
+
+// statusForError - status, error class and message answered for an error returned by the service
+func statusForError(err error) (status int, class string, message string) {
+	var (
+		validationErrors validator.ValidationErrors
+		domainErr        *models.Error
+	)
+
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound, pkg_tracing.ErrorClassNotFound, "Item not found"
+	case errors.Is(err, models.ErrInvalidID):
+		return http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest, "Invalid id"
+	case errors.Is(err, models.ErrInvalidTransition):
+		message = "Status can't change to the requested one"
+		if errors.As(err, &domainErr) && domainErr.Err != nil {
+			message = domainErr.Err.Error()
+		}
+		return http.StatusConflict, pkg_tracing.ErrorClassConflict, message
+	case errors.Is(err, models.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, pkg_tracing.ErrorClassConflict, preconditionFailedMessage
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict, pkg_tracing.ErrorClassConflict, "Item conflicts with its current state"
+	case errors.As(err, &validationErrors):
+		return http.StatusBadRequest, pkg_tracing.ErrorClassValidation, "Validation errors in your request"
+	case errors.Is(err, models.ErrValidation):
+		// Only the cause is shown to clients, not the failed operation
+		message = "Validation errors in your request"
+		if errors.As(err, &domainErr) && domainErr.Err != nil {
+			message = domainErr.Err.Error()
+		}
+		return http.StatusBadRequest, pkg_tracing.ErrorClassValidation, message
+	default:
+		return http.StatusInternalServerError, pkg_tracing.ErrorClassInternal, "There is something error"
+	}
+}
+
+// responseServiceError - answer an error returned by the service as statusForError maps it and record it on span
+func responseServiceError(w http.ResponseWriter, r *http.Request, span trace.Span, err error) {
+	status, class, message := statusForError(err)
+	pkg_tracing.RecordHTTPError(span, err, status, class)
+
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrors):
+		response.ResponseErrorValidation(w, r, validationErrors)
+	case status == http.StatusNotFound:
+		response.ResponseNotFound(w, r, message)
+	case status == http.StatusBadRequest:
+		response.ResponseBadRequest(w, r, message)
+	case status == http.StatusConflict:
+		response.ResponseConflict(w, r, message)
+	case status == http.StatusPreconditionFailed:
+		response.ResponsePreconditionFailed(w, r, message)
+	default:
+		response.ResponseError(w, withSpan(r, span), err)
+	}
 }
 
 // GetAll - get all todo http handler
@@ -44,22 +187,44 @@ func (handler *todoHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.GetAll")
 	defer span.End()
 
-	qQuery := r.URL.Query().Get("q")
-	pageQuery := r.URL.Query().Get("page")
-	perPageQuery := r.URL.Query().Get("per_page")
+	handler.list(ctx, w, r, span, false)
+}
+
+// GetTrash - get the todo in the trash http handler, the most recently deleted first by default
+func (handler *todoHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.GetTrash")
+	defer span.End()
+
+	handler.list(ctx, w, r, span, true)
+}
+
+// list - respond with a page of the todos matching the query of r, the ones in the trash when deleted
+func (handler *todoHandler) list(ctx context.Context, w http.ResponseWriter, r *http.Request, span trace.Span, deleted bool) {
+	query := r.URL.Query()
+	pageQuery := query.Get("page")
+	perPageQuery := query.Get("per_page")
 
-	err := utils.ValidateStruct(&models.TodoListRequest{
+	listRequest := &models.TodoListRequest{
 		Keywords: &models.SearchForm{
-			Keywords: qQuery,
+			Keywords: query.Get("q"),
 		},
-		Page:    pageQuery,
-		PerPage: perPageQuery,
-	})
+		Search:      query.Get("search"),
+		Status:      query.Get("status"),
+		Tags:        query["tag"],
+		PriorityMin: query.Get("priority_min"),
+		PriorityMax: query.Get("priority_max"),
+		Overdue:     query.Get("overdue"),
+		DueBefore:   query.Get("due_before"),
+		DueAfter:    query.Get("due_after"),
+		Sort:        query.Get("sort"),
+		Page:        pageQuery,
+		PerPage:     perPageQuery,
+		Cursor:      query.Get("cursor"),
+		Total:       query.Get("total"),
+	}
+	err := utils.ValidateStruct(listRequest)
 	if err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
+		pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassValidation)
 
 		response.ResponseErrorValidation(w, r, err)
 		return
@@ -69,29 +234,83 @@ func (handler *todoHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	perPage := utils.PerPage(perPageQuery)
 	offset := utils.Offset(currentPage, perPage)
 
-	results, totalData, err := handler.todoService.GetAll(ctx, qQuery, perPage, offset)
+	filter := listRequest.Filter()
+	filter.Deleted = deleted
+	sort := listRequest.SortOrder()
+	if deleted && len(sort) == 0 {
+		sort = models.TodoSort{{Field: models.SortDeletedAt, Desc: true}}
+	}
+	page := models.Pagination{
+		Limit:     perPage,
+		Offset:    offset,
+		SkipTotal: listRequest.SkipTotal(),
+	}
+
+	// A cursor carries its sort, a sort sent along with it must be the same
+	if listRequest.Cursor != "" {
+		cursor, err := models.DecodeTodoCursor(listRequest.Cursor)
+		if err == nil && listRequest.Sort != "" && sort.String() != cursor.Sort.String() {
+			err = errCursorSort
+		}
+		if err != nil {
+			pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+			response.ResponseBadRequest(w, r, "Invalid cursor")
+			return
+		}
+
+		sort = cursor.Sort
+		page.Cursor = cursor
+	}
+
+	if sort.Has(models.SortScore) && filter.Search == "" {
+		pkg_tracing.RecordHTTPError(span, errScoreSort, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+		response.ResponseBadRequest(w, r, "Sorting by score needs a search")
+		return
+	}
+
+	result, err := handler.todoService.GetAll(ctx, filter, sort, page)
 	if err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	meta := &response.Meta{
+		PerPage: perPage,
+	}
+	if page.Cursor == nil {
+		meta.CurrentPage = currentPage
+	}
+	if result.Total != nil {
+		totalPages := utils.TotalPage(*result.Total, perPage)
+		meta.TotalPage = &totalPages
+		meta.TotalData = result.Total
+	}
 
-		response.ResponseError(w, r, err)
+	meta.NextCursor, err = encodeCursor(result.Next)
+	if err == nil {
+		meta.PrevCursor, err = encodeCursor(result.Prev)
+	}
+	if err != nil {
+		pkg_tracing.RecordHTTPError(span, err, http.StatusInternalServerError, pkg_tracing.ErrorClassInternal)
+		response.ResponseError(w, withSpan(r, span), err)
 		return
 	}
-	totalPages := utils.TotalPage(totalData, perPage)
 
 	response.ResponseOKList(w, r, &response.ResponseSuccessList{
-		Data: results,
-		Meta: &response.Meta{
-			PerPage:     perPage,
-			CurrentPage: currentPage,
-			TotalPage:   totalPages,
-			TotalData:   totalData,
-		},
+		Data: result.Todos,
+		Meta: meta,
 	})
 }
 
+// encodeCursor - token of cursor, empty when there is no such page
+func encodeCursor(cursor *models.TodoCursor) (string, error) {
+	if cursor == nil {
+		return "", nil
+	}
+
+	return cursor.Encode()
+}
+
 // GetByID - get todo by id http handler
 func (handler *todoHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.GetByID")
@@ -103,17 +322,14 @@ func (handler *todoHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	// Get detail
 	result, err := handler.todoService.GetByID(ctx, id)
 	if err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
-
-		if err.Error() == "not found" {
-			response.ResponseNotFound(w, r, "Item not found")
-			return
-		}
+		responseServiceError(w, r, span, err)
+		return
+	}
 
-		response.ResponseError(w, r, err)
+	tag := etag(result)
+	w.Header().Set("ETag", tag)
+	if notModified(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -130,33 +346,22 @@ func (handler *todoHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	data := &models.TodoRequest{}
 	if err := render.Bind(r, data); err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
-
 		if err.Error() == io.EOF.Error() {
+			pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+
 			response.ResponseBodyError(w, r, err)
 			return
 		}
 
-		span.SetAttributes(attribute.Bool("validation.error", true))
+		pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassValidation)
 
 		response.ResponseErrorValidation(w, r, err)
 		return
 	}
 
-	result, err := handler.todoService.Create(ctx, &models.Todo{
-		Title:       data.Title,
-		Description: data.Description,
-	})
+	result, err := handler.todoService.Create(ctx, data.Todo())
 	if err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
-
-		response.ResponseError(w, r, err)
+		responseServiceError(w, r, span, err)
 		return
 	}
 
@@ -173,51 +378,125 @@ func (handler *todoHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Get and filter id param
 	id := chi.URLParam(r, "id")
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		responsePreconditionFailed(w, r, span, err)
+		return
+	}
+
 	data := &models.TodoRequest{}
 	if err := render.Bind(r, data); err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
-
 		if err.Error() == io.EOF.Error() {
+			pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+
 			response.ResponseBodyError(w, r, err)
 			return
 		}
 
+		pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassValidation)
+
 		response.ResponseErrorValidation(w, r, err)
 		return
 	}
 
 	// Edit data
-	_, err := handler.todoService.Update(ctx, id, &models.Todo{
-		Title:       data.Title,
-		Description: data.Description,
+	result, err := handler.todoService.Update(ctx, id, data.Todo(), version)
+
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(result))
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
 	})
+}
+
+// Patch - patch instance by id http handler, the body is a merge patch or a JSON patch
+func (handler *todoHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.Patch")
+	defer span.End()
 
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
+		responsePreconditionFailed(w, r, span, err)
+		return
+	}
 
-		if err.Error() == "not found" {
-			response.ResponseNotFound(w, r, "Item not found")
-			return
-		}
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != models.MergePatchType && contentType != models.JSONPatchType) {
+		pkg_tracing.RecordHTTPError(span, errPatchType, http.StatusUnsupportedMediaType, pkg_tracing.ErrorClassBadRequest)
+		response.ResponseUnsupportedMediaType(w, r, "Content-Type must be "+models.MergePatchType+" or "+models.JSONPatchType)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err == nil && len(body) == 0 {
+		err = io.EOF
+	}
+	if err != nil {
+		pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+
+		response.ResponseBodyError(w, r, err)
+		return
+	}
 
-		response.ResponseError(w, r, err)
+	result, err := handler.todoService.Patch(ctx, id, &models.TodoPatch{
+		ContentType: contentType,
+		Document:    body,
+	}, version)
+	if err != nil {
+		responseServiceError(w, r, span, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(result))
 	response.ResponseOK(w, r, &response.ResponseSuccess{
-		Data: response.H{
-			"id": id,
-		},
+		Data: result,
+	})
+}
+
+// Complete - mark instance by id as done http handler
+func (handler *todoHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	handler.transition(w, r, "todoHandler.Complete", handler.todoService.Complete)
+}
+
+// Reopen - move instance by id back to todo http handler
+func (handler *todoHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	handler.transition(w, r, "todoHandler.Reopen", handler.todoService.Reopen)
+}
+
+// transition - change the status of instance by id with change, checked against If-Match, and answer the todo
+func (handler *todoHandler) transition(w http.ResponseWriter, r *http.Request, name string, change func(ctx context.Context, id string, version int64) (*models.Todo, error)) {
+	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), name)
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		responsePreconditionFailed(w, r, span, err)
+		return
+	}
+
+	result, err := change(ctx, id, version)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(result))
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
 	})
 }
 
-// Delete - delete instance by id http handler
+// Delete - move instance by id to the trash http handler
 func (handler *todoHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.Delete")
 	defer span.End()
@@ -225,20 +504,16 @@ func (handler *todoHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// Get and filter id param
 	id := chi.URLParam(r, "id")
 
-	// Delete record
-	err := handler.todoService.Delete(ctx, id)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		span.SetAttributes(
-			attribute.Key("error").Bool(true),
-		)
-		span.RecordError(err)
-
-		if err.Error() == "not found" {
-			response.ResponseNotFound(w, r, "Item not found")
-			return
-		}
+		responsePreconditionFailed(w, r, span, err)
+		return
+	}
 
-		response.ResponseError(w, r, err)
+	// Delete record
+	err = handler.todoService.Delete(ctx, id, version)
+	if err != nil {
+		responseServiceError(w, r, span, err)
 		return
 	}
 
@@ -248,3 +523,74 @@ func (handler *todoHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// Restore - move instance by id out of the trash http handler
+func (handler *todoHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.Restore")
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	result, err := handler.todoService.Restore(ctx, id)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(result))
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
+
+// History - get the revisions of instance by id http handler
+func (handler *todoHandler) History(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.History")
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	result, err := handler.todoService.History(ctx, id)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
+
+// Revert - set instance by id back to a revision http handler
+func (handler *todoHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("todoHandler").Start(r.Context(), "todoHandler.Revert")
+	defer span.End()
+
+	// Get and filter id and rev params
+	id := chi.URLParam(r, "id")
+	rev, err := strconv.ParseInt(chi.URLParam(r, "rev"), 10, 64)
+	if err != nil || rev <= 0 {
+		pkg_tracing.RecordHTTPError(span, errRevision, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+		response.ResponseBadRequest(w, r, "Invalid revision")
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		responsePreconditionFailed(w, r, span, err)
+		return
+	}
+
+	result, err := handler.todoService.Revert(ctx, id, rev, version)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(result))
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
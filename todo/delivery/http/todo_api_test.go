@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	handlers "go-distributed-tracing/todo/delivery/http"
 	"go-distributed-tracing/todo/models"
@@ -15,18 +16,28 @@ import (
 	mockServices "go-distributed-tracing/todo/mocks/services"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 var ErrDefault error = errors.New("error")
-var ErrNotFound error = errors.New("not found")
+var ErrNotFound error = models.NewError(models.ErrNotFound, "TodoService.GetByID", "1", nil)
+var ErrInvalidID error = models.NewError(models.ErrInvalidID, "TodoService.GetByID", "abc", nil)
+var ErrConflict error = models.ErrConflict
+var ErrInvalidTransition error = models.NewError(models.ErrInvalidTransition, "TodoService.Complete", "1", errors.New("cannot change status from archived to done"))
 var WhenError400EOF string = "when return 400 bad request (error EOF)"
 var WhenError500Service string = "when return 500 internal error (error service)"
 var WhenError500Query string = "when return 500 internal error (error query)"
 var WhenError400Validation string = "when return 400 bad request (error validation)"
 var WhenError404NotFound string = "when return 404 not found (resouce not found)"
+var WhenError400InvalidID string = "when return 400 bad request (invalid id)"
+var WhenError409Conflict string = "when return 409 conflict"
 var WhenSuccess201Created string = "when return 201 created"
 var WhenSuccess200OK string = "when return 200 ok"
 
@@ -80,10 +91,10 @@ func TestTodoGetAll(t *testing.T) {
 		mockService.On(
 			"GetAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("int"),
-			mock.AnythingOfType("int"),
-		).Return(nil, 1, ErrDefault)
+			mock.AnythingOfType("models.TodoFilter"),
+			mock.AnythingOfType("models.TodoSort"),
+			mock.AnythingOfType("models.Pagination"),
+		).Return(nil, ErrDefault)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
@@ -103,6 +114,7 @@ func TestTodoGetAll(t *testing.T) {
 
 		mockListTodo := make([]*models.Todo, 0)
 		mockListTodo = append(mockListTodo, &models.Todo{})
+		total := 1
 
 		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?page=1&per_page=10", nil)
 		assert.NoError(t, err)
@@ -112,10 +124,10 @@ func TestTodoGetAll(t *testing.T) {
 		mockService.On(
 			"GetAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("int"),
-			mock.AnythingOfType("int"),
-		).Return(mockListTodo, 1, nil)
+			mock.AnythingOfType("models.TodoFilter"),
+			mock.AnythingOfType("models.TodoSort"),
+			mock.AnythingOfType("models.Pagination"),
+		).Return(&models.TodoPage{Todos: mockListTodo, Total: &total}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
@@ -130,227 +142,294 @@ func TestTodoGetAll(t *testing.T) {
 		// Check if the mock called
 		mockService.AssertExpectations(t)
 	})
-}
-
-// TestTodoCreate - testing create [201]
-func TestTodoCreate(t *testing.T) {
-	t.Run(WhenError400EOF, func(t *testing.T) {
+	t.Run("when filtering by status", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader([]byte("")))
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?q=milk&status=done", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"GetAll",
+			mock.Anything,
+			models.TodoFilter{Keyword: "milk", Status: models.StatusDone},
+			mock.AnythingOfType("models.TodoSort"),
+			mock.AnythingOfType("models.Pagination"),
+		).Return(&models.TodoPage{Todos: []*models.Todo{}}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Create)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
 	})
-	t.Run("when return 400 bad request (error validation) ", func(t *testing.T) {
+	t.Run("when filtering by tag, priority and due date", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		mockPostBody := map[string]interface{}{
-			"title":       "",
-			"description": "",
-		}
-		body, _ := json.Marshal(mockPostBody)
-
-		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader(body))
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?tag=Home&tag=bills&priority_min=2&overdue=true&due_after=2022-12-01T00:00:00Z", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
+
+		priorityMin := 2
+		dueAfter := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
 
 		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"GetAll",
+			mock.Anything,
+			models.TodoFilter{
+				Tags:        []string{"bills", "home"},
+				PriorityMin: &priorityMin,
+				Overdue:     true,
+				DueAfter:    &dueAfter,
+			},
+			mock.AnythingOfType("models.TodoSort"),
+			mock.AnythingOfType("models.Pagination"),
+		).Return(&models.TodoPage{Todos: []*models.Todo{}}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Create)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
 	})
-	t.Run("when error 500 internal error (error service)", func(t *testing.T) {
+	t.Run("when sorting", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		mockPostBody := map[string]interface{}{
-			"title":       "lorem ipsum",
-			"description": "desc",
-		}
-		body, _ := json.Marshal(mockPostBody)
-
-		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader(body))
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?sort=-priority,due_at", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On("Create", mock.Anything, mock.AnythingOfType("*models.Todo")).Return(nil, errors.New(""))
+		mockService.On(
+			"GetAll",
+			mock.Anything,
+			mock.AnythingOfType("models.TodoFilter"),
+			models.TodoSort{
+				{Field: models.SortPriority, Desc: true},
+				{Field: models.SortDueAt},
+			},
+			mock.AnythingOfType("models.Pagination"),
+		).Return(&models.TodoPage{Todos: []*models.Todo{}}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Create)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, http.StatusOK, rr.Code)
 
 		// Check if the mock called
 		mockService.AssertExpectations(t)
 	})
-	t.Run("when return 201 created", func(t *testing.T) {
+	t.Run("when return 400 bad request (unknown sort field)", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		mockPostBody := map[string]interface{}{
-			"title":       "lorem ipsum",
-			"description": "desc",
-		}
-		body, _ := json.Marshal(mockPostBody)
-
-		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader(body))
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?sort=-description", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On("Create", mock.Anything, mock.AnythingOfType("*models.Todo")).Return(&models.Todo{}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Create)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "sort must be a comma separated list of title, status")
 
 		// Check if the mock called
-		mockService.AssertExpectations(t)
+		mockService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
-}
-
-// TestTodoGetByID - testing GetByID [200]
-func TestTodoGetByID(t *testing.T) {
-	t.Run(WhenError404NotFound, func(t *testing.T) {
+	t.Run("when paging with a cursor", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		sort := models.ParseTodoSort("-priority")
+		key := &models.Todo{ID: primitive.NewObjectID(), Priority: 3}
+		cursor, err := (&models.TodoCursor{Sort: sort, Key: key}).Encode()
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?per_page=2&total=false&cursor="+cursor, nil)
+		assert.NoError(t, err)
+
+		next := &models.Todo{ID: primitive.NewObjectID(), Priority: 1}
 
 		mockService := new(mockServices.TodoService)
-		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrNotFound)
+		mockService.On(
+			"GetAll",
+			mock.Anything,
+			mock.AnythingOfType("models.TodoFilter"),
+			sort,
+			mock.MatchedBy(func(page models.Pagination) bool {
+				return page.Limit == 2 && page.SkipTotal && page.Cursor != nil &&
+					!page.Cursor.Before && page.Cursor.Key.ID == key.ID && page.Cursor.Key.Priority == 3
+			}),
+		).Return(&models.TodoPage{
+			Todos: []*models.Todo{next},
+			Prev:  &models.TodoCursor{Sort: sort, Before: true, Key: next},
+		}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Meta map[string]interface{} `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.NotContains(t, body.Meta, "page")
+		assert.NotContains(t, body.Meta, "total_count")
+		assert.NotContains(t, body.Meta, "next_cursor")
+
+		prev, err := models.DecodeTodoCursor(body.Meta["prev_cursor"].(string))
+		assert.NoError(t, err)
+		assert.True(t, prev.Before)
+		assert.Equal(t, next.ID, prev.Key.ID)
 
 		// Check if the mock called
 		mockService.AssertExpectations(t)
 	})
-	t.Run(WhenError500Service, func(t *testing.T) {
+	t.Run("when paging with pages", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?page=2&per_page=1", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
+
+		todo := &models.Todo{ID: primitive.NewObjectID()}
+		total := 3
 
 		mockService := new(mockServices.TodoService)
-		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrDefault)
+		mockService.On(
+			"GetAll",
+			mock.Anything,
+			mock.AnythingOfType("models.TodoFilter"),
+			mock.AnythingOfType("models.TodoSort"),
+			models.Pagination{Limit: 1, Offset: 1},
+		).Return(&models.TodoPage{
+			Todos: []*models.Todo{todo},
+			Total: &total,
+			Next:  &models.TodoCursor{Key: todo},
+			Prev:  &models.TodoCursor{Before: true, Key: todo},
+		}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Meta map[string]interface{} `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, float64(2), body.Meta["page"])
+		assert.Equal(t, float64(3), body.Meta["total_count"])
+		assert.Equal(t, float64(3), body.Meta["page_count"])
+		assert.NotEmpty(t, body.Meta["next_cursor"])
+		assert.NotEmpty(t, body.Meta["prev_cursor"])
 
 		// Check if the mock called
 		mockService.AssertExpectations(t)
 	})
-	t.Run(WhenSuccess200OK, func(t *testing.T) {
+	t.Run("when return 400 bad request (invalid cursor)", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		cursor, err := (&models.TodoCursor{Sort: models.ParseTodoSort("title"), Key: &models.Todo{}}).Encode()
 		assert.NoError(t, err)
 
-		req.Header.Set("Content-Type", "application/json")
+		tests := []struct {
+			name  string
+			query string
+		}{
+			{name: "not signed", query: "cursor=eyJpZCI6IjAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMCJ9.forged"},
+			{name: "another sort", query: "sort=-title&cursor=" + cursor},
+			{name: "with a page", query: "page=2&cursor=" + cursor},
+		}
 
-		mockService := new(mockServices.TodoService)
-		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Todo{}, nil)
-		tp := trace.NewTracerProvider()
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?"+tt.query, nil)
+				assert.NoError(t, err)
 
-		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+				mockService := new(mockServices.TodoService)
+				tp := trace.NewTracerProvider()
 
-		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.GetByID)
-		handler.ServeHTTP(rr, req)
+				todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
-		// Check the status code is what expected
-		assert.Equal(t, http.StatusOK, rr.Code)
+				rr := httptest.NewRecorder()
+				handler := http.HandlerFunc(todoHandler.GetAll)
+				handler.ServeHTTP(rr, req)
 
-		// Check if the mock called
-		mockService.AssertExpectations(t)
-	})
-}
+				// Check the status code is what expected
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
 
-// TestTodoUpdate - testing update [200]
-func TestTodoUpdate(t *testing.T) {
-	t.Run(WhenError400EOF, func(t *testing.T) {
+				// Check if the mock called
+				mockService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+	t.Run("when searching", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodPut, "/api/v1/product?id=1", bytes.NewReader([]byte("")))
+		req, err := http.NewRequest(http.MethodGet, `/api/v1/todo?search=milk+-"oat+milk"`, nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
 		mockService.On(
-			"Update",
+			"GetAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("*models.Todo"),
-		).Return(&models.Todo{}, nil)
+			models.TodoFilter{Search: `milk -"oat milk"`},
+			models.TodoSort{{Field: models.SortScore, Desc: true}},
+			mock.AnythingOfType("models.Pagination"),
+		).Return(&models.TodoPage{Todos: []*models.Todo{{Title: "Buy milk", Score: 2}}}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Update)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"score":2`)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
 	})
-	t.Run(WhenError400Validation, func(t *testing.T) {
+	t.Run("when return 400 bad request (score without search)", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		mockPostBody := map[string]interface{}{
-			"title":       "",
-			"description": "",
-		}
-		body, _ := json.Marshal(mockPostBody)
-
-		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?sort=-score", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
 		tp := trace.NewTracerProvider()
@@ -358,156 +437,762 @@ func TestTodoUpdate(t *testing.T) {
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Update)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 
 		// Check if the mock called
-		mockService.AssertExpectations(t)
+		mockService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
-	t.Run(WhenError404NotFound, func(t *testing.T) {
+	t.Run("when return 400 bad request (invalid due date)", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		mockPostBody := map[string]interface{}{
-			"title":       "a",
-			"description": "a",
-		}
-		body, _ := json.Marshal(mockPostBody)
-
-		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?due_before=tomorrow", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On(
-			"Update",
-			mock.Anything,
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("*models.Todo"),
-		).Return(nil, ErrNotFound)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Update)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusNotFound, rr.Code)
-
-		// Check if the mock called
-		mockService.AssertExpectations(t)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "due_before")
 	})
-	t.Run(WhenError500Service, func(t *testing.T) {
+	t.Run("when return 400 bad request (unknown status)", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		mockPostBody := map[string]interface{}{
-			"title":       "a",
-			"description": "a",
-		}
-		body, _ := json.Marshal(mockPostBody)
-
-		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?status=blocked", nil)
 		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On(
-			"Update",
-			mock.Anything,
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("*models.Todo"),
-		).Return(nil, ErrDefault)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Update)
+		handler := http.HandlerFunc(todoHandler.GetAll)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
 
 		// Check if the mock called
-		mockService.AssertExpectations(t)
+		mockService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
-	t.Run(WhenSuccess200OK, func(t *testing.T) {
-		utils.InitializeValidator()
+}
 
-		mockPostBody := map[string]interface{}{
-			"title":       "a",
-			"description": "a",
-		}
-		body, _ := json.Marshal(mockPostBody)
+// TestTodoCreate - testing create [201]
+func TestTodoCreate(t *testing.T) {
+	t.Run(WhenError400EOF, func(t *testing.T) {
+		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader([]byte("")))
 		assert.NoError(t, err)
 		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On(
-			"Update",
-			mock.Anything,
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("*models.Todo"),
-		).Return(&models.Todo{}, nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Update)
+		handler := http.HandlerFunc(todoHandler.Create)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusOK, rr.Code)
-
-		// Check if the mock called
-		mockService.AssertExpectations(t)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
 	})
-}
-
-// TestDeleteSuccess - testing delete [200]
-func TestTodoDelete(t *testing.T) {
-	t.Run(WhenError404NotFound, func(t *testing.T) {
+	t.Run("when return 400 bad request (error validation) ", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodDelete, "/api/v1/product?id=", nil)
+		mockPostBody := map[string]interface{}{
+			"title":       "",
+			"description": "",
+		}
+		body, _ := json.Marshal(mockPostBody)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader(body))
 		assert.NoError(t, err)
 		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(ErrNotFound)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Delete)
+		handler := http.HandlerFunc(todoHandler.Create)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
-		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
 	})
-	t.Run(WhenError500Service, func(t *testing.T) {
+	t.Run("when error 500 internal error (error service)", func(t *testing.T) {
 		utils.InitializeValidator()
 
-		req, err := http.NewRequest(http.MethodDelete, "/api/v1/todo?id=1", nil)
+		mockPostBody := map[string]interface{}{
+			"title":       "lorem ipsum",
+			"description": "desc",
+		}
+		body, _ := json.Marshal(mockPostBody)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader(body))
 		assert.NoError(t, err)
 		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(ErrDefault)
+		mockService.On("Create", mock.Anything, mock.AnythingOfType("*models.Todo")).Return(nil, errors.New(""))
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(todoHandler.Delete)
+		handler := http.HandlerFunc(todoHandler.Create)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 201 created", func(t *testing.T) {
+		utils.InitializeValidator()
+
+		mockPostBody := map[string]interface{}{
+			"title":       "lorem ipsum",
+			"description": "desc",
+		}
+		body, _ := json.Marshal(mockPostBody)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo", bytes.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Create", mock.Anything, mock.AnythingOfType("*models.Todo")).Return(&models.Todo{}, nil)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Create)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestTodoGetByID - testing GetByID [200]
+func TestTodoGetByID(t *testing.T) {
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrNotFound)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError500Service, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrDefault)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when log the error with the handler span", func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrDefault)
+		recorder := tracetest.NewSpanRecorder()
+		tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+		hook := logtest.NewGlobal()
+		defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		spans := recorder.Ended()
+		if assert.Len(t, spans, 1) && assert.NotNil(t, hook.LastEntry()) {
+			assert.Equal(t, spans[0].SpanContext(), oteltrace.SpanContextFromContext(hook.LastEntry().Context))
+		}
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Todo{}, nil)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestTodoGetByIDDomainErrors - testing GetByID mapping of domain errors
+func TestTodoGetByIDDomainErrors(t *testing.T) {
+	t.Run(WhenError400InvalidID, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=abc", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrInvalidID)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError409Conflict, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/todo?id=1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("GetByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrConflict)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.GetByID)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestTodoUpdate - testing update [200]
+func TestTodoUpdate(t *testing.T) {
+	t.Run(WhenError400EOF, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/product?id=1", bytes.NewReader([]byte("")))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"Update",
+			mock.Anything,
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("*models.Todo"),
+			mock.AnythingOfType("int64"),
+		).Return(&models.Todo{}, nil)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Update)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run(WhenError400Validation, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		mockPostBody := map[string]interface{}{
+			"title":       "",
+			"description": "",
+		}
+		body, _ := json.Marshal(mockPostBody)
+
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Update)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		mockPostBody := map[string]interface{}{
+			"title":       "a",
+			"description": "a",
+		}
+		body, _ := json.Marshal(mockPostBody)
+
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"Update",
+			mock.Anything,
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("*models.Todo"),
+			mock.AnythingOfType("int64"),
+		).Return(nil, ErrNotFound)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Update)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError500Service, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		mockPostBody := map[string]interface{}{
+			"title":       "a",
+			"description": "a",
+		}
+		body, _ := json.Marshal(mockPostBody)
+
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"Update",
+			mock.Anything,
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("*models.Todo"),
+			mock.AnythingOfType("int64"),
+		).Return(nil, ErrDefault)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Update)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		mockPostBody := map[string]interface{}{
+			"title":       "a",
+			"description": "a",
+		}
+		body, _ := json.Marshal(mockPostBody)
+
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/todo?id=1", bytes.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"Update",
+			mock.Anything,
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("*models.Todo"),
+			mock.AnythingOfType("int64"),
+		).Return(&models.Todo{Title: "a", Description: "a", Status: models.StatusTodo}, nil)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Update)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"title":"a"`)
+		assert.Contains(t, rr.Body.String(), `"status":"todo"`)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestTodoPatch - testing patch [200]
+func TestTodoPatch(t *testing.T) {
+	t.Run("when return 415 unsupported media type", func(t *testing.T) {
+		for _, contentType := range []string{"", "application/json", "text/plain"} {
+			req, err := http.NewRequest(http.MethodPatch, "/api/v1/todo/1", bytes.NewReader([]byte(`{"title":"a"}`)))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			mockService := new(mockServices.TodoService)
+			tp := trace.NewTracerProvider()
+
+			todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(todoHandler.Patch)
+			handler.ServeHTTP(rr, req)
+
+			// Check the status code is what expected
+			assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code, contentType)
+
+			// Check if the mock called
+			mockService.AssertExpectations(t)
+		}
+	})
+	t.Run(WhenError400EOF, func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPatch, "/api/v1/todo/1", bytes.NewReader([]byte("")))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", models.MergePatchType)
+
+		mockService := new(mockServices.TodoService)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Patch)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError400Validation, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		validationErr := utils.ValidateStruct(&models.TodoRequest{Description: "a"})
+		assert.Error(t, validationErr)
+
+		req, err := http.NewRequest(http.MethodPatch, "/api/v1/todo/1", bytes.NewReader([]byte(`{"title":null}`)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", models.MergePatchType)
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Patch", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*models.TodoPatch"), mock.AnythingOfType("int64")).
+			Return(nil, models.NewError(models.ErrValidation, "TodoService.Patch", "1", validationErr))
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Patch)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "title")
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 409 conflict (test operation failed)", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPatch, "/api/v1/todo/1", bytes.NewReader([]byte(`[{"op":"test","path":"/title","value":"b"}]`)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", models.JSONPatchType)
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Patch", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*models.TodoPatch"), mock.AnythingOfType("int64")).
+			Return(nil, models.NewError(models.ErrConflict, "TodoService.Patch", "1", errors.New("test operation failed")))
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Patch)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPatch, "/api/v1/todo/1", bytes.NewReader([]byte(`{"title":"a"}`)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", models.MergePatchType)
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Patch", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*models.TodoPatch"), mock.AnythingOfType("int64")).
+			Return(nil, ErrNotFound)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Patch)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		router := chi.NewRouter()
+		mockService := new(mockServices.TodoService)
+		mockService.On("Patch", mock.Anything, "1", &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"title":"Buy oat milk"}`),
+		}, mock.AnythingOfType("int64")).Return(&models.Todo{Title: "Buy oat milk", Status: models.StatusTodo}, nil)
+		tp := trace.NewTracerProvider()
+
+		handlers.NewTodoHTTPHandler(router, tp, mockService).RegisterRoutes()
+
+		req, err := http.NewRequest(http.MethodPatch, "/todo/1", bytes.NewReader([]byte(`{"title":"Buy oat milk"}`)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", models.MergePatchType+"; charset=utf-8")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"title":"Buy oat milk"`)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestTodoConditionalRequests - testing ETag, If-None-Match [304] and If-Match [412]
+func TestTodoConditionalRequests(t *testing.T) {
+	newRouter := func(mockService *mockServices.TodoService) *chi.Mux {
+		router := chi.NewRouter()
+		handlers.NewTodoHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+		return router
+	}
+
+	t.Run("when return the ETag of the version", func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("GetByID", mock.Anything, "1").Return(&models.Todo{Title: "a", Version: 3}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/todo/1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("If-None-Match", `"2"`)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+		assert.Contains(t, rr.Body.String(), `"version":3`)
+	})
+	t.Run("when return 304 not modified", func(t *testing.T) {
+		for _, ifNoneMatch := range []string{`"3"`, `W/"3"`, `"1", "3"`, `*`} {
+			mockService := new(mockServices.TodoService)
+			mockService.On("GetByID", mock.Anything, "1").Return(&models.Todo{Title: "a", Version: 3}, nil)
+
+			req, err := http.NewRequest(http.MethodGet, "/todo/1", nil)
+			assert.NoError(t, err)
+			req.Header.Set("If-None-Match", ifNoneMatch)
+
+			rr := httptest.NewRecorder()
+			newRouter(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusNotModified, rr.Code, ifNoneMatch)
+			assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+			assert.Empty(t, rr.Body.String())
+		}
+	})
+	t.Run("when pass the If-Match version to the service", func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Update", mock.Anything, "1", mock.AnythingOfType("*models.Todo"), int64(3)).
+			Return(&models.Todo{Title: "a", Description: "a", Version: 4}, nil)
+
+		req, err := http.NewRequest(http.MethodPut, "/todo/1", bytes.NewReader([]byte(`{"title":"a","description":"a"}`)))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 412 precondition failed (stale version)", func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Delete", mock.Anything, "1", int64(2)).
+			Return(models.NewError(models.ErrPreconditionFailed, "TodoService.Delete", "1", nil))
+
+		req, err := http.NewRequest(http.MethodDelete, "/todo/1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("If-Match", `"2"`)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 412 precondition failed (not a version ETag)", func(t *testing.T) {
+		for _, ifMatch := range []string{`W/"3"`, `3`, `"abc"`, `"0"`, `"1", "3"`} {
+			mockService := new(mockServices.TodoService)
+
+			req, err := http.NewRequest(http.MethodPatch, "/todo/1", bytes.NewReader([]byte(`{"title":"a"}`)))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", models.MergePatchType)
+			req.Header.Set("If-Match", ifMatch)
+
+			rr := httptest.NewRecorder()
+			newRouter(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusPreconditionFailed, rr.Code, ifMatch)
+			mockService.AssertExpectations(t)
+		}
+	})
+	t.Run("when any version matches", func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Delete", mock.Anything, "1", int64(0)).Return(nil)
+
+		req, err := http.NewRequest(http.MethodDelete, "/todo/1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("If-Match", "*")
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestDeleteSuccess - testing delete [200]
+func TestTodoDelete(t *testing.T) {
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/v1/product?id=", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Delete", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("int64")).Return(ErrNotFound)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Delete)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run(WhenError500Service, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/v1/todo?id=1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Delete", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("int64")).Return(ErrDefault)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Delete)
 		handler.ServeHTTP(rr, req)
 
 		// Check the status code is what expected
@@ -524,7 +1209,7 @@ func TestTodoDelete(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		mockService := new(mockServices.TodoService)
-		mockService.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+		mockService.On("Delete", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("int64")).Return(nil)
 		tp := trace.NewTracerProvider()
 
 		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
@@ -540,3 +1225,374 @@ func TestTodoDelete(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 }
+
+// TestTodoComplete - testing complete [200]
+func TestTodoComplete(t *testing.T) {
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo/1/complete", nil)
+		assert.NoError(t, err)
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Complete", mock.Anything, mock.AnythingOfType("string"), int64(0)).Return(nil, ErrNotFound)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Complete)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 409 conflict (invalid transition)", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo/1/complete", nil)
+		assert.NoError(t, err)
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Complete", mock.Anything, mock.AnythingOfType("string"), int64(0)).Return(nil, ErrInvalidTransition)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Complete)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(), "cannot change status from archived to done")
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 412 precondition failed", func(t *testing.T) {
+		router := chi.NewRouter()
+		mockService := new(mockServices.TodoService)
+		mockService.On("Complete", mock.Anything, "1", int64(2)).
+			Return(nil, models.NewError(models.ErrPreconditionFailed, "TodoService.Complete", "1", nil))
+
+		handlers.NewTodoHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+
+		for _, ifMatch := range []string{`"2"`, `W/"2"`} {
+			req, err := http.NewRequest(http.MethodPost, "/todo/1/complete", nil)
+			assert.NoError(t, err)
+			req.Header.Set("If-Match", ifMatch)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusPreconditionFailed, rr.Code, ifMatch)
+		}
+		mockService.AssertNumberOfCalls(t, "Complete", 1)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		completedAt := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+
+		router := chi.NewRouter()
+		mockService := new(mockServices.TodoService)
+		mockService.On("Complete", mock.Anything, "1", int64(3)).
+			Return(&models.Todo{Title: "Buy milk", Status: models.StatusDone, CompletedAt: &completedAt, Version: 4}, nil)
+		tp := trace.NewTracerProvider()
+
+		handlers.NewTodoHTTPHandler(router, tp, mockService).RegisterRoutes()
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/complete", nil)
+		assert.NoError(t, err)
+		req.Header.Set("If-Match", `"3"`)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+		assert.Contains(t, rr.Body.String(), `"status":"done"`)
+		assert.Contains(t, rr.Body.String(), `"completed_at":"2022-12-01T10:00:00Z"`)
+		// The whole todo is answered, like the other writes
+		assert.Contains(t, rr.Body.String(), `"title":"Buy milk"`)
+		assert.Contains(t, rr.Body.String(), `"version":4`)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestTodoReopen - testing reopen [200]
+func TestTodoReopen(t *testing.T) {
+	t.Run(WhenError500Service, func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/todo/1/reopen", nil)
+		assert.NoError(t, err)
+
+		mockService := new(mockServices.TodoService)
+		mockService.On("Reopen", mock.Anything, mock.AnythingOfType("string"), int64(0)).Return(nil, ErrDefault)
+		tp := trace.NewTracerProvider()
+
+		todoHandler := handlers.NewTodoHTTPHandler(chi.NewRouter(), tp, mockService)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(todoHandler.Reopen)
+		handler.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		router := chi.NewRouter()
+		mockService := new(mockServices.TodoService)
+		mockService.On("Reopen", mock.Anything, "1", int64(0)).Return(&models.Todo{Status: models.StatusTodo, Version: 5}, nil)
+		tp := trace.NewTracerProvider()
+
+		handlers.NewTodoHTTPHandler(router, tp, mockService).RegisterRoutes()
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/reopen", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		// Check the status code is what expected
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"5"`, rr.Header().Get("ETag"))
+		assert.Contains(t, rr.Body.String(), `"status":"todo"`)
+		assert.Contains(t, rr.Body.String(), `"completed_at":null`)
+
+		// Check if the mock called
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestTodoGetTrash(t *testing.T) {
+	newRouter := func(mockService *mockServices.TodoService) *chi.Mux {
+		router := chi.NewRouter()
+		handlers.NewTodoHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+		return router
+	}
+
+	t.Run("when list the trash, most recently deleted first", func(t *testing.T) {
+		utils.InitializeValidator()
+
+		deletedAt := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+		total := 1
+		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"GetAll",
+			mock.Anything,
+			mock.MatchedBy(func(filter models.TodoFilter) bool { return filter.Deleted }),
+			models.TodoSort{{Field: models.SortDeletedAt, Desc: true}},
+			mock.AnythingOfType("models.Pagination"),
+		).Return(&models.TodoPage{Todos: []*models.Todo{{Title: "a", DeletedAt: &deletedAt}}, Total: &total}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/todo/trash", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"deleted_at":"2022-12-01T00:00:00Z"`)
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when list the trash with a sort", func(t *testing.T) {
+		utils.InitializeValidator()
+
+		mockService := new(mockServices.TodoService)
+		mockService.On(
+			"GetAll",
+			mock.Anything,
+			mock.MatchedBy(func(filter models.TodoFilter) bool { return filter.Deleted && filter.Keyword == "milk" }),
+			models.TodoSort{{Field: models.SortTitle}},
+			mock.AnythingOfType("models.Pagination"),
+		).Return(&models.TodoPage{Todos: []*models.Todo{}}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/todo/trash?q=milk&sort=title", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError400Validation, func(t *testing.T) {
+		utils.InitializeValidator()
+
+		mockService := new(mockServices.TodoService)
+
+		req, err := http.NewRequest(http.MethodGet, "/todo/trash?sort=deleted", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockService.AssertNotCalled(t, "GetAll")
+	})
+}
+
+func TestTodoRestore(t *testing.T) {
+	newRouter := func(mockService *mockServices.TodoService) *chi.Mux {
+		router := chi.NewRouter()
+		handlers.NewTodoHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+		return router
+	}
+
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Restore", mock.Anything, "1").Return(nil, ErrNotFound)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/restore", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError500Service, func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Restore", mock.Anything, "1").Return(nil, ErrDefault)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/restore", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Restore", mock.Anything, "1").Return(&models.Todo{Title: "a", Version: 3}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/restore", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+		assert.Contains(t, rr.Body.String(), `"title":"a"`)
+		assert.NotContains(t, rr.Body.String(), `"deleted_at"`)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestTodoHistory(t *testing.T) {
+	newRouter := func(mockService *mockServices.TodoService) *chi.Mux {
+		router := chi.NewRouter()
+		handlers.NewTodoHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+		return router
+	}
+
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("History", mock.Anything, "1").Return(nil, ErrNotFound)
+
+		req, err := http.NewRequest(http.MethodGet, "/todo/1/history", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("History", mock.Anything, "1").Return([]*models.Revision{{
+			Rev:     1,
+			Action:  models.ActionCreate,
+			Changes: []models.FieldChange{{Field: models.FieldTitle, To: "a"}},
+			Actor:   "alice",
+			TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		}}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/todo/1/history", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"rev":1,"action":"create","changes":[{"field":"title","from":null,"to":"a"}],"actor":"alice","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestTodoRevert(t *testing.T) {
+	newRouter := func(mockService *mockServices.TodoService) *chi.Mux {
+		router := chi.NewRouter()
+		handlers.NewTodoHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+		return router
+	}
+
+	t.Run("when return 400 bad request (invalid revision)", func(t *testing.T) {
+		for _, rev := range []string{"abc", "0", "-1"} {
+			mockService := new(mockServices.TodoService)
+
+			req, err := http.NewRequest(http.MethodPost, "/todo/1/revert/"+rev, nil)
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			newRouter(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, rev)
+			assert.Contains(t, rr.Body.String(), "Invalid revision")
+			mockService.AssertNotCalled(t, "Revert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Revert", mock.Anything, "1", int64(9), int64(0)).Return(nil, ErrNotFound)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/revert/9", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run("when return 412 precondition failed (stale version)", func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Revert", mock.Anything, "1", int64(1), int64(2)).
+			Return(nil, models.NewError(models.ErrPreconditionFailed, "TodoService.Revert", "1", nil))
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/revert/1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("If-Match", `"2"`)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+		mockService.On("Revert", mock.Anything, "1", int64(1), int64(3)).Return(&models.Todo{Title: "a", Version: 4}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/todo/1/revert/1", nil)
+		assert.NoError(t, err)
+		req.Header.Set("If-Match", `"3"`)
+
+		rr := httptest.NewRecorder()
+		newRouter(mockService).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+		assert.Contains(t, rr.Body.String(), `"title":"a"`)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/services"
+	"go-distributed-tracing/utils"
+	response "go-distributed-tracing/utils/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// webhookHandler represent the http handler of webhooks
+type webhookHandler struct {
+	router         *chi.Mux
+	tp             *sdktrace.TracerProvider
+	webhookService services.WebhookService
+}
+
+// NewWebhookHTTPHandler - make webhook http handler
+func NewWebhookHTTPHandler(router *chi.Mux, tp *sdktrace.TracerProvider, service services.WebhookService) *webhookHandler {
+	return &webhookHandler{
+		router:         router,
+		tp:             tp,
+		webhookService: service,
+	}
+}
+
+func (handler *webhookHandler) RegisterRoutes() {
+	handler.router.Get("/webhooks", handler.GetAll)
+	handler.router.Get("/webhooks/dead-letters", handler.DeadLetters)
+	handler.router.Get("/webhooks/{id}", handler.GetByID)
+	handler.router.Post("/webhooks", handler.Create)
+	handler.router.Put("/webhooks/{id}", handler.Update)
+	handler.router.Delete("/webhooks/{id}", handler.Delete)
+	handler.router.Get("/webhooks/{id}/deliveries", handler.Deliveries)
+	handler.router.Post("/webhooks/deliveries/{id}/retry", handler.Retry)
+}
+
+// bindWebhook - bind the webhook request of r, answering the error when it fails
+func bindWebhook(w http.ResponseWriter, r *http.Request, span trace.Span) (*models.WebhookRequest, bool) {
+	data := &models.WebhookRequest{}
+	if err := render.Bind(r, data); err != nil {
+		// The body is missing or isn't JSON
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassBadRequest)
+
+			response.ResponseBodyError(w, r, err)
+			return nil, false
+		}
+
+		pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassValidation)
+
+		response.ResponseErrorValidation(w, r, err)
+		return nil, false
+	}
+
+	return data, true
+}
+
+// GetAll - get all webhook http handler
+func (handler *webhookHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.GetAll")
+	defer span.End()
+
+	result, err := handler.webhookService.GetAll(ctx)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
+
+// GetByID - get webhook by id http handler
+func (handler *webhookHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.GetByID")
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	result, err := handler.webhookService.GetByID(ctx, id)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
+
+// Create - create webhook http handler
+func (handler *webhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.Create")
+	defer span.End()
+
+	data, ok := bindWebhook(w, r, span)
+	if !ok {
+		return
+	}
+
+	result, err := handler.webhookService.Create(ctx, data.Webhook())
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseCreated(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
+
+// Update - update webhook by id http handler
+func (handler *webhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.Update")
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	data, ok := bindWebhook(w, r, span)
+	if !ok {
+		return
+	}
+
+	result, err := handler.webhookService.Update(ctx, id, data.Webhook())
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
+
+// Delete - delete webhook by id and its deliveries http handler
+func (handler *webhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.Delete")
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	if err := handler.webhookService.Delete(ctx, id); err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: response.H{
+			"id": id,
+		},
+	})
+}
+
+// Deliveries - get the delivery log of webhook by id http handler, newest first
+func (handler *webhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.Deliveries")
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	handler.listDeliveries(w, r, span, func(status models.DeliveryStatus, limit int, offset int) ([]*models.Delivery, error) {
+		return handler.webhookService.Deliveries(ctx, id, status, limit, offset)
+	})
+}
+
+// DeadLetters - get the dead deliveries of every webhook http handler, newest first
+func (handler *webhookHandler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.DeadLetters")
+	defer span.End()
+
+	handler.listDeliveries(w, r, span, func(_ models.DeliveryStatus, limit int, offset int) ([]*models.Delivery, error) {
+		return handler.webhookService.DeadLetters(ctx, limit, offset)
+	})
+}
+
+// listDeliveries - respond with the page of deliveries find gets for the query of r
+func (handler *webhookHandler) listDeliveries(w http.ResponseWriter, r *http.Request, span trace.Span, find func(status models.DeliveryStatus, limit int, offset int) ([]*models.Delivery, error)) {
+	query := r.URL.Query()
+	listRequest := &models.DeliveryListRequest{
+		Status:  query.Get("status"),
+		Page:    query.Get("page"),
+		PerPage: query.Get("per_page"),
+	}
+	if err := utils.ValidateStruct(listRequest); err != nil {
+		pkg_tracing.RecordHTTPError(span, err, http.StatusBadRequest, pkg_tracing.ErrorClassValidation)
+
+		response.ResponseErrorValidation(w, r, err)
+		return
+	}
+
+	currentPage := utils.CurrentPage(listRequest.Page)
+	perPage := utils.PerPage(listRequest.PerPage)
+
+	result, err := find(models.DeliveryStatus(listRequest.Status), perPage, utils.Offset(currentPage, perPage))
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseOKList(w, r, &response.ResponseSuccessList{
+		Data: result,
+		Meta: &response.Meta{
+			PerPage:     perPage,
+			CurrentPage: currentPage,
+		},
+	})
+}
+
+// Retry - queue a dead delivery by id for another attempt http handler
+func (handler *webhookHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	ctx, span := handler.tp.Tracer("webhookHandler").Start(r.Context(), "webhookHandler.Retry")
+	defer span.End()
+
+	// Get and filter id param
+	id := chi.URLParam(r, "id")
+
+	result, err := handler.webhookService.Retry(ctx, id)
+	if err != nil {
+		responseServiceError(w, r, span, err)
+		return
+	}
+
+	response.ResponseOK(w, r, &response.ResponseSuccess{
+		Data: result,
+	})
+}
@@ -0,0 +1,232 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	handlers "go-distributed-tracing/todo/delivery/http"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+
+	mockServices "go-distributed-tracing/todo/mocks/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+var webhookID = primitive.NewObjectID().Hex()
+
+// newWebhookRouter - router serving the webhook routes with mockService
+func newWebhookRouter(mockService *mockServices.WebhookService) *chi.Mux {
+	router := chi.NewRouter()
+	handlers.NewWebhookHTTPHandler(router, trace.NewTracerProvider(), mockService).RegisterRoutes()
+	return router
+}
+
+// serveWebhook - serve a request with body to the webhook routes with mockService
+func serveWebhook(t *testing.T, mockService *mockServices.WebhookService, method string, target string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, err := http.NewRequest(method, target, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	newWebhookRouter(mockService).ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestWebhookCreate(t *testing.T) {
+	body := `{"url": "https://example.com/hook", "events": ["todo.created", "todo.created"], "secret": "0123456789abcdef"}`
+
+	t.Run(WhenError400EOF, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+
+		for _, body := range []string{"", "{"} {
+			rr := serveWebhook(t, mockService, http.MethodPost, "/webhooks", body)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), "Check your body request")
+		}
+	})
+	t.Run(WhenError400Validation, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+
+		rr := serveWebhook(t, mockService, http.MethodPost, "/webhooks", `{"url": "ftp://example.com", "secret": "short"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"url"`)
+		assert.Contains(t, rr.Body.String(), `"secret"`)
+		mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+	t.Run(WhenError500Service, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Create", mock.Anything, mock.AnythingOfType("*models.Webhook")).Return(nil, ErrDefault)
+
+		rr := serveWebhook(t, mockService, http.MethodPost, "/webhooks", body)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+	t.Run(WhenSuccess201Created, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Create", mock.Anything, &models.Webhook{
+			URL:    "https://example.com/hook",
+			Events: []models.EventType{models.EventTodoCreated},
+			Secret: "0123456789abcdef",
+		}).Return(&models.Webhook{
+			ID:     primitive.NewObjectID(),
+			URL:    "https://example.com/hook",
+			Events: []models.EventType{models.EventTodoCreated},
+			Secret: "0123456789abcdef",
+		}, nil)
+
+		rr := serveWebhook(t, mockService, http.MethodPost, "/webhooks", body)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"url":"https://example.com/hook"`)
+		// The secret is never answered back
+		assert.NotContains(t, rr.Body.String(), "0123456789abcdef")
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestWebhookUpdate(t *testing.T) {
+	body := `{"url": "https://example.com/hook", "secret": "0123456789abcdef"}`
+
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Update", mock.Anything, webhookID, mock.AnythingOfType("*models.Webhook")).
+			Return(nil, models.NewError(models.ErrNotFound, "WebhookRepository.Update", webhookID, nil))
+
+		rr := serveWebhook(t, mockService, http.MethodPut, "/webhooks/"+webhookID, body)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Update", mock.Anything, webhookID, mock.AnythingOfType("*models.Webhook")).
+			Return(&models.Webhook{URL: "https://example.com/hook"}, nil)
+
+		rr := serveWebhook(t, mockService, http.MethodPut, "/webhooks/"+webhookID, body)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestWebhookGetAndDelete(t *testing.T) {
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		mockService := new(mockServices.WebhookService)
+		mockService.On("GetAll", mock.Anything).Return([]*models.Webhook{{URL: "https://example.com/hook"}}, nil)
+		mockService.On("GetByID", mock.Anything, webhookID).Return(&models.Webhook{URL: "https://example.com/hook"}, nil)
+		mockService.On("Delete", mock.Anything, webhookID).Return(nil)
+
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			rr := serveWebhook(t, mockService, method, "/webhooks/"+webhookID, "")
+			assert.Equal(t, http.StatusOK, rr.Code, method)
+		}
+		rr := serveWebhook(t, mockService, http.MethodGet, "/webhooks", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "https://example.com/hook")
+		mockService.AssertExpectations(t)
+	})
+	t.Run(WhenError400InvalidID, func(t *testing.T) {
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Delete", mock.Anything, "abc").
+			Return(models.NewError(models.ErrInvalidID, "WebhookRepository.Delete", "abc", nil))
+
+		rr := serveWebhook(t, mockService, http.MethodDelete, "/webhooks/abc", "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	t.Run(WhenError400Validation, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+
+		rr := serveWebhook(t, mockService, http.MethodGet, "/webhooks/"+webhookID+"/deliveries?status=failed", "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"status"`)
+		mockService.AssertNotCalled(t, "Deliveries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run(WhenError404NotFound, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Deliveries", mock.Anything, webhookID, models.DeliveryStatus(""), 10, 0).
+			Return(nil, models.NewError(models.ErrNotFound, "WebhookRepository.FindById", webhookID, nil))
+
+		rr := serveWebhook(t, mockService, http.MethodGet, "/webhooks/"+webhookID+"/deliveries", "")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		utils.InitializeValidator()
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Deliveries", mock.Anything, webhookID, models.DeliveryPending, 5, 5).
+			Return([]*models.Delivery{{EventType: models.EventTodoCreated, Status: models.DeliveryPending}}, nil)
+
+		rr := serveWebhook(t, mockService, http.MethodGet, "/webhooks/"+webhookID+"/deliveries?status=pending&page=2&per_page=5", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []*models.Delivery `json:"data"`
+			Meta map[string]int     `json:"meta"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Len(t, body.Data, 1)
+		assert.Equal(t, map[string]int{"page": 2, "per_page": 5}, body.Meta)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+	utils.InitializeValidator()
+	mockService := new(mockServices.WebhookService)
+	mockService.On("DeadLetters", mock.Anything, 10, 0).
+		Return([]*models.Delivery{{EventType: models.EventTodoDeleted, Status: models.DeliveryDead}}, nil)
+
+	rr := serveWebhook(t, mockService, http.MethodGet, "/webhooks/dead-letters", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status":"dead"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestWebhookRetry(t *testing.T) {
+	t.Run(WhenError409Conflict, func(t *testing.T) {
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Retry", mock.Anything, webhookID).
+			Return(nil, models.NewError(models.ErrConflict, "WebhookService.Retry", webhookID, nil))
+
+		rr := serveWebhook(t, mockService, http.MethodPost, "/webhooks/deliveries/"+webhookID+"/retry", "")
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+	t.Run(WhenSuccess200OK, func(t *testing.T) {
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Retry", mock.Anything, webhookID).Return(&models.Delivery{Status: models.DeliveryPending}, nil)
+
+		rr := serveWebhook(t, mockService, http.MethodPost, "/webhooks/deliveries/"+webhookID+"/retry", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"status":"pending"`)
+	})
+}
@@ -7,6 +7,10 @@ import (
 	models "go-distributed-tracing/todo/models"
 
 	mock "github.com/stretchr/testify/mock"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	time "time"
 )
 
 // TodoRepository is an autogenerated mock type for the TodoRepository type
@@ -14,20 +18,22 @@ type TodoRepository struct {
 	mock.Mock
 }
 
-// CountFindAll provides a mock function with given fields: ctx, keyword
-func (_m *TodoRepository) CountFindAll(ctx context.Context, keyword string) (int, error) {
-	ret := _m.Called(ctx, keyword)
+// BulkWrite provides a mock function with given fields: ctx, writes, atomic
+func (_m *TodoRepository) BulkWrite(ctx context.Context, writes []*models.TodoWrite, atomic bool) ([]*models.BulkResult, error) {
+	ret := _m.Called(ctx, writes, atomic)
 
-	var r0 int
-	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
-		r0 = rf(ctx, keyword)
+	var r0 []*models.BulkResult
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.TodoWrite, bool) []*models.BulkResult); ok {
+		r0 = rf(ctx, writes, atomic)
 	} else {
-		r0 = ret.Get(0).(int)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.BulkResult)
+		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, keyword)
+	if rf, ok := ret.Get(1).(func(context.Context, []*models.TodoWrite, bool) error); ok {
+		r1 = rf(ctx, writes, atomic)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -35,20 +41,43 @@ func (_m *TodoRepository) CountFindAll(ctx context.Context, keyword string) (int
 	return r0, r1
 }
 
-// CountFindByID provides a mock function with given fields: ctx, id
-func (_m *TodoRepository) CountFindByID(ctx context.Context, id string) (int, error) {
-	ret := _m.Called(ctx, id)
+// ClaimEvents provides a mock function with given fields: ctx, now, until, limit
+func (_m *TodoRepository) ClaimEvents(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Event, error) {
+	ret := _m.Called(ctx, now, until, limit)
+
+	var r0 []*models.Event
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time, int) []*models.Event); ok {
+		r0 = rf(ctx, now, until, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Event)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Time, int) error); ok {
+		r1 = rf(ctx, now, until, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountFindAll provides a mock function with given fields: ctx, filter
+func (_m *TodoRepository) CountFindAll(ctx context.Context, filter models.TodoFilter) (int, error) {
+	ret := _m.Called(ctx, filter)
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, models.TodoFilter) int); ok {
+		r0 = rf(ctx, filter)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, id)
+	if rf, ok := ret.Get(1).(func(context.Context, models.TodoFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -56,13 +85,13 @@ func (_m *TodoRepository) CountFindByID(ctx context.Context, id string) (int, er
 	return r0, r1
 }
 
-// Delete provides a mock function with given fields: ctx, id
-func (_m *TodoRepository) Delete(ctx context.Context, id string) error {
-	ret := _m.Called(ctx, id)
+// Delete provides a mock function with given fields: ctx, id, version
+func (_m *TodoRepository) Delete(ctx context.Context, id string, version int64) error {
+	ret := _m.Called(ctx, id, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -70,13 +99,27 @@ func (_m *TodoRepository) Delete(ctx context.Context, id string) error {
 	return r0
 }
 
-// FindAll provides a mock function with given fields: ctx, keyword, limit, offset
-func (_m *TodoRepository) FindAll(ctx context.Context, keyword string, limit int, offset int) ([]*models.Todo, error) {
-	ret := _m.Called(ctx, keyword, limit, offset)
+// DeleteEvents provides a mock function with given fields: ctx, ids
+func (_m *TodoRepository) DeleteEvents(ctx context.Context, ids []primitive.ObjectID) error {
+	ret := _m.Called(ctx, ids)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []primitive.ObjectID) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindAll provides a mock function with given fields: ctx, filter, sort, limit, offset
+func (_m *TodoRepository) FindAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, limit int, offset int) ([]*models.Todo, error) {
+	ret := _m.Called(ctx, filter, sort, limit, offset)
 
 	var r0 []*models.Todo
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []*models.Todo); ok {
-		r0 = rf(ctx, keyword, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, models.TodoFilter, models.TodoSort, int, int) []*models.Todo); ok {
+		r0 = rf(ctx, filter, sort, limit, offset)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Todo)
@@ -84,8 +127,8 @@ func (_m *TodoRepository) FindAll(ctx context.Context, keyword string, limit int
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
-		r1 = rf(ctx, keyword, limit, offset)
+	if rf, ok := ret.Get(1).(func(context.Context, models.TodoFilter, models.TodoSort, int, int) error); ok {
+		r1 = rf(ctx, filter, sort, limit, offset)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -116,6 +159,96 @@ func (_m *TodoRepository) FindById(ctx context.Context, id string) (*models.Todo
 	return r0, r1
 }
 
+// FindRevisions provides a mock function with given fields: ctx, id
+func (_m *TodoRepository) FindRevisions(ctx context.Context, id string) ([]*models.Revision, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 []*models.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.Revision); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Patch provides a mock function with given fields: ctx, id, value, fields, version
+func (_m *TodoRepository) Patch(ctx context.Context, id string, value *models.Todo, fields []string, version int64) (*models.Todo, error) {
+	ret := _m.Called(ctx, id, value, fields, version)
+
+	var r0 *models.Todo
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.Todo, []string, int64) *models.Todo); ok {
+		r0 = rf(ctx, id, value, fields, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Todo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.Todo, []string, int64) error); ok {
+		r1 = rf(ctx, id, value, fields, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: ctx, before
+func (_m *TodoRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: ctx, id
+func (_m *TodoRepository) Restore(ctx context.Context, id string) (*models.Todo, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *models.Todo
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Todo); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Todo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Store provides a mock function with given fields: ctx, value
 func (_m *TodoRepository) Store(ctx context.Context, value *models.Todo) (*models.Todo, error) {
 	ret := _m.Called(ctx, value)
@@ -139,13 +272,13 @@ func (_m *TodoRepository) Store(ctx context.Context, value *models.Todo) (*model
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: ctx, id, value
-func (_m *TodoRepository) Update(ctx context.Context, id string, value *models.Todo) (*models.Todo, error) {
-	ret := _m.Called(ctx, id, value)
+// Update provides a mock function with given fields: ctx, id, value, version
+func (_m *TodoRepository) Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	ret := _m.Called(ctx, id, value, version)
 
 	var r0 *models.Todo
-	if rf, ok := ret.Get(0).(func(context.Context, string, *models.Todo) *models.Todo); ok {
-		r0 = rf(ctx, id, value)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.Todo, int64) *models.Todo); ok {
+		r0 = rf(ctx, id, value, version)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Todo)
@@ -153,8 +286,8 @@ func (_m *TodoRepository) Update(ctx context.Context, id string, value *models.T
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, *models.Todo) error); ok {
-		r1 = rf(ctx, id, value)
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.Todo, int64) error); ok {
+		r1 = rf(ctx, id, value, version)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -7,6 +7,8 @@ import (
 	models "go-distributed-tracing/todo/models"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // TodoService is an autogenerated mock type for the TodoService type
@@ -14,6 +16,52 @@ type TodoService struct {
 	mock.Mock
 }
 
+// Bulk provides a mock function with given fields: ctx, operations, atomic
+func (_m *TodoService) Bulk(ctx context.Context, operations []*models.BulkOperation, atomic bool) ([]*models.BulkResult, error) {
+	ret := _m.Called(ctx, operations, atomic)
+
+	var r0 []*models.BulkResult
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.BulkOperation, bool) []*models.BulkResult); ok {
+		r0 = rf(ctx, operations, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.BulkResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*models.BulkOperation, bool) error); ok {
+		r1 = rf(ctx, operations, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Complete provides a mock function with given fields: ctx, id, version
+func (_m *TodoService) Complete(ctx context.Context, id string, version int64) (*models.Todo, error) {
+	ret := _m.Called(ctx, id, version)
+
+	var r0 *models.Todo
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) *models.Todo); ok {
+		r0 = rf(ctx, id, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Todo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, id, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, value
 func (_m *TodoService) Create(ctx context.Context, value *models.Todo) (*models.Todo, error) {
 	ret := _m.Called(ctx, value)
@@ -37,13 +85,13 @@ func (_m *TodoService) Create(ctx context.Context, value *models.Todo) (*models.
 	return r0, r1
 }
 
-// Delete provides a mock function with given fields: ctx, id
-func (_m *TodoService) Delete(ctx context.Context, id string) error {
-	ret := _m.Called(ctx, id)
+// Delete provides a mock function with given fields: ctx, id, version
+func (_m *TodoService) Delete(ctx context.Context, id string, version int64) error {
+	ret := _m.Called(ctx, id, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -51,38 +99,144 @@ func (_m *TodoService) Delete(ctx context.Context, id string) error {
 	return r0
 }
 
-// GetAll provides a mock function with given fields: ctx, keyword, limit, offset
-func (_m *TodoService) GetAll(ctx context.Context, keyword string, limit int, offset int) ([]*models.Todo, int, error) {
-	ret := _m.Called(ctx, keyword, limit, offset)
+// GetAll provides a mock function with given fields: ctx, filter, sort, page
+func (_m *TodoService) GetAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, page models.Pagination) (*models.TodoPage, error) {
+	ret := _m.Called(ctx, filter, sort, page)
 
-	var r0 []*models.Todo
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []*models.Todo); ok {
-		r0 = rf(ctx, keyword, limit, offset)
+	var r0 *models.TodoPage
+	if rf, ok := ret.Get(0).(func(context.Context, models.TodoFilter, models.TodoSort, models.Pagination) *models.TodoPage); ok {
+		r0 = rf(ctx, filter, sort, page)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*models.Todo)
+			r0 = ret.Get(0).(*models.TodoPage)
 		}
 	}
 
-	var r1 int
-	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) int); ok {
-		r1 = rf(ctx, keyword, limit, offset)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, models.TodoFilter, models.TodoSort, models.Pagination) error); ok {
+		r1 = rf(ctx, filter, sort, page)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByID provides a mock function with given fields: ctx, id
+func (_m *TodoService) GetByID(ctx context.Context, id string) (*models.Todo, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *models.Todo
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Todo); ok {
+		r0 = rf(ctx, id)
 	} else {
-		r1 = ret.Get(1).(int)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Todo)
+		}
 	}
 
-	var r2 error
-	if rf, ok := ret.Get(2).(func(context.Context, string, int, int) error); ok {
-		r2 = rf(ctx, keyword, limit, offset)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
 	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
-// GetByID provides a mock function with given fields: ctx, id
-func (_m *TodoService) GetByID(ctx context.Context, id string) (*models.Todo, error) {
+// History provides a mock function with given fields: ctx, id
+func (_m *TodoService) History(ctx context.Context, id string) ([]*models.Revision, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 []*models.Revision
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.Revision); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Revision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Patch provides a mock function with given fields: ctx, id, patch, version
+func (_m *TodoService) Patch(ctx context.Context, id string, patch *models.TodoPatch, version int64) (*models.Todo, error) {
+	ret := _m.Called(ctx, id, patch, version)
+
+	var r0 *models.Todo
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.TodoPatch, int64) *models.Todo); ok {
+		r0 = rf(ctx, id, patch, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Todo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.TodoPatch, int64) error); ok {
+		r1 = rf(ctx, id, patch, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: ctx, before
+func (_m *TodoService) Purge(ctx context.Context, before time.Time) (int, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reopen provides a mock function with given fields: ctx, id, version
+func (_m *TodoService) Reopen(ctx context.Context, id string, version int64) (*models.Todo, error) {
+	ret := _m.Called(ctx, id, version)
+
+	var r0 *models.Todo
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) *models.Todo); ok {
+		r0 = rf(ctx, id, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Todo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, id, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: ctx, id
+func (_m *TodoService) Restore(ctx context.Context, id string) (*models.Todo, error) {
 	ret := _m.Called(ctx, id)
 
 	var r0 *models.Todo
@@ -104,13 +258,36 @@ func (_m *TodoService) GetByID(ctx context.Context, id string) (*models.Todo, er
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: ctx, id, value
-func (_m *TodoService) Update(ctx context.Context, id string, value *models.Todo) (*models.Todo, error) {
-	ret := _m.Called(ctx, id, value)
+// Revert provides a mock function with given fields: ctx, id, rev, version
+func (_m *TodoService) Revert(ctx context.Context, id string, rev int64, version int64) (*models.Todo, error) {
+	ret := _m.Called(ctx, id, rev, version)
+
+	var r0 *models.Todo
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64) *models.Todo); ok {
+		r0 = rf(ctx, id, rev, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Todo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, int64) error); ok {
+		r1 = rf(ctx, id, rev, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, id, value, version
+func (_m *TodoService) Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	ret := _m.Called(ctx, id, value, version)
 
 	var r0 *models.Todo
-	if rf, ok := ret.Get(0).(func(context.Context, string, *models.Todo) *models.Todo); ok {
-		r0 = rf(ctx, id, value)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.Todo, int64) *models.Todo); ok {
+		r0 = rf(ctx, id, value, version)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Todo)
@@ -118,8 +295,8 @@ func (_m *TodoService) Update(ctx context.Context, id string, value *models.Todo
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, *models.Todo) error); ok {
-		r1 = rf(ctx, id, value)
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.Todo, int64) error); ok {
+		r1 = rf(ctx, id, value, version)
 	} else {
 		r1 = ret.Error(1)
 	}
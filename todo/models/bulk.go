@@ -0,0 +1,73 @@
+package models
+
+import (
+	"net/http"
+
+	"go-distributed-tracing/utils"
+)
+
+// BulkOp - write of a todo in a bulk
+type BulkOp string
+
+// Bulk operations
+const (
+	BulkCreate BulkOp = "create"
+	BulkUpdate BulkOp = "update"
+	BulkDelete BulkOp = "delete"
+)
+
+// MaxBulkOperations - operations a bulk request may hold, see the validation of BulkRequest.Operations
+const MaxBulkOperations = 1000
+
+// BulkBatchSize - operations a repository sends to the database at once
+const BulkBatchSize = 100
+
+// BulkRequest - bulk request, operations are validated one by one when applied so each gets its own error. Only
+// a null operation fails the validation of the request.
+type BulkRequest struct {
+	// Atomic - write every operation or none, otherwise the valid ones are written
+	Atomic     bool             `json:"atomic"`
+	Operations []*BulkOperation `json:"operations" validate:"required,min=1,max=1000,dive,required,structonly"`
+}
+
+func (br *BulkRequest) Bind(r *http.Request) error {
+	return utils.ValidateStruct(br)
+}
+
+// BulkOperation - operation of a bulk request. Create takes a todo, update an id and a todo and delete an id.
+// Version is checked like If-Match, 0 matches any version.
+type BulkOperation struct {
+	Op      BulkOp       `json:"op" validate:"required,oneof=create update delete"`
+	ID      string       `json:"id" validate:"required_unless=Op create"`
+	Version int64        `json:"version" validate:"min=0"`
+	Todo    *TodoRequest `json:"todo" validate:"required_unless=Op delete"`
+}
+
+// TodoWrite - operation of a bulk as written by a repository, Value is the todo to store or the updated todo
+type TodoWrite struct {
+	Op      BulkOp
+	ID      string
+	Value   *Todo
+	Version int64
+}
+
+// BulkResult - outcome of an operation of a bulk, Todo is the stored or updated todo. Err is set when the
+// operation failed, ErrAborted when another one of an atomic bulk did.
+type BulkResult struct {
+	Op   BulkOp
+	ID   string
+	Todo *Todo
+	Err  error
+}
+
+// Failed - the results of a bulk that have an error
+func Failed(results []*BulkResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
@@ -0,0 +1,51 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkOperationValidation(t *testing.T) {
+	todo := &models.TodoRequest{Title: "Buy milk", Description: "At the farm shop"}
+
+	t.Run("valid operations", func(t *testing.T) {
+		for _, operation := range []*models.BulkOperation{
+			{Op: models.BulkCreate, Todo: todo},
+			{Op: models.BulkUpdate, ID: "1", Todo: todo, Version: 2},
+			{Op: models.BulkDelete, ID: "1"},
+		} {
+			assert.NoError(t, utils.ValidateStruct(operation), operation.Op)
+		}
+	})
+
+	t.Run("invalid operations", func(t *testing.T) {
+		for field, operation := range map[string]*models.BulkOperation{
+			"op":    {Op: "upsert", ID: "1", Todo: todo},
+			"id":    {Op: models.BulkDelete},
+			"todo":  {Op: models.BulkUpdate, ID: "1"},
+			"title": {Op: models.BulkCreate, Todo: &models.TodoRequest{Description: "At the farm shop"}},
+		} {
+			var validationErrors validator.ValidationErrors
+			err := utils.ValidateStruct(operation)
+			if assert.True(t, errors.As(err, &validationErrors), field) {
+				assert.Contains(t, utils.ValidatonError(err).Errors, field)
+			}
+		}
+	})
+
+	t.Run("message of a missing id", func(t *testing.T) {
+		err := utils.ValidateStruct(&models.BulkOperation{Op: models.BulkDelete})
+		assert.Equal(t, "id is required unless op is create", utils.ValidatonError(err).Errors["id"])
+	})
+}
+
+func TestFailed(t *testing.T) {
+	assert.Equal(t, 1, models.Failed([]*models.BulkResult{{}, {Err: models.ErrNotFound}, {Todo: &models.Todo{}}}))
+	assert.Zero(t, models.Failed(nil))
+}
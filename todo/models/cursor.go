@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"go-distributed-tracing/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Keyset - todos strictly after Key in Sort order, Key only needs the sort fields and the id
+type Keyset struct {
+	Sort TodoSort
+	Key  *Todo
+}
+
+// TodoCursor - position in a sorted todo list, the page starts after Key or ends before it
+type TodoCursor struct {
+	Sort   TodoSort
+	Before bool
+	Key    *Todo
+}
+
+// Pagination - which part of a todo list to get, a Cursor replaces the Offset
+type Pagination struct {
+	Limit     int
+	Offset    int
+	Cursor    *TodoCursor
+	SkipTotal bool
+}
+
+// TodoPage - one page of a todo list, Total is nil when skipped and Next and Prev are nil on the first and last pages
+type TodoPage struct {
+	Todos []*Todo
+	Total *int
+	Next  *TodoCursor
+	Prev  *TodoCursor
+}
+
+// cursorToken - signed content of a cursor, only the sort fields of the key are set
+type cursorToken struct {
+	Sort        string             `json:"sort,omitempty"`
+	Before      bool               `json:"before,omitempty"`
+	ID          primitive.ObjectID `json:"id"`
+	Title       *string            `json:"title,omitempty"`
+	Status      *TodoStatus        `json:"status,omitempty"`
+	Priority    *int               `json:"priority,omitempty"`
+	DueAt       *time.Time         `json:"due_at,omitempty"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+	CreatedAt   *time.Time         `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time         `json:"updated_at,omitempty"`
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty"`
+	Score       *float64           `json:"score,omitempty"`
+}
+
+// Encode - opaque token of the cursor
+func (c *TodoCursor) Encode() (string, error) {
+	token := cursorToken{
+		Sort:   c.Sort.String(),
+		Before: c.Before,
+		ID:     c.Key.ID,
+	}
+
+	for _, field := range c.Sort {
+		switch field.Field {
+		case SortTitle:
+			token.Title = &c.Key.Title
+		case SortStatus:
+			token.Status = &c.Key.Status
+		case SortPriority:
+			token.Priority = &c.Key.Priority
+		case SortDueAt:
+			token.DueAt = c.Key.DueAt
+		case SortCompletedAt:
+			token.CompletedAt = c.Key.CompletedAt
+		case SortCreatedAt:
+			token.CreatedAt = &c.Key.CreatedAt
+		case SortUpdatedAt:
+			token.UpdatedAt = &c.Key.UpdatedAt
+		case SortDeletedAt:
+			token.DeletedAt = c.Key.DeletedAt
+		case SortScore:
+			token.Score = &c.Key.Score
+		}
+	}
+
+	return utils.SignToken(token)
+}
+
+// DecodeTodoCursor - cursor of a token made by Encode, utils.ErrInvalidToken if it was altered
+func DecodeTodoCursor(value string) (*TodoCursor, error) {
+	var token cursorToken
+	if err := utils.VerifyToken(value, &token); err != nil {
+		return nil, err
+	}
+
+	key := &Todo{
+		ID:          token.ID,
+		DueAt:       token.DueAt,
+		CompletedAt: token.CompletedAt,
+		DeletedAt:   token.DeletedAt,
+	}
+	if token.Title != nil {
+		key.Title = *token.Title
+	}
+	if token.Status != nil {
+		key.Status = *token.Status
+	}
+	if token.Priority != nil {
+		key.Priority = *token.Priority
+	}
+	if token.CreatedAt != nil {
+		key.CreatedAt = *token.CreatedAt
+	}
+	if token.UpdatedAt != nil {
+		key.UpdatedAt = *token.UpdatedAt
+	}
+	if token.Score != nil {
+		key.Score = *token.Score
+	}
+
+	return &TodoCursor{
+		Sort:   ParseTodoSort(token.Sort),
+		Before: token.Before,
+		Key:    key,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTodoCursor(t *testing.T) {
+	dueAt := time.Date(2022, 12, 1, 10, 0, 0, 123000000, time.UTC)
+	todo := &models.Todo{
+		ID:          primitive.NewObjectID(),
+		Title:       "Buy milk",
+		Description: "not part of the key",
+		Priority:    3,
+		DueAt:       &dueAt,
+		CreatedAt:   time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("keeps the sort fields", func(t *testing.T) {
+		sort := models.ParseTodoSort("-due_at,title")
+		token, err := (&models.TodoCursor{Sort: sort, Before: true, Key: todo}).Encode()
+		assert.NoError(t, err)
+
+		cursor, err := models.DecodeTodoCursor(token)
+		assert.NoError(t, err)
+		assert.Equal(t, sort, cursor.Sort)
+		assert.True(t, cursor.Before)
+		assert.Equal(t, &models.Todo{ID: todo.ID, Title: "Buy milk", DueAt: &dueAt}, cursor.Key)
+	})
+
+	t.Run("missing date", func(t *testing.T) {
+		token, err := (&models.TodoCursor{Sort: models.ParseTodoSort("completed_at"), Key: todo}).Encode()
+		assert.NoError(t, err)
+
+		cursor, err := models.DecodeTodoCursor(token)
+		assert.NoError(t, err)
+		assert.Nil(t, cursor.Key.CompletedAt)
+		assert.Equal(t, todo.ID, cursor.Key.ID)
+	})
+
+	t.Run("altered token", func(t *testing.T) {
+		token, err := (&models.TodoCursor{Key: todo}).Encode()
+		assert.NoError(t, err)
+
+		_, err = models.DecodeTodoCursor(token + "a")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+}
+
+func TestTodoSortReverse(t *testing.T) {
+	assert.Equal(t, models.TodoSort{
+		{Field: models.SortTitle, Desc: true},
+		{Field: models.SortID, Desc: true},
+	}, models.ParseTodoSort("title").Reverse())
+	assert.Equal(t, models.TodoSort{{Field: models.SortID}}, models.TodoSort(nil).WithID())
+}
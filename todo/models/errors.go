@@ -0,0 +1,83 @@
+package models
+
+import (
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+)
+
+// Kind - sentinel domain error, compare with errors.Is
+type Kind struct {
+	message string
+	class   string
+}
+
+func (k *Kind) Error() string {
+	return k.message
+}
+
+// ErrorClass - classification attached to spans
+func (k *Kind) ErrorClass() string {
+	return k.class
+}
+
+// Domain errors returned by repositories and services
+var (
+	ErrNotFound   = &Kind{message: "not found", class: pkg_tracing.ErrorClassNotFound}
+	ErrInvalidID  = &Kind{message: "invalid id", class: pkg_tracing.ErrorClassBadRequest}
+	ErrConflict   = &Kind{message: "conflict", class: pkg_tracing.ErrorClassConflict}
+	ErrValidation = &Kind{message: "validation failed", class: pkg_tracing.ErrorClassValidation}
+	// ErrInvalidTransition - the todo status can't change to the requested one
+	ErrInvalidTransition = &Kind{message: "invalid status transition", class: pkg_tracing.ErrorClassConflict}
+	// ErrPreconditionFailed - the todo isn't at the version the write expected
+	ErrPreconditionFailed = &Kind{message: "precondition failed", class: pkg_tracing.ErrorClassConflict}
+	// ErrAborted - the write was rolled back because another write of the same atomic bulk failed
+	ErrAborted = &Kind{message: "aborted", class: pkg_tracing.ErrorClassConflict}
+)
+
+// Error - domain error carrying the failed operation and the underlying cause
+type Error struct {
+	// Kind is one of the Err* sentinels
+	Kind *Kind
+	// Op is the operation that failed, e.g. TodoRepository.FindById
+	Op string
+	// ID is the todo id the operation was called with
+	ID string
+	// Err is the underlying cause, may be nil
+	Err error
+}
+
+// NewError - wrap err with a domain kind
+func NewError(kind *Kind, op string, id string, err error) error {
+	return &Error{
+		Kind: kind,
+		Op:   op,
+		ID:   id,
+		Err:  err,
+	}
+}
+
+func (e *Error) Error() string {
+	message := e.Op + ": " + e.Kind.Error()
+	if e.ID != "" {
+		message += " (id " + e.ID + ")"
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+
+	return message
+}
+
+// Unwrap - give access to the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is - match the domain kind, e.g. errors.Is(err, models.ErrNotFound)
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// ErrorClass - classification attached to spans
+func (e *Error) ErrorClass() string {
+	return e.Kind.ErrorClass()
+}
@@ -0,0 +1,38 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestError(t *testing.T) {
+	err := models.NewError(models.ErrNotFound, "TodoRepository.FindById", "1", mongo.ErrNoDocuments)
+
+	t.Run("matches its kind", func(t *testing.T) {
+		assert.True(t, errors.Is(err, models.ErrNotFound))
+		assert.False(t, errors.Is(err, models.ErrConflict))
+	})
+
+	t.Run("keeps the cause", func(t *testing.T) {
+		assert.True(t, errors.Is(err, mongo.ErrNoDocuments))
+		assert.Equal(t, "TodoRepository.FindById: not found (id 1): mongo: no documents in result", err.Error())
+	})
+
+	t.Run("exposes operation and id", func(t *testing.T) {
+		var domainErr *models.Error
+
+		assert.True(t, errors.As(err, &domainErr))
+		assert.Equal(t, "TodoRepository.FindById", domainErr.Op)
+		assert.Equal(t, "1", domainErr.ID)
+	})
+
+	t.Run("classification", func(t *testing.T) {
+		assert.Equal(t, "not_found", models.ErrNotFound.ErrorClass())
+		assert.Equal(t, "bad_request", models.NewError(models.ErrInvalidID, "op", "x", nil).(*models.Error).ErrorClass())
+	})
+}
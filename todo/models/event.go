@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventType - kind of write of a todo
+type EventType string
+
+// Todo lifecycle events
+const (
+	EventTodoCreated   EventType = "todo.created"
+	EventTodoUpdated   EventType = "todo.updated"
+	EventTodoCompleted EventType = "todo.completed"
+	EventTodoDeleted   EventType = "todo.deleted"
+)
+
+// EventTypes - every event type, webhooks subscribe to some of them
+var EventTypes = []EventType{EventTodoCreated, EventTodoUpdated, EventTodoCompleted, EventTodoDeleted}
+
+// Event - a todo was written. Todo is the todo after the write, nil when it was deleted. TraceParent is the
+// W3C trace context of the write, consumers continue its trace.
+type Event struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	Type        EventType          `json:"type" bson:"type"`
+	TodoID      primitive.ObjectID `json:"todo_id" bson:"todoId"`
+	Todo        *Todo              `json:"todo,omitempty" bson:"todo,omitempty"`
+	Actor       string             `json:"actor" bson:"actor"`
+	OccurredAt  time.Time          `json:"occurred_at" bson:"occurredAt"`
+	TraceParent string             `json:"traceparent,omitempty" bson:"traceParent,omitempty"`
+}
+
+// NewEvent - event of a write of todo made with ctx
+func NewEvent(ctx context.Context, eventType EventType, todoID primitive.ObjectID, todo *Todo, now time.Time) *Event {
+	return &Event{
+		ID:         primitive.NewObjectID(),
+		Type:       eventType,
+		TodoID:     todoID,
+		Todo:       todo,
+		Actor:      ActorFromContext(ctx),
+		OccurredAt: now,
+	}
+}
+
+// UpdateEventType - event of a write moving todo from before to after, completed when it got done
+func UpdateEventType(before *Todo, after *Todo) EventType {
+	if after.Status == StatusDone && before.Status != StatusDone {
+		return EventTodoCompleted
+	}
+
+	return EventTodoUpdated
+}
@@ -0,0 +1,36 @@
+package models_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewEvent(t *testing.T) {
+	now := time.Now()
+	todo := &models.Todo{ID: primitive.NewObjectID(), Title: "Buy milk"}
+
+	event := models.NewEvent(models.WithActor(context.Background(), "alice"), models.EventTodoCreated, todo.ID, todo, now)
+
+	assert.False(t, event.ID.IsZero())
+	assert.Equal(t, models.EventTodoCreated, event.Type)
+	assert.Equal(t, todo.ID, event.TodoID)
+	assert.Equal(t, todo, event.Todo)
+	assert.Equal(t, "alice", event.Actor)
+	assert.Equal(t, now, event.OccurredAt)
+}
+
+func TestUpdateEventType(t *testing.T) {
+	todo := &models.Todo{Status: models.StatusTodo}
+	done := &models.Todo{Status: models.StatusDone}
+
+	assert.Equal(t, models.EventTodoCompleted, models.UpdateEventType(todo, done))
+	assert.Equal(t, models.EventTodoUpdated, models.UpdateEventType(done, done))
+	assert.Equal(t, models.EventTodoUpdated, models.UpdateEventType(done, todo))
+	assert.Equal(t, models.EventTodoUpdated, models.UpdateEventType(todo, todo))
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// MaxIdempotencyKeyLength - longer idempotency keys are rejected
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyRecord - request made with an idempotency key and the response it got, replayed when the key is
+// sent again until ExpiresAt. Status is 0 while the first request is running, a retry takes the key over once
+// LockedUntil passed without a response, e.g. after a crash.
+type IdempotencyRecord struct {
+	Key         string            `bson:"_id"`
+	Fingerprint string            `bson:"fingerprint"`
+	Status      int               `bson:"status"`
+	Header      map[string]string `bson:"header,omitempty"`
+	Body        []byte            `bson:"body,omitempty"`
+	CreatedAt   time.Time         `bson:"createdAt"`
+	ExpiresAt   time.Time         `bson:"expiresAt"`
+	LockedUntil time.Time         `bson:"lockedUntil"`
+}
+
+// Done - the response of the request is stored
+func (ir *IdempotencyRecord) Done() bool {
+	return ir.Status != 0
+}
+
+// Locked - the first request of the key is still running
+func (ir *IdempotencyRecord) Locked(now time.Time) bool {
+	return !ir.Done() && now.Before(ir.LockedUntil)
+}
+
+// Expired - the key can be used for a new request
+func (ir *IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(ir.ExpiresAt)
+}
+
+// Fingerprint - hash of a request, requests sent again with the same key must have the same one
+func Fingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,31 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := models.Fingerprint("POST", "/todo", []byte(`{"title":"a"}`))
+
+	assert.Equal(t, fingerprint, models.Fingerprint("POST", "/todo", []byte(`{"title":"a"}`)))
+	assert.NotEqual(t, fingerprint, models.Fingerprint("POST", "/todo", []byte(`{"title":"b"}`)))
+	assert.NotEqual(t, fingerprint, models.Fingerprint("PUT", "/todo", []byte(`{"title":"a"}`)))
+	assert.NotEqual(t, fingerprint, models.Fingerprint("POST", "/todo/bulk", []byte(`{"title":"a"}`)))
+}
+
+func TestIdempotencyRecord(t *testing.T) {
+	now := time.Now()
+	record := &models.IdempotencyRecord{ExpiresAt: now.Add(time.Minute)}
+
+	assert.False(t, record.Done())
+	assert.False(t, record.Expired(now))
+	assert.True(t, record.Expired(now.Add(time.Minute)))
+
+	record.Status = 201
+	assert.True(t, record.Done())
+}
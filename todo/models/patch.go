@@ -0,0 +1,109 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"go-distributed-tracing/pkg/jsonpatch"
+)
+
+// Content types of a todo patch
+const (
+	// MergePatchType - JSON Merge Patch, RFC 7396
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType - JSON Patch, RFC 6902
+	JSONPatchType = "application/json-patch+json"
+)
+
+// Fields of a todo that a patch can change
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldStatus      = "status"
+	FieldCompletedAt = "completed_at"
+	FieldDueAt       = "due_at"
+	FieldPriority    = "priority"
+	FieldTags        = "tags"
+)
+
+// TodoPatch - patch document of a todo
+type TodoPatch struct {
+	// ContentType - MergePatchType or JSONPatchType
+	ContentType string
+	Document    []byte
+}
+
+// Apply - the request todo would make once patched. The patch applies to the writable fields
+// of todo, unknown fields in the result are an error.
+func (p *TodoPatch) Apply(todo *Todo) (*TodoRequest, error) {
+	doc, err := json.Marshal(&TodoRequest{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Status:      todo.Status,
+		DueAt:       todo.DueAt,
+		Priority:    todo.Priority,
+		Tags:        NormalizeTags(todo.Tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.ContentType {
+	case MergePatchType:
+		doc, err = jsonpatch.MergePatch(doc, p.Document)
+	case JSONPatchType:
+		doc, err = jsonpatch.Apply(doc, p.Document)
+	default:
+		err = jsonpatch.ErrInvalidPatch
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TodoRequest{}
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ChangedFields - the Field* constants whose value differs between before and after
+func ChangedFields(before, after *Todo) []string {
+	var fields []string
+	if before.Title != after.Title {
+		fields = append(fields, FieldTitle)
+	}
+	if before.Description != after.Description {
+		fields = append(fields, FieldDescription)
+	}
+	if before.Status != after.Status {
+		fields = append(fields, FieldStatus)
+	}
+	if !equalTime(before.CompletedAt, after.CompletedAt) {
+		fields = append(fields, FieldCompletedAt)
+	}
+	if !equalTime(before.DueAt, after.DueAt) {
+		fields = append(fields, FieldDueAt)
+	}
+	if before.Priority != after.Priority {
+		fields = append(fields, FieldPriority)
+	}
+	if !reflect.DeepEqual(NormalizeTags(before.Tags), NormalizeTags(after.Tags)) {
+		fields = append(fields, FieldTags)
+	}
+
+	return fields
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
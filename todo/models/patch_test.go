@@ -0,0 +1,115 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"go-distributed-tracing/pkg/jsonpatch"
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTodoPatchApply(t *testing.T) {
+	dueAt := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	todo := &models.Todo{
+		Title:       "Buy milk",
+		Description: "At the farm shop",
+		Status:      models.StatusTodo,
+		DueAt:       &dueAt,
+		Priority:    2,
+		Tags:        []string{"errand"},
+	}
+
+	t.Run("merge-patch", func(t *testing.T) {
+		patch := &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"title":"Buy oat milk","due_at":null}`),
+		}
+
+		result, err := patch.Apply(todo)
+		require.NoError(t, err)
+		assert.Equal(t, &models.TodoRequest{
+			Title:       "Buy oat milk",
+			Description: "At the farm shop",
+			Status:      models.StatusTodo,
+			Priority:    2,
+			Tags:        []string{"errand"},
+		}, result)
+	})
+
+	t.Run("json-patch", func(t *testing.T) {
+		patch := &models.TodoPatch{
+			ContentType: models.JSONPatchType,
+			Document:    []byte(`[{"op":"add","path":"/tags/-","value":"home"},{"op":"replace","path":"/priority","value":5}]`),
+		}
+
+		result, err := patch.Apply(todo)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"errand", "home"}, result.Tags)
+		assert.Equal(t, 5, result.Priority)
+		assert.Equal(t, "Buy milk", result.Title)
+	})
+
+	t.Run("error-unknown-field", func(t *testing.T) {
+		patch := &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"created_at":"2022-12-01T00:00:00Z"}`),
+		}
+
+		_, err := patch.Apply(todo)
+		assert.Error(t, err)
+	})
+
+	t.Run("error-test-failed", func(t *testing.T) {
+		patch := &models.TodoPatch{
+			ContentType: models.JSONPatchType,
+			Document:    []byte(`[{"op":"test","path":"/title","value":"Buy bread"}]`),
+		}
+
+		_, err := patch.Apply(todo)
+		assert.ErrorIs(t, err, jsonpatch.ErrTestFailed)
+	})
+
+	t.Run("error-content-type", func(t *testing.T) {
+		patch := &models.TodoPatch{
+			ContentType: "application/json",
+			Document:    []byte(`{}`),
+		}
+
+		_, err := patch.Apply(todo)
+		assert.ErrorIs(t, err, jsonpatch.ErrInvalidPatch)
+	})
+}
+
+func TestChangedFields(t *testing.T) {
+	dueAt := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	before := &models.Todo{
+		Title:       "Buy milk",
+		Description: "At the farm shop",
+		Status:      models.StatusTodo,
+		DueAt:       &dueAt,
+		Tags:        []string{"errand"},
+	}
+
+	sameDueAt := dueAt.In(time.FixedZone("UTC+7", 7*60*60))
+	same := *before
+	same.DueAt = &sameDueAt
+	assert.Empty(t, models.ChangedFields(before, &same))
+
+	completedAt := dueAt
+	after := *before
+	after.Title = "Buy oat milk"
+	after.Status = models.StatusDone
+	after.CompletedAt = &completedAt
+	after.DueAt = nil
+	after.Tags = []string{"errand", "home"}
+	assert.Equal(t, []string{
+		models.FieldTitle,
+		models.FieldStatus,
+		models.FieldCompletedAt,
+		models.FieldDueAt,
+		models.FieldTags,
+	}, models.ChangedFields(before, &after))
+}
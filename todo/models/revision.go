@@ -0,0 +1,152 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RevisionAction - write that created a revision
+type RevisionAction string
+
+// Revision actions
+const (
+	ActionCreate  RevisionAction = "create"
+	ActionUpdate  RevisionAction = "update"
+	ActionPatch   RevisionAction = "patch"
+	ActionDelete  RevisionAction = "delete"
+	ActionRestore RevisionAction = "restore"
+)
+
+// FieldDeletedAt - field of a todo set by Delete and cleared by Restore, patches can't change it
+const FieldDeletedAt = "deleted_at"
+
+// AnonymousActor - actor of the writes of unidentified clients
+const AnonymousActor = "anonymous"
+
+// Revision - immutable record of a write of a todo. Rev is the version the write left the todo at.
+type Revision struct {
+	TodoID    primitive.ObjectID `json:"todo_id" bson:"todoId"`
+	Rev       int64              `json:"rev" bson:"rev"`
+	Action    RevisionAction     `json:"action" bson:"action"`
+	Changes   []FieldChange      `json:"changes" bson:"changes"`
+	Actor     string             `json:"actor" bson:"actor"`
+	TraceID   string             `json:"trace_id,omitempty" bson:"traceId,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"createdAt"`
+}
+
+// FieldChange - value of a field before and after a write, as JSON values
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	From  interface{} `json:"from" bson:"from"`
+	To    interface{} `json:"to" bson:"to"`
+}
+
+type actorKey struct{}
+
+// WithActor - ctx carrying the actor of the writes made with it
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext - actor carried by ctx, AnonymousActor when there is none
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return AnonymousActor
+}
+
+// Diff - changes of the fields of a todo from before to after, before is nil for a new todo
+func Diff(before, after *Todo) ([]FieldChange, error) {
+	from := &Todo{}
+	if before != nil {
+		from = before
+	}
+
+	fields := ChangedFields(from, after)
+	if !equalTime(from.DeletedAt, after.DeletedAt) {
+		fields = append(fields, FieldDeletedAt)
+	}
+
+	fromValues, err := fieldValues(from)
+	if err != nil {
+		return nil, err
+	}
+	toValues, err := fieldValues(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		change := FieldChange{Field: field, To: toValues[field]}
+		if before != nil {
+			change.From = fromValues[field]
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// Revert - todo as it was at revision rev, undoing the changes of revisions, which must be every revision
+// after rev up to the version of todo, oldest first. Only the fields a patch can change are reverted, the
+// trash is left alone.
+func Revert(todo *Todo, revisions []*Revision, rev int64) (*Todo, error) {
+	if int64(len(revisions)) != todo.Version-rev {
+		return nil, fmt.Errorf("%d revisions after %d, version %d", len(revisions), rev, todo.Version)
+	}
+
+	values, err := fieldValues(todo)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].Rev != rev+int64(i)+1 {
+			return nil, fmt.Errorf("revision %d is missing", rev+int64(i)+1)
+		}
+
+		for _, change := range revisions[i].Changes {
+			if change.Field != FieldDeletedAt {
+				values[change.Field] = change.From
+			}
+		}
+	}
+
+	doc, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Todo{}
+	if err := json.Unmarshal(doc, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// fieldValues - JSON values of the fields of todo, by field name
+func fieldValues(todo *Todo) (map[string]interface{}, error) {
+	doc, err := json.Marshal(todo)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(doc, &values); err != nil {
+		return nil, err
+	}
+	// Omitted when empty
+	if _, ok := values[FieldDeletedAt]; !ok {
+		values[FieldDeletedAt] = nil
+	}
+
+	return values, nil
+}
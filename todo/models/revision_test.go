@@ -0,0 +1,119 @@
+package models_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActorFromContext(t *testing.T) {
+	assert.Equal(t, models.AnonymousActor, models.ActorFromContext(context.Background()))
+	assert.Equal(t, "alice", models.ActorFromContext(models.WithActor(context.Background(), "alice")))
+}
+
+func TestDiff(t *testing.T) {
+	dueAt := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	created := &models.Todo{
+		Title:  "Buy milk",
+		Status: models.StatusTodo,
+		DueAt:  &dueAt,
+		Tags:   []string{},
+	}
+
+	t.Run("create", func(t *testing.T) {
+		changes, err := models.Diff(nil, created)
+		require.NoError(t, err)
+		assert.Equal(t, []models.FieldChange{
+			{Field: models.FieldTitle, To: "Buy milk"},
+			{Field: models.FieldStatus, To: "todo"},
+			{Field: models.FieldDueAt, To: "2022-12-31T00:00:00Z"},
+		}, changes)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated := *created
+		updated.Title = "Buy oat milk"
+		updated.DueAt = nil
+		updated.Tags = []string{"home"}
+
+		changes, err := models.Diff(created, &updated)
+		require.NoError(t, err)
+		assert.Equal(t, []models.FieldChange{
+			{Field: models.FieldTitle, From: "Buy milk", To: "Buy oat milk"},
+			{Field: models.FieldDueAt, From: "2022-12-31T00:00:00Z", To: nil},
+			{Field: models.FieldTags, From: []interface{}{}, To: []interface{}{"home"}},
+		}, changes)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deletedAt := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+		deleted := *created
+		deleted.DeletedAt = &deletedAt
+
+		changes, err := models.Diff(created, &deleted)
+		require.NoError(t, err)
+		assert.Equal(t, []models.FieldChange{
+			{Field: models.FieldDeletedAt, From: nil, To: "2023-01-02T00:00:00Z"},
+		}, changes)
+	})
+}
+
+func TestRevert(t *testing.T) {
+	dueAt := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	deletedAt := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	current := &models.Todo{
+		Title:    "Buy oat milk",
+		Status:   models.StatusDone,
+		Priority: 3,
+		Tags:     []string{"home"},
+		Version:  4,
+	}
+	revisions := []*models.Revision{
+		{Rev: 2, Changes: []models.FieldChange{
+			{Field: models.FieldTitle, From: "Buy milk", To: "Buy oat milk"},
+			{Field: models.FieldDueAt, From: "2022-12-31T00:00:00Z", To: nil},
+		}},
+		{Rev: 3, Changes: []models.FieldChange{
+			{Field: models.FieldDeletedAt, From: nil, To: "2023-01-02T00:00:00Z"},
+		}},
+		{Rev: 4, Changes: []models.FieldChange{
+			{Field: models.FieldPriority, From: float64(0), To: float64(3)},
+			{Field: models.FieldTags, From: []interface{}{}, To: []interface{}{"home"}},
+		}},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		result, err := models.Revert(current, revisions, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Buy milk", result.Title)
+		assert.Equal(t, &dueAt, result.DueAt)
+		assert.Equal(t, 0, result.Priority)
+		assert.Empty(t, result.Tags)
+		assert.Equal(t, models.StatusDone, result.Status)
+		assert.Nil(t, result.DeletedAt, "the trash is left alone")
+		assert.Equal(t, int64(4), result.Version)
+	})
+
+	t.Run("success keeps the trash", func(t *testing.T) {
+		deleted := *current
+		deleted.DeletedAt = &deletedAt
+
+		result, err := models.Revert(&deleted, revisions[2:], 3)
+		require.NoError(t, err)
+		assert.Equal(t, &deletedAt, result.DeletedAt)
+		assert.Equal(t, "Buy oat milk", result.Title)
+	})
+
+	t.Run("error when a revision is missing", func(t *testing.T) {
+		_, err := models.Revert(current, []*models.Revision{revisions[0], revisions[2]}, 2)
+		assert.Error(t, err)
+
+		_, err = models.Revert(current, revisions[1:], 1)
+		assert.Error(t, err)
+	})
+}
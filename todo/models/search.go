@@ -0,0 +1,114 @@
+package models
+
+import (
+	"strings"
+)
+
+// Weights of the fields in the relevance score, also used for the Mongo text index
+const (
+	TitleWeight       = 2
+	DescriptionWeight = 1
+)
+
+// SearchQuery - parsed full-text search, the syntax of Mongo $text: words, "phrases" and -negations
+type SearchQuery struct {
+	Terms    []string
+	Phrases  []string
+	Excluded []string
+}
+
+// ParseSearch - parse a search string, everything lower case
+func ParseSearch(value string) SearchQuery {
+	var query SearchQuery
+
+	value = strings.ToLower(value)
+	for value != "" {
+		value = strings.TrimLeft(value, " \t\r\n")
+		if value == "" {
+			break
+		}
+
+		negated := strings.HasPrefix(value, "-")
+		if negated {
+			value = value[1:]
+		}
+
+		var (
+			text   string
+			phrase bool
+		)
+		if strings.HasPrefix(value, `"`) {
+			// An unterminated phrase runs to the end
+			value, phrase = value[1:], true
+			end := strings.Index(value, `"`)
+			if end < 0 {
+				text, value = value, ""
+			} else {
+				text, value = value[:end], value[end+1:]
+			}
+			text = strings.TrimSpace(text)
+		} else {
+			end := strings.IndexAny(value, " \t\r\n")
+			if end < 0 {
+				end = len(value)
+			}
+			text, value = value[:end], value[end:]
+		}
+
+		switch {
+		case text == "":
+		case negated:
+			query.Excluded = append(query.Excluded, text)
+		case phrase:
+			query.Phrases = append(query.Phrases, text)
+		default:
+			query.Terms = append(query.Terms, text)
+		}
+	}
+
+	return query
+}
+
+// Match - the todo has every phrase, or any term when there is no phrase, and none of the excluded words
+func (q SearchQuery) Match(todo *Todo) bool {
+	title, description := strings.ToLower(todo.Title), strings.ToLower(todo.Description)
+	has := func(text string) bool {
+		return strings.Contains(title, text) || strings.Contains(description, text)
+	}
+
+	for _, text := range q.Excluded {
+		if has(text) {
+			return false
+		}
+	}
+
+	if len(q.Phrases) > 0 {
+		for _, text := range q.Phrases {
+			if !has(text) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, text := range q.Terms {
+		if has(text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Score - relevance of todo, the weighted number of occurrences of the terms and phrases
+func (q SearchQuery) Score(todo *Todo) float64 {
+	title, description := strings.ToLower(todo.Title), strings.ToLower(todo.Description)
+
+	score := 0
+	for _, text := range append(append([]string{}, q.Terms...), q.Phrases...) {
+		score += TitleWeight*strings.Count(title, text) + DescriptionWeight*strings.Count(description, text)
+	}
+
+	return float64(score)
+}
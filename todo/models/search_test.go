@@ -0,0 +1,46 @@
+package models_test
+
+import (
+	"testing"
+
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected models.SearchQuery
+	}{
+		{name: "words", value: " Buy  MILK ", expected: models.SearchQuery{Terms: []string{"buy", "milk"}}},
+		{name: "phrase", value: `milk "farm shop"`, expected: models.SearchQuery{Terms: []string{"milk"}, Phrases: []string{"farm shop"}}},
+		{name: "negations", value: `milk -recipe -"oat milk"`, expected: models.SearchQuery{Terms: []string{"milk"}, Excluded: []string{"recipe", "oat milk"}}},
+		{name: "unterminated phrase", value: `"farm shop`, expected: models.SearchQuery{Phrases: []string{"farm shop"}}},
+		{name: "empty parts", value: `"" - milk`, expected: models.SearchQuery{Terms: []string{"milk"}}},
+		{name: "empty", value: "", expected: models.SearchQuery{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, models.ParseSearch(tt.value))
+		})
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	todo := &models.Todo{Title: "Buy milk", Description: "Milk and eggs from the farm shop"}
+
+	assert.True(t, models.ParseSearch("milk").Match(todo))
+	assert.True(t, models.ParseSearch("holiday eggs").Match(todo))
+	assert.True(t, models.ParseSearch(`"farm shop" holiday`).Match(todo))
+	assert.False(t, models.ParseSearch(`"farm shop" "oat milk"`).Match(todo))
+	assert.False(t, models.ParseSearch("milk -eggs").Match(todo))
+	assert.False(t, models.ParseSearch("-holiday").Match(todo))
+
+	// Title occurrences weigh more than description ones
+	assert.Equal(t, float64(models.TitleWeight+models.DescriptionWeight), models.ParseSearch("milk").Score(todo))
+	assert.Equal(t, float64(models.DescriptionWeight), models.ParseSearch("eggs -holiday").Score(todo))
+	assert.Equal(t, float64(0), models.ParseSearch("holiday").Score(todo))
+}
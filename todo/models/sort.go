@@ -0,0 +1,96 @@
+package models
+
+import (
+	"strings"
+)
+
+// Fields a todo list can be sorted on, as named in the API
+const (
+	SortTitle       = "title"
+	SortStatus      = "status"
+	SortPriority    = "priority"
+	SortDueAt       = "due_at"
+	SortCompletedAt = "completed_at"
+	SortCreatedAt   = "created_at"
+	SortUpdatedAt   = "updated_at"
+	SortDeletedAt   = "deleted_at"
+	// SortScore - relevance to a full-text search, only when searching
+	SortScore = "score"
+	// SortID - the tie-breaker repositories append, not accepted from clients
+	SortID = "id"
+)
+
+// SortField - one key of a todo list order
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// TodoSort - order of a todo list. Repositories always append the id as the last key
+// so that pages are stable, an empty TodoSort is the id order, i.e. creation order.
+// Titles are compared byte by byte and missing dates sort before any date.
+type TodoSort []SortField
+
+// ParseTodoSort - parse a validated sort parameter, e.g. -created_at,title
+func ParseTodoSort(value string) TodoSort {
+	var sort TodoSort
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(field, "-")
+		sort = append(sort, SortField{
+			Field: strings.TrimPrefix(field, "-"),
+			Desc:  desc,
+		})
+	}
+
+	return sort
+}
+
+// String - the sort parameter of sort
+func (sort TodoSort) String() string {
+	fields := make([]string, 0, len(sort))
+	for _, field := range sort {
+		if field.Desc {
+			fields = append(fields, "-"+field.Field)
+			continue
+		}
+
+		fields = append(fields, field.Field)
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// Has - the sort uses field
+func (sort TodoSort) Has(field string) bool {
+	for _, value := range sort {
+		if value.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithID - sort ending with the id, the order repositories actually apply
+func (sort TodoSort) WithID() TodoSort {
+	if sort.Has(SortID) {
+		return sort
+	}
+
+	return append(append(TodoSort{}, sort...), SortField{Field: SortID})
+}
+
+// Reverse - the opposite order, including the id
+func (sort TodoSort) Reverse() TodoSort {
+	var reversed TodoSort
+	for _, field := range sort.WithID() {
+		reversed = append(reversed, SortField{Field: field.Field, Desc: !field.Desc})
+	}
+
+	return reversed
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// TodoStatus - step of the todo workflow
+type TodoStatus string
+
+// Todo statuses
+const (
+	StatusTodo       TodoStatus = "todo"
+	StatusInProgress TodoStatus = "in_progress"
+	StatusDone       TodoStatus = "done"
+	StatusArchived   TodoStatus = "archived"
+)
+
+// statusTransitions - the statuses each status can move to
+var statusTransitions = map[TodoStatus][]TodoStatus{
+	StatusTodo:       {StatusInProgress, StatusDone, StatusArchived},
+	StatusInProgress: {StatusTodo, StatusDone, StatusArchived},
+	StatusDone:       {StatusTodo, StatusInProgress, StatusArchived},
+	StatusArchived:   {StatusTodo},
+}
+
+// CanTransitionTo - next is reachable from s in one step
+func (s TodoStatus) CanTransitionTo(next TodoStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Transition - move todo to next, keeping completed_at in step with the status.
+// Setting the current status again is a no-op.
+func (t *Todo) Transition(next TodoStatus, now time.Time) error {
+	if t.Status == "" {
+		t.Status = StatusTodo
+	}
+
+	if next == t.Status {
+		return nil
+	}
+
+	if !t.Status.CanTransitionTo(next) {
+		return fmt.Errorf("cannot change status from %s to %s", t.Status, next)
+	}
+
+	switch next {
+	case StatusDone:
+		t.CompletedAt = &now
+	case StatusTodo, StatusInProgress:
+		t.CompletedAt = nil
+	}
+	// Archiving keeps completed_at, a done todo stays completed once archived
+	t.Status = next
+
+	return nil
+}
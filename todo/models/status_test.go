@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoTransition(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		from        models.TodoStatus
+		completedAt *time.Time
+		to          models.TodoStatus
+		wantErr     bool
+		completed   *time.Time
+	}{
+		{name: "todo to in_progress", from: models.StatusTodo, to: models.StatusInProgress},
+		{name: "todo to done", from: models.StatusTodo, to: models.StatusDone, completed: &now},
+		{name: "unset status is todo", from: "", to: models.StatusDone, completed: &now},
+		{name: "in_progress to done", from: models.StatusInProgress, to: models.StatusDone, completed: &now},
+		{name: "done to todo clears completed_at", from: models.StatusDone, completedAt: &earlier, to: models.StatusTodo},
+		{name: "done to in_progress clears completed_at", from: models.StatusDone, completedAt: &earlier, to: models.StatusInProgress},
+		{name: "done to archived keeps completed_at", from: models.StatusDone, completedAt: &earlier, to: models.StatusArchived, completed: &earlier},
+		{name: "same status is a no-op", from: models.StatusDone, completedAt: &earlier, to: models.StatusDone, completed: &earlier},
+		{name: "archived to todo", from: models.StatusArchived, to: models.StatusTodo},
+		{name: "archived to done", from: models.StatusArchived, to: models.StatusDone, wantErr: true},
+		{name: "archived to in_progress", from: models.StatusArchived, to: models.StatusInProgress, wantErr: true},
+		{name: "unknown status", from: models.StatusTodo, to: "blocked", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := &models.Todo{Status: tt.from, CompletedAt: tt.completedAt}
+
+			err := todo.Transition(tt.to, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, tt.from, todo.Status, "status is unchanged on error")
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.to, todo.Status)
+			assert.Equal(t, tt.completed, todo.CompletedAt)
+		})
+	}
+}
@@ -2,6 +2,9 @@ package models
 
 import (
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"go-distributed-tracing/utils"
@@ -14,25 +17,150 @@ type Todo struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Title       string             `json:"title" bson:"title"`
 	Description string             `json:"description" bson:"description"`
+	Status      TodoStatus         `json:"status" bson:"status"`
+	CompletedAt *time.Time         `json:"completed_at" bson:"completedAt"`
+	DueAt       *time.Time         `json:"due_at" bson:"dueAt"`
+	Priority    int                `json:"priority" bson:"priority"` // 0 is no priority, 5 the most urgent
+	Tags        []string           `json:"tags" bson:"tags"`
 	CreatedAt   time.Time          `json:"created_at" bson:"createdAt"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updatedAt"`
+	Version     int64              `json:"version" bson:"version"`                 // starts at 1, incremented by every write
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty" bson:"deletedAt"`  // set while the todo is in the trash
+	Score       float64            `json:"score,omitempty" bson:"score,omitempty"` // relevance to a full-text search, only set when searching
+}
+
+// TodoFilter - criteria of a todo list, zero values match everything
+type TodoFilter struct {
+	// Keyword - case-insensitive match on the title, taken literally
+	Keyword string
+	// Search - full-text search on the title and the description, see ParseSearch
+	Search string
+	Status TodoStatus
+	// Tags - the todo has every one of the tags
+	Tags []string
+	// PriorityMin, PriorityMax - inclusive priority range
+	PriorityMin *int
+	PriorityMax *int
+	// Overdue - due in the past and neither done nor archived
+	Overdue bool
+	// DueBefore, DueAfter - exclusive due date range, todos without a due date never match
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	// After - keyset pagination, only todos after a position of a sorted list
+	After *Keyset
+	// Deleted - list the trash, i.e. the deleted todos, instead of the live ones
+	Deleted bool
+	// IDs - the todo is one of the ids
+	IDs []primitive.ObjectID
 }
 
 // TodoRequest - todo request
 type TodoRequest struct {
-	Title       string `form:"title" json:"title" validate:"required"`
-	Description string `form:"description" json:"description" validate:"required"`
+	Title       string     `form:"title" json:"title" validate:"required"`
+	Description string     `form:"description" json:"description" validate:"required"`
+	Status      TodoStatus `form:"status" json:"status" validate:"omitempty,oneof=todo in_progress done archived"`
+	DueAt       *time.Time `form:"due_at" json:"due_at"`
+	Priority    int        `form:"priority" json:"priority" validate:"min=0,max=5"`
+	Tags        []string   `form:"tags" json:"tags" validate:"max=20,dive,required,max=50"`
 }
 
 func (tr *TodoRequest) Bind(r *http.Request) error {
 	return utils.ValidateStruct(tr)
 }
 
+// Todo - the todo of a validated request
+func (tr *TodoRequest) Todo() *Todo {
+	return &Todo{
+		Title:       tr.Title,
+		Description: tr.Description,
+		Status:      tr.Status,
+		DueAt:       tr.DueAt,
+		Priority:    tr.Priority,
+		Tags:        tr.Tags,
+	}
+}
+
 // TodoListRequest - form for list validation
 type TodoListRequest struct {
-	Keywords *SearchForm
-	Page     string `form:"page" json:"page" validate:"sgte=1"`
-	PerPage  string `form:"per_page" json:"per_page" validate:"sgte=1,slte=100"`
+	Keywords    *SearchForm
+	Search      string   `form:"search" json:"search" validate:"max=255"`
+	Status      string   `form:"status" json:"status" validate:"omitempty,oneof=todo in_progress done archived"`
+	Tags        []string `form:"tag" json:"tag" validate:"max=20,dive,required,max=50"`
+	PriorityMin string   `form:"priority_min" json:"priority_min" validate:"sinteger,sgte=0,slte=5"`
+	PriorityMax string   `form:"priority_max" json:"priority_max" validate:"sinteger,sgte=0,slte=5"`
+	Overdue     string   `form:"overdue" json:"overdue" validate:"omitempty,boolean"`
+	DueBefore   string   `form:"due_before" json:"due_before" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	DueAfter    string   `form:"due_after" json:"due_after" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Sort        string   `form:"sort" json:"sort" validate:"max=255,sortby=title status priority due_at completed_at created_at updated_at deleted_at score"`
+	Page        string   `form:"page" json:"page" validate:"excluded_with=Cursor,sgte=1"`
+	PerPage     string   `form:"per_page" json:"per_page" validate:"sgte=1,slte=100"`
+	Cursor      string   `form:"cursor" json:"cursor" validate:"max=2048"`
+	Total       string   `form:"total" json:"total" validate:"omitempty,boolean"`
+}
+
+// Filter - the list criteria of a validated request
+func (lr *TodoListRequest) Filter() TodoFilter {
+	filter := TodoFilter{
+		Search: strings.TrimSpace(lr.Search),
+		Status: TodoStatus(lr.Status),
+	}
+	if lr.Keywords != nil {
+		filter.Keyword = lr.Keywords.Keywords
+	}
+	if len(lr.Tags) > 0 {
+		filter.Tags = NormalizeTags(lr.Tags)
+	}
+
+	if value, err := strconv.Atoi(lr.PriorityMin); err == nil {
+		filter.PriorityMin = &value
+	}
+	if value, err := strconv.Atoi(lr.PriorityMax); err == nil {
+		filter.PriorityMax = &value
+	}
+
+	filter.Overdue, _ = strconv.ParseBool(lr.Overdue)
+
+	if value, err := time.Parse(time.RFC3339, lr.DueBefore); err == nil {
+		filter.DueBefore = &value
+	}
+	if value, err := time.Parse(time.RFC3339, lr.DueAfter); err == nil {
+		filter.DueAfter = &value
+	}
+
+	return filter
+}
+
+// SortOrder - the list order of a validated request, the most relevant first when searching
+func (lr *TodoListRequest) SortOrder() TodoSort {
+	if lr.Sort == "" && strings.TrimSpace(lr.Search) != "" {
+		return TodoSort{{Field: SortScore, Desc: true}}
+	}
+
+	return ParseTodoSort(lr.Sort)
+}
+
+// SkipTotal - the client asked not to count the matching todos
+func (lr *TodoListRequest) SkipTotal() bool {
+	total, err := strconv.ParseBool(lr.Total)
+	return err == nil && !total
+}
+
+// NormalizeTags - trimmed, lower case, sorted and unique tags, never nil
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	results := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		results = append(results, tag)
+	}
+	sort.Strings(results)
+
+	return results
 }
 
 // SearchForm - search list struct
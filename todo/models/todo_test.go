@@ -0,0 +1,124 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"errand", "home"}, models.NormalizeTags([]string{" Home", "errand", "", "HOME "}))
+	assert.Equal(t, []string{}, models.NormalizeTags(nil))
+}
+
+func TestTodoListRequestFilter(t *testing.T) {
+	t.Run("every criteria", func(t *testing.T) {
+		listRequest := &models.TodoListRequest{
+			Keywords:    &models.SearchForm{Keywords: "milk"},
+			Search:      ` "farm shop" -oat `,
+			Status:      "in_progress",
+			Tags:        []string{"Home", "errand"},
+			PriorityMin: "1",
+			PriorityMax: "4",
+			Overdue:     "true",
+			DueBefore:   "2022-12-31T00:00:00Z",
+			DueAfter:    "2022-12-01T00:00:00+07:00",
+		}
+		assert.NoError(t, utils.ValidateStruct(listRequest))
+
+		priorityMin, priorityMax := 1, 4
+		dueBefore := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+		dueAfter := time.Date(2022, 11, 30, 17, 0, 0, 0, time.UTC)
+
+		filter := listRequest.Filter()
+		assert.Equal(t, "milk", filter.Keyword)
+		assert.Equal(t, `"farm shop" -oat`, filter.Search)
+		assert.Equal(t, models.StatusInProgress, filter.Status)
+		assert.Equal(t, []string{"errand", "home"}, filter.Tags)
+		assert.Equal(t, &priorityMin, filter.PriorityMin)
+		assert.Equal(t, &priorityMax, filter.PriorityMax)
+		assert.True(t, filter.Overdue)
+		assert.True(t, dueBefore.Equal(*filter.DueBefore))
+		assert.True(t, dueAfter.Equal(*filter.DueAfter))
+	})
+
+	t.Run("empty request matches everything", func(t *testing.T) {
+		listRequest := &models.TodoListRequest{}
+		assert.NoError(t, utils.ValidateStruct(listRequest))
+		assert.Equal(t, models.TodoFilter{}, listRequest.Filter())
+	})
+
+	t.Run("invalid criteria", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			listRequest *models.TodoListRequest
+		}{
+			{name: "priority below range", listRequest: &models.TodoListRequest{PriorityMin: "-1"}},
+			{name: "priority above range", listRequest: &models.TodoListRequest{PriorityMax: "6"}},
+			{name: "priority not a number", listRequest: &models.TodoListRequest{PriorityMin: "high"}},
+			{name: "overdue not a boolean", listRequest: &models.TodoListRequest{Overdue: "yes please"}},
+			{name: "due date not RFC 3339", listRequest: &models.TodoListRequest{DueBefore: "31/12/2022"}},
+			{name: "empty tag", listRequest: &models.TodoListRequest{Tags: []string{""}}},
+			{name: "unknown sort field", listRequest: &models.TodoListRequest{Sort: "description"}},
+			{name: "sort field used twice", listRequest: &models.TodoListRequest{Sort: "title,-title"}},
+			{name: "empty sort field", listRequest: &models.TodoListRequest{Sort: "title,"}},
+			{name: "total not a boolean", listRequest: &models.TodoListRequest{Total: "no"}},
+			{name: "page with a cursor", listRequest: &models.TodoListRequest{Page: "2", Cursor: "cursor"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Error(t, utils.ValidateStruct(tt.listRequest))
+			})
+		}
+	})
+}
+
+func TestTodoListRequestSortOrder(t *testing.T) {
+	listRequest := &models.TodoListRequest{Sort: "-created_at, title"}
+	assert.NoError(t, utils.ValidateStruct(listRequest))
+	assert.Equal(t, models.TodoSort{
+		{Field: models.SortCreatedAt, Desc: true},
+		{Field: models.SortTitle},
+	}, listRequest.SortOrder())
+	assert.Equal(t, "-created_at,title", listRequest.SortOrder().String())
+
+	assert.Nil(t, (&models.TodoListRequest{}).SortOrder())
+
+	// The most relevant first when searching without a sort
+	assert.Equal(t, models.TodoSort{{Field: models.SortScore, Desc: true}}, (&models.TodoListRequest{Search: "milk"}).SortOrder())
+	assert.Equal(t, models.ParseTodoSort("title"), (&models.TodoListRequest{Search: "milk", Sort: "title"}).SortOrder())
+}
+
+func TestTodoListRequestSkipTotal(t *testing.T) {
+	assert.False(t, (&models.TodoListRequest{}).SkipTotal())
+	assert.False(t, (&models.TodoListRequest{Total: "true"}).SkipTotal())
+	assert.True(t, (&models.TodoListRequest{Total: "false"}).SkipTotal())
+}
+
+func TestTodoRequestValidation(t *testing.T) {
+	valid := func() *models.TodoRequest {
+		return &models.TodoRequest{Title: "title", Description: "description", Priority: 5, Tags: []string{"home"}}
+	}
+	assert.NoError(t, utils.ValidateStruct(valid()))
+
+	tooUrgent := valid()
+	tooUrgent.Priority = 6
+	assert.Error(t, utils.ValidateStruct(tooUrgent))
+
+	longTag := valid()
+	longTag.Tags = []string{strings.Repeat("a", 51)}
+	assert.Error(t, utils.ValidateStruct(longTag))
+
+	tooManyTags := valid()
+	tooManyTags.Tags = make([]string, 21)
+	for i := range tooManyTags.Tags {
+		tooManyTags.Tags[i] = "tag"
+	}
+	assert.Error(t, utils.ValidateStruct(tooManyTags))
+}
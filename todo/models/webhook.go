@@ -0,0 +1,126 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-distributed-tracing/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook - subscription of a URL to todo events, its deliveries are signed with Secret
+type Webhook struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	URL       string             `json:"url" bson:"url"`
+	Events    []EventType        `json:"events" bson:"events"` // empty subscribes to every event
+	Secret    string             `json:"-" bson:"secret"`
+	CreatedAt time.Time          `json:"created_at" bson:"createdAt"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updatedAt"`
+}
+
+// Subscribes - the webhook gets the events of eventType
+func (w *Webhook) Subscribes(eventType EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, subscribed := range w.Events {
+		if subscribed == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookSignature - X-Webhook-Signature of a delivery of body sent at timestamp, the HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the secret of the webhook
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRequest - webhook request, the secret is never answered back
+type WebhookRequest struct {
+	URL    string      `json:"url" validate:"required,httpurl,max=2048"`
+	Events []EventType `json:"events" validate:"max=4,dive,oneof=todo.created todo.updated todo.completed todo.deleted"`
+	Secret string      `json:"secret" validate:"required,min=16,max=256"`
+}
+
+func (wr *WebhookRequest) Bind(r *http.Request) error {
+	return utils.ValidateStruct(wr)
+}
+
+// Webhook - the webhook of a validated request
+func (wr *WebhookRequest) Webhook() *Webhook {
+	events := []EventType{}
+	seen := map[EventType]bool{}
+	for _, eventType := range wr.Events {
+		if !seen[eventType] {
+			events = append(events, eventType)
+			seen[eventType] = true
+		}
+	}
+
+	return &Webhook{
+		URL:    wr.URL,
+		Events: events,
+		Secret: wr.Secret,
+	}
+}
+
+// DeliveryStatus - state of a delivery
+type DeliveryStatus string
+
+// Delivery statuses, a pending delivery is retried until it succeeds or it is dead
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// Delivery - an event sent to a webhook. TraceParent is the W3C traceparent of the write of the event, the
+// attempts continue its trace.
+type Delivery struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID     primitive.ObjectID `json:"webhook_id" bson:"webhookId"`
+	EventID       primitive.ObjectID `json:"event_id" bson:"eventId"`
+	EventType     EventType          `json:"event_type" bson:"eventType"`
+	Payload       json.RawMessage    `json:"payload" bson:"payload"`
+	TraceParent   string             `json:"traceparent,omitempty" bson:"traceParent,omitempty"`
+	Status        DeliveryStatus     `json:"status" bson:"status"`
+	Attempts      []DeliveryAttempt  `json:"attempts" bson:"attempts"`
+	NextAttemptAt *time.Time         `json:"next_attempt_at" bson:"nextAttemptAt"` // nil once succeeded or dead
+	CreatedAt     time.Time          `json:"created_at" bson:"createdAt"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updatedAt"`
+}
+
+// DeliveryAttempt - a request of a delivery, StatusCode is 0 when no response was received
+type DeliveryAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms" bson:"durationMs"`
+}
+
+// DeliveryFilter - criteria of a delivery list, zero values match everything
+type DeliveryFilter struct {
+	WebhookID primitive.ObjectID
+	Status    DeliveryStatus
+}
+
+// DeliveryListRequest - form for delivery list validation
+type DeliveryListRequest struct {
+	Status  string `form:"status" json:"status" validate:"omitempty,oneof=pending succeeded dead"`
+	Page    string `form:"page" json:"page" validate:"sgte=1"`
+	PerPage string `form:"per_page" json:"per_page" validate:"sgte=1,slte=100"`
+}
@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSubscribes(t *testing.T) {
+	all := &models.Webhook{}
+	some := &models.Webhook{Events: []models.EventType{models.EventTodoCreated, models.EventTodoDeleted}}
+
+	for _, eventType := range models.EventTypes {
+		assert.True(t, all.Subscribes(eventType), eventType)
+	}
+	assert.True(t, some.Subscribes(models.EventTodoCreated))
+	assert.True(t, some.Subscribes(models.EventTodoDeleted))
+	assert.False(t, some.Subscribes(models.EventTodoUpdated))
+	assert.False(t, some.Subscribes(models.EventTodoCompleted))
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"todo.created"}`)
+
+	mac := hmac.New(sha256.New, []byte("0123456789abcdef"))
+	mac.Write([]byte(`1700000000.{"type":"todo.created"}`))
+
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), models.WebhookSignature("0123456789abcdef", 1700000000, body))
+	assert.NotEqual(t, models.WebhookSignature("0123456789abcdef", 1700000000, body), models.WebhookSignature("0123456789abcdef", 1700000001, body))
+	assert.NotEqual(t, models.WebhookSignature("0123456789abcdef", 1700000000, body), models.WebhookSignature("fedcba9876543210", 1700000000, body))
+}
+
+func TestWebhookRequestValidation(t *testing.T) {
+	secret := "0123456789abcdef"
+
+	t.Run("valid requests", func(t *testing.T) {
+		for _, request := range []*models.WebhookRequest{
+			{URL: "http://localhost:8080/hook", Secret: secret},
+			{URL: "https://example.com/hook?team=a", Events: []models.EventType{models.EventTodoCompleted}, Secret: secret},
+		} {
+			assert.NoError(t, utils.ValidateStruct(request), request.URL)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for field, request := range map[string]*models.WebhookRequest{
+			"url":       {URL: "ftp://example.com/hook", Secret: secret},
+			"events[0]": {URL: "http://localhost/hook", Events: []models.EventType{"todo.archived"}, Secret: secret},
+			"secret":    {URL: "http://localhost/hook", Secret: "short"},
+		} {
+			var validationErrors validator.ValidationErrors
+			err := utils.ValidateStruct(request)
+			if assert.True(t, errors.As(err, &validationErrors), field) {
+				assert.Contains(t, utils.ValidatonError(err).Errors, field)
+			}
+		}
+
+		err := utils.ValidateStruct(&models.WebhookRequest{URL: "/hook", Secret: secret})
+		if assert.Error(t, err) {
+			assert.Equal(t, "url must be an http or https URL", utils.ValidatonError(err).Errors["url"])
+		}
+		assert.Error(t, utils.ValidateStruct(&models.WebhookRequest{URL: "http://localhost/" + strings.Repeat("a", 2048), Secret: secret}))
+	})
+}
+
+func TestWebhookRequestWebhook(t *testing.T) {
+	request := &models.WebhookRequest{
+		URL:    "http://localhost/hook",
+		Events: []models.EventType{models.EventTodoCreated, models.EventTodoDeleted, models.EventTodoCreated},
+		Secret: "0123456789abcdef",
+	}
+
+	webhook := request.Webhook()
+
+	assert.Equal(t, "http://localhost/hook", webhook.URL)
+	assert.Equal(t, []models.EventType{models.EventTodoCreated, models.EventTodoDeleted}, webhook.Events)
+	assert.Equal(t, "0123456789abcdef", webhook.Secret)
+	assert.Equal(t, []models.EventType{}, (&models.WebhookRequest{}).Webhook().Events)
+}
+
+func TestDeliveryListRequestValidation(t *testing.T) {
+	assert.NoError(t, utils.ValidateStruct(&models.DeliveryListRequest{}))
+	assert.NoError(t, utils.ValidateStruct(&models.DeliveryListRequest{Status: "dead", Page: "2", PerPage: "100"}))
+	assert.Error(t, utils.ValidateStruct(&models.DeliveryListRequest{Status: "failed"}))
+	assert.Error(t, utils.ValidateStruct(&models.DeliveryListRequest{PerPage: "101"}))
+	assert.Error(t, utils.ValidateStruct(&models.DeliveryListRequest{Page: "0"}))
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+)
+
+// errBulkFailed - a write of an atomic bulk failed, the bulk is rolled back
+var errBulkFailed = errors.New("a write of the atomic bulk failed")
+
+// startBulkSpan - start the span of a whole bulk, the batches get child spans
+func startBulkSpan(ctx context.Context, system attribute.KeyValue, writes []*models.TodoWrite, atomic bool) (context.Context, trace.Span) {
+	ctx, span := startSpan(ctx, "BulkWrite", system)
+	span.SetAttributes(
+		attribute.Int("todo.bulk.size", len(writes)),
+		attribute.Bool("todo.bulk.atomic", atomic),
+	)
+
+	return ctx, span
+}
+
+// newBulkResults - a result per write, filled as the writes are done
+func newBulkResults(writes []*models.TodoWrite) []*models.BulkResult {
+	results := make([]*models.BulkResult, len(writes))
+	for i, write := range writes {
+		results[i] = &models.BulkResult{Op: write.Op, ID: write.ID}
+	}
+
+	return results
+}
+
+// failBulk - fail the results that succeeded with err, their writes were rolled back
+func failBulk(results []*models.BulkResult, err error) {
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+
+		if result.Op == models.BulkCreate {
+			result.ID = ""
+		}
+		result.Todo = nil
+		result.Err = err
+	}
+}
+
+// abortBulk - fail the results of an atomic bulk that was rolled back because one of its writes failed
+func abortBulk(results []*models.BulkResult) {
+	failBulk(results, models.NewError(models.ErrAborted, "TodoRepository.BulkWrite", "", nil))
+}
+
+// inBatches - run write on every batch of models.BulkBatchSize writes, each in a child span of ctx, write fills
+// the results of its batch. An error of a batch fails the batch unless atomic, where it stops the bulk like a
+// failed write, with errBulkFailed.
+func inBatches(ctx context.Context, system attribute.KeyValue, writes []*models.TodoWrite, results []*models.BulkResult, atomic bool,
+	write func(ctx context.Context, writes []*models.TodoWrite, results []*models.BulkResult) error) error {
+	for start := 0; start < len(writes); start += models.BulkBatchSize {
+		end := start + models.BulkBatchSize
+		if end > len(writes) {
+			end = len(writes)
+		}
+
+		err := writeBatch(ctx, system, start/models.BulkBatchSize, writes[start:end], results[start:end], write)
+		if err != nil && atomic {
+			return err
+		}
+		if err != nil {
+			failBulk(results[start:end], err)
+		}
+
+		if atomic && models.Failed(results[start:end]) > 0 {
+			return errBulkFailed
+		}
+	}
+
+	return nil
+}
+
+// writeBatch - run write on a batch of a bulk in its own span
+func writeBatch(ctx context.Context, system attribute.KeyValue, batch int, writes []*models.TodoWrite, results []*models.BulkResult,
+	write func(ctx context.Context, writes []*models.TodoWrite, results []*models.BulkResult) error) error {
+	ctx, span := startSpan(ctx, "BulkWriteBatch", system)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("todo.bulk.batch", batch),
+		attribute.Int("todo.bulk.batch_size", len(writes)),
+	)
+
+	if err := write(ctx, writes, results); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("todo.bulk.failed", models.Failed(results)))
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+// IdempotencyRepository represent the store of the requests made with an idempotency key
+type IdempotencyRepository interface {
+	// Reserve stores record, unless the key has a record that didn't expire and is done or still locked, which is
+	// returned instead
+	Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// Complete stores the response of record while it holds the reservation of its key
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	// Release deletes the reservation of record when its request failed, so it can be retried
+	Release(ctx context.Context, record *models.IdempotencyRecord) error
+}
+
+// maxReserveAttempts - a key expiring or released while it is reserved again is retried a few times
+const maxReserveAttempts = 3
+
+// errReserve - the key of a record kept changing while it was reserved
+var errReserve = errors.New("idempotency key changed while reserving it")
+
+type mongoIdempotencyRepository struct {
+	client *mongo.Client
+}
+
+// startIdempotencySpan - start an IdempotencyRepository span tagged with the database system
+func startIdempotencySpan(ctx context.Context, name string, system attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer("IdempotencyRepository").Start(ctx, "IdempotencyRepository."+name, trace.WithAttributes(system))
+}
+
+// NewMongoIdempotencyRepository will create an IdempotencyRepository, expired keys are deleted by the TTL index
+// MigrateMongo creates
+func NewMongoIdempotencyRepository(client *mongo.Client) IdempotencyRepository {
+	return &mongoIdempotencyRepository{
+		client: client,
+	}
+}
+
+func (m *mongoIdempotencyRepository) collection() *mongo.Collection {
+	return m.client.Database(os.Getenv("DB_NAME")).Collection("idempotency_key")
+}
+
+// Reserve - insert record, the _id is the key
+func (m *mongoIdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	ctx, span := startIdempotencySpan(ctx, "Reserve", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		_, err := m.collection().InsertOne(ctx, record)
+		if err == nil {
+			return nil, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		stored := &models.IdempotencyRecord{}
+		err = m.collection().FindOne(ctx, bson.M{"_id": record.Key}).Decode(stored)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		now := utils.GetTimeNow()
+		if !stored.Expired(now) {
+			if stored.Done() || stored.Locked(now) {
+				return stored, nil
+			}
+
+			// The request holding the key is gone, the first retry to get here takes it over
+			result, err := m.collection().ReplaceOne(ctx, bson.M{
+				"_id":         record.Key,
+				"status":      0,
+				"lockedUntil": bson.M{"$not": bson.M{"$gt": now}},
+			}, record)
+			if err != nil {
+				pkg_tracing.RecordError(span, err)
+				return nil, err
+			}
+			if result.MatchedCount == 1 {
+				return nil, nil
+			}
+			continue
+		}
+
+		// The TTL monitor runs every minute, expired keys can still be stored
+		_, err = m.collection().DeleteOne(ctx, bson.M{"_id": record.Key, "expiresAt": stored.ExpiresAt})
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
+
+	pkg_tracing.RecordError(span, errReserve)
+	return nil, errReserve
+}
+
+// Complete - set the response of the reserved record
+func (m *mongoIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := startIdempotencySpan(ctx, "Complete", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	_, err := m.collection().UpdateOne(ctx,
+		bson.M{"_id": record.Key, "fingerprint": record.Fingerprint, "status": 0, "lockedUntil": record.LockedUntil},
+		bson.M{"$set": bson.M{"status": record.Status, "header": record.Header, "body": record.Body}},
+	)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Release - delete the reserved record while it has no response
+func (m *mongoIdempotencyRepository) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := startIdempotencySpan(ctx, "Release", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	_, err := m.collection().DeleteOne(ctx, bson.M{"_id": record.Key, "status": 0, "lockedUntil": record.LockedUntil})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
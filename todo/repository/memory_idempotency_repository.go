@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+// NewMemoryIdempotencyRepository will create an in-memory IdempotencyRepository, safe for concurrent use
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{
+		records: map[string]*models.IdempotencyRecord{},
+	}
+}
+
+// copyRecord - records are copied in and out so callers can't change the stored ones
+func copyRecord(record *models.IdempotencyRecord) *models.IdempotencyRecord {
+	result := *record
+	result.Header = map[string]string{}
+	for key, value := range record.Header {
+		result.Header[key] = value
+	}
+	result.Body = append([]byte(nil), record.Body...)
+
+	return &result
+}
+
+// holds - record still has the reservation of stored, no response was stored and no retry took it over
+func holds(stored *models.IdempotencyRecord, record *models.IdempotencyRecord) bool {
+	return !stored.Done() && stored.LockedUntil.Equal(record.LockedUntil)
+}
+
+// Reserve - store record, the expired records are deleted first and a record without a response is taken over
+// once it is no longer locked
+func (m *memoryIdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	_, span := startIdempotencySpan(ctx, "Reserve", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := utils.GetTimeNow()
+	for key, stored := range m.records {
+		if stored.Expired(now) {
+			delete(m.records, key)
+		}
+	}
+
+	if stored, ok := m.records[record.Key]; ok && (stored.Done() || stored.Locked(now)) {
+		return copyRecord(stored), nil
+	}
+
+	m.records[record.Key] = copyRecord(record)
+
+	return nil, nil
+}
+
+// Complete - set the response of the reserved record
+func (m *memoryIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	_, span := startIdempotencySpan(ctx, "Complete", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.records[record.Key]
+	if !ok || !holds(stored, record) || stored.Fingerprint != record.Fingerprint {
+		return nil
+	}
+
+	completed := copyRecord(record)
+	completed.CreatedAt = stored.CreatedAt
+	completed.ExpiresAt = stored.ExpiresAt
+	m.records[record.Key] = completed
+
+	return nil
+}
+
+// Release - delete the reserved record while it has no response
+func (m *memoryIdempotencyRepository) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	_, span := startIdempotencySpan(ctx, "Release", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.records[record.Key]; ok && holds(stored, record) {
+		delete(m.records, record.Key)
+	}
+
+	return nil
+}
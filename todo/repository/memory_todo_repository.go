@@ -0,0 +1,598 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+// dbSystemMemory - db.system attribute of the in-memory repository spans
+var dbSystemMemory = semconv.DBSystemKey.String("memory")
+
+type memoryTodoRepository struct {
+	mu    sync.RWMutex
+	todos map[primitive.ObjectID]*models.Todo
+	// order keeps insertion order, like the natural order of a Mongo collection
+	order     []primitive.ObjectID
+	revisions map[primitive.ObjectID][]*models.Revision
+	// outbox keeps the unpublished events in the order of their writes
+	outbox []*outboxEvent
+}
+
+// NewMemoryTodoRepository will create an in-memory TodoRepository, safe for concurrent use
+func NewMemoryTodoRepository() TodoRepository {
+	return &memoryTodoRepository{
+		todos:     map[primitive.ObjectID]*models.Todo{},
+		revisions: map[primitive.ObjectID][]*models.Revision{},
+	}
+}
+
+// match - find todo matching filter, the keyword is a case-insensitive literal like the Mongo $regex filter.
+// Todos are copied with their score when searching. Must be called with m.mu held.
+func (m *memoryTodoRepository) match(filter models.TodoFilter) ([]*models.Todo, error) {
+	regex, err := regexp.Compile("(?i)" + regexp.QuoteMeta(filter.Keyword))
+	if err != nil {
+		return nil, err
+	}
+
+	now := utils.GetTimeNow()
+	search := models.ParseSearch(filter.Search)
+
+	var results []*models.Todo
+	for _, id := range m.order {
+		todo := m.todos[id]
+		if filter.Search != "" {
+			if !search.Match(todo) {
+				continue
+			}
+
+			todo = copyTodo(todo)
+			todo.Score = search.Score(todo)
+		}
+
+		if regex.MatchString(todo.Title) && matchFilter(todo, filter, now) {
+			results = append(results, todo)
+		}
+	}
+
+	return results, nil
+}
+
+// matchFilter - todo matches the criteria of filter besides the keyword
+func matchFilter(todo *models.Todo, filter models.TodoFilter, now time.Time) bool {
+	if (todo.DeletedAt != nil) != filter.Deleted {
+		return false
+	}
+
+	if filter.Status != "" && todo.Status != filter.Status {
+		return false
+	}
+
+	for _, tag := range filter.Tags {
+		if !hasTag(todo, tag) {
+			return false
+		}
+	}
+
+	if len(filter.IDs) > 0 && !hasID(filter.IDs, todo.ID) {
+		return false
+	}
+
+	if filter.PriorityMin != nil && todo.Priority < *filter.PriorityMin {
+		return false
+	}
+	if filter.PriorityMax != nil && todo.Priority > *filter.PriorityMax {
+		return false
+	}
+
+	if filter.Overdue {
+		if todo.DueAt == nil || !todo.DueAt.Before(now) {
+			return false
+		}
+		if todo.Status != models.StatusTodo && todo.Status != models.StatusInProgress {
+			return false
+		}
+	}
+	if filter.DueBefore != nil && (todo.DueAt == nil || !todo.DueAt.Before(*filter.DueBefore)) {
+		return false
+	}
+	if filter.DueAfter != nil && (todo.DueAt == nil || !todo.DueAt.After(*filter.DueAfter)) {
+		return false
+	}
+
+	if filter.After != nil && compareTodos(todo, filter.After.Key, filter.After.Sort) <= 0 {
+		return false
+	}
+
+	return true
+}
+
+func hasTag(todo *models.Todo, tag string) bool {
+	for _, value := range todo.Tags {
+		if value == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, value := range ids {
+		if value == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copyTodo - copy of todo sharing no memory with it
+func copyTodo(todo *models.Todo) *models.Todo {
+	result := *todo
+	if todo.CompletedAt != nil {
+		completedAt := *todo.CompletedAt
+		result.CompletedAt = &completedAt
+	}
+	if todo.DueAt != nil {
+		dueAt := *todo.DueAt
+		result.DueAt = &dueAt
+	}
+	if todo.DeletedAt != nil {
+		deletedAt := *todo.DeletedAt
+		result.DeletedAt = &deletedAt
+	}
+	result.Tags = append([]string{}, todo.Tags...)
+
+	return &result
+}
+
+// writable - live todo docID at version for op, any version when 0. Must be called with m.mu held.
+func (m *memoryTodoRepository) writable(op string, id string, docID primitive.ObjectID, version int64) (*models.Todo, error) {
+	todo, ok := m.todos[docID]
+	if !ok || todo.DeletedAt != nil {
+		return nil, models.NewError(models.ErrNotFound, op, id, nil)
+	}
+
+	if version > 0 && todo.Version != version {
+		return nil, models.NewError(models.ErrPreconditionFailed, op, id, nil)
+	}
+
+	return todo, nil
+}
+
+// commit - replace todo before by after and record the revision and the event, before is nil for a new
+// todo. Must be called with m.mu held.
+func (m *memoryTodoRepository) commit(ctx context.Context, action models.RevisionAction, before, after *models.Todo) error {
+	revision, err := newRevision(ctx, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	if before == nil {
+		m.order = append(m.order, after.ID)
+	}
+	m.todos[after.ID] = after
+	m.revisions[after.ID] = append(m.revisions[after.ID], revision)
+	m.outbox = append(m.outbox, newOutboxEvent(ctx, action, before, after))
+
+	return nil
+}
+
+// FindAll - find all todo
+func (m *memoryTodoRepository) FindAll(ctx context.Context, filter models.TodoFilter, order models.TodoSort, limit int, offset int) ([]*models.Todo, error) {
+	_, span := startSpan(ctx, "FindAll", dbSystemMemory)
+	defer span.End()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched, err := m.match(filter)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return []*models.Todo{}, err
+	}
+	sortTodos(matched, order)
+
+	if offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[offset:]
+
+	// A zero limit means no limit, like options.Find().SetLimit(0)
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+
+	var results []*models.Todo
+	for _, todo := range matched {
+		results = append(results, copyTodo(todo))
+	}
+
+	return results, nil
+}
+
+// CountFindAll - count find all todo
+func (m *memoryTodoRepository) CountFindAll(ctx context.Context, filter models.TodoFilter) (int, error) {
+	_, span := startSpan(ctx, "CountFindAll", dbSystemMemory)
+	defer span.End()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched, err := m.match(filter)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	return len(matched), nil
+}
+
+// FindById - find todo by id
+func (m *memoryTodoRepository) FindById(ctx context.Context, id string) (*models.Todo, error) {
+	_, span := startSpan(ctx, "FindById", dbSystemMemory)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.FindById", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	todo, ok := m.todos[docID]
+	if !ok || todo.DeletedAt != nil {
+		err = models.NewError(models.ErrNotFound, "TodoRepository.FindById", id, nil)
+		pkg_tracing.RecordError(span, err)
+		return &models.Todo{}, err
+	}
+
+	return copyTodo(todo), nil
+}
+
+// Store - store todo
+func (m *memoryTodoRepository) Store(ctx context.Context, value *models.Todo) (*models.Todo, error) {
+	_, span := startSpan(ctx, "Store", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	todo, err := m.store(ctx, value)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return &models.Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// store - store a new todo with the fields of value, and return a copy. Must be called with m.mu held.
+func (m *memoryTodoRepository) store(ctx context.Context, value *models.Todo) (*models.Todo, error) {
+	todo := copyTodo(newTodo(value, utils.GetTimeNow()))
+	todo.ID = primitive.NewObjectID()
+
+	if err := m.commit(ctx, models.ActionCreate, nil, todo); err != nil {
+		return nil, err
+	}
+
+	return copyTodo(todo), nil
+}
+
+// Update - update todo by id, and return the updated todo
+func (m *memoryTodoRepository) Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	_, span := startSpan(ctx, "Update", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	todo, err := m.update(ctx, id, value, version)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// update - update todo by id, and return a copy of the updated todo. Must be called with m.mu held.
+func (m *memoryTodoRepository) update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidID, "TodoRepository.Update", id, err)
+	}
+
+	before, err := m.writable("TodoRepository.Update", id, docID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	todo := updated(before, value, utils.GetTimeNow())
+	if err := m.commit(ctx, models.ActionUpdate, before, todo); err != nil {
+		return nil, err
+	}
+
+	return copyTodo(todo), nil
+}
+
+// Patch - set the fields of todo by id to the ones of value, and return the patched todo
+func (m *memoryTodoRepository) Patch(ctx context.Context, id string, value *models.Todo, fields []string, version int64) (*models.Todo, error) {
+	_, span := startSpan(ctx, "Patch", dbSystemMemory)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Patch", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, err := m.writable("TodoRepository.Patch", id, docID, version)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	todo := copyTodo(before)
+	applyPatch(todo, value, fields)
+	todo.UpdatedAt = utils.GetTimeNow()
+	todo.Version++
+
+	if err := m.commit(ctx, models.ActionPatch, before, todo); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return copyTodo(todo), nil
+}
+
+// Delete - move todo by id to the trash
+func (m *memoryTodoRepository) Delete(ctx context.Context, id string, version int64) error {
+	_, span := startSpan(ctx, "Delete", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.delete(ctx, id, version); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// delete - move todo by id to the trash. Must be called with m.mu held.
+func (m *memoryTodoRepository) delete(ctx context.Context, id string, version int64) error {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.NewError(models.ErrInvalidID, "TodoRepository.Delete", id, err)
+	}
+
+	before, err := m.writable("TodoRepository.Delete", id, docID, version)
+	if err != nil {
+		return err
+	}
+
+	return m.commit(ctx, models.ActionDelete, before, deleted(before, utils.GetTimeNow()))
+}
+
+// Restore - move todo by id out of the trash, and return the restored todo
+func (m *memoryTodoRepository) Restore(ctx context.Context, id string) (*models.Todo, error) {
+	_, span := startSpan(ctx, "Restore", dbSystemMemory)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Restore", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, ok := m.todos[docID]
+	if !ok || before.DeletedAt == nil {
+		err = models.NewError(models.ErrNotFound, "TodoRepository.Restore", id, nil)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	todo := copyTodo(before)
+	todo.DeletedAt = nil
+	todo.UpdatedAt = utils.GetTimeNow()
+	todo.Version++
+
+	if err := m.commit(ctx, models.ActionRestore, before, todo); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return copyTodo(todo), nil
+}
+
+// Purge - permanently delete the todos deleted before before, and return how many
+func (m *memoryTodoRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	_, span := startSpan(ctx, "Purge", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Revisions outlive the todo, they are the audit trail
+	order := m.order[:0]
+	purged := 0
+	for _, docID := range m.order {
+		todo := m.todos[docID]
+		if todo.DeletedAt != nil && todo.DeletedAt.Before(before) {
+			delete(m.todos, docID)
+			purged++
+			continue
+		}
+
+		order = append(order, docID)
+	}
+	m.order = order
+
+	return purged, nil
+}
+
+// FindRevisions - find the revisions of todo by id, oldest first
+func (m *memoryTodoRepository) FindRevisions(ctx context.Context, id string) ([]*models.Revision, error) {
+	_, span := startSpan(ctx, "FindRevisions", dbSystemMemory)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.FindRevisions", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := []*models.Revision{}
+	for _, revision := range m.revisions[docID] {
+		result := *revision
+		result.Changes = append([]models.FieldChange{}, revision.Changes...)
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+
+// BulkWrite - create, update and delete todos like Store, Update and Delete. An atomic bulk that fails
+// puts the todos back as they were.
+func (m *memoryTodoRepository) BulkWrite(ctx context.Context, writes []*models.TodoWrite, atomic bool) ([]*models.BulkResult, error) {
+	ctx, span := startBulkSpan(ctx, dbSystemMemory, writes, atomic)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Writes replace todos and append to the slices, so shallow copies keep the state before the bulk
+	todos := make(map[primitive.ObjectID]*models.Todo, len(m.todos))
+	for id, todo := range m.todos {
+		todos[id] = todo
+	}
+	revisions := make(map[primitive.ObjectID][]*models.Revision, len(m.revisions))
+	for id, todoRevisions := range m.revisions {
+		revisions[id] = todoRevisions
+	}
+	order, outbox := m.order, m.outbox
+
+	results := newBulkResults(writes)
+	err := inBatches(ctx, dbSystemMemory, writes, results, atomic, func(ctx context.Context, writes []*models.TodoWrite, results []*models.BulkResult) error {
+		for i, write := range writes {
+			switch write.Op {
+			case models.BulkCreate:
+				results[i].Todo, results[i].Err = m.store(ctx, write.Value)
+				if results[i].Err == nil {
+					results[i].ID = results[i].Todo.ID.Hex()
+				}
+			case models.BulkUpdate:
+				results[i].Todo, results[i].Err = m.update(ctx, write.ID, write.Value, write.Version)
+			case models.BulkDelete:
+				results[i].Err = m.delete(ctx, write.ID, write.Version)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		m.todos, m.revisions, m.order, m.outbox = todos, revisions, order, outbox
+		if errors.Is(err, errBulkFailed) {
+			abortBulk(results)
+			return results, nil
+		}
+
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ClaimEvents - postpone the available events, oldest first
+func (m *memoryTodoRepository) ClaimEvents(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Event, error) {
+	_, span := startSpan(ctx, "ClaimEvents", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Claims postpone events, the outbox isn't in the order of availability
+	available := []*outboxEvent{}
+	for _, event := range m.outbox {
+		if !event.AvailableAt.After(now) {
+			available = append(available, event)
+		}
+	}
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].AvailableAt.Before(available[j].AvailableAt)
+	})
+	if len(available) > limit {
+		available = available[:limit]
+	}
+
+	results := []*models.Event{}
+	for _, event := range available {
+		event.AvailableAt = until
+
+		result := event.Event
+		if result.Todo != nil {
+			result.Todo = copyTodo(result.Todo)
+		}
+		results = append(results, &result)
+	}
+
+	span.SetAttributes(attribute.Int("outbox.event.claimed", len(results)))
+
+	return results, nil
+}
+
+// DeleteEvents - delete the events by id from the outbox
+func (m *memoryTodoRepository) DeleteEvents(ctx context.Context, ids []primitive.ObjectID) error {
+	_, span := startSpan(ctx, "DeleteEvents", dbSystemMemory)
+	defer span.End()
+
+	deleted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A new slice, a BulkWrite may hold the old one to roll back
+	outbox := []*outboxEvent{}
+	for _, event := range m.outbox {
+		if !deleted[event.ID] {
+			outbox = append(outbox, event)
+		}
+	}
+	m.outbox = outbox
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package repository_test
+
+import (
+	"testing"
+
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/todo/repository/repositorytest"
+)
+
+func TestMemoryTodoRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.TodoRepository {
+		return repository.NewMemoryTodoRepository()
+	})
+}
+
+func TestMemoryIdempotencyRepository(t *testing.T) {
+	repositorytest.RunIdempotency(t, func(t *testing.T) repository.IdempotencyRepository {
+		return repository.NewMemoryIdempotencyRepository()
+	})
+}
+
+func TestMemoryWebhookRepository(t *testing.T) {
+	repositorytest.RunWebhook(t, func(t *testing.T) repository.WebhookRepository {
+		return repository.NewMemoryWebhookRepository()
+	})
+}
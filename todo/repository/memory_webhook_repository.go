@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+type memoryWebhookRepository struct {
+	mu       sync.Mutex
+	webhooks map[primitive.ObjectID]*models.Webhook
+	// deliveries are kept in insertion order, i.e. oldest first
+	deliveries []*models.Delivery
+}
+
+// NewMemoryWebhookRepository will create an in-memory WebhookRepository, safe for concurrent use
+func NewMemoryWebhookRepository() WebhookRepository {
+	return &memoryWebhookRepository{
+		webhooks: map[primitive.ObjectID]*models.Webhook{},
+	}
+}
+
+func copyWebhook(webhook *models.Webhook) *models.Webhook {
+	result := *webhook
+	result.Events = append([]models.EventType{}, webhook.Events...)
+
+	return &result
+}
+
+func copyDelivery(delivery *models.Delivery) *models.Delivery {
+	result := *delivery
+	result.Payload = append([]byte(nil), delivery.Payload...)
+	result.Attempts = append([]models.DeliveryAttempt{}, delivery.Attempts...)
+	if delivery.NextAttemptAt != nil {
+		next := *delivery.NextAttemptAt
+		result.NextAttemptAt = &next
+	}
+
+	return &result
+}
+
+// sameTime - a and b are both nil or the same instant
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+// FindAll - find every webhook, oldest first like their ids
+func (m *memoryWebhookRepository) FindAll(ctx context.Context) ([]*models.Webhook, error) {
+	_, span := startWebhookSpan(ctx, "FindAll", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := []*models.Webhook{}
+	for _, webhook := range m.webhooks {
+		results = append(results, copyWebhook(webhook))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID.Hex() < results[j].ID.Hex()
+	})
+
+	return results, nil
+}
+
+// find - webhook by id, must be called with m.mu held
+func (m *memoryWebhookRepository) find(op string, id string) (*models.Webhook, error) {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidID, op, id, err)
+	}
+
+	webhook, ok := m.webhooks[docID]
+	if !ok {
+		return nil, models.NewError(models.ErrNotFound, op, id, nil)
+	}
+
+	return webhook, nil
+}
+
+// FindById - find webhook by id
+func (m *memoryWebhookRepository) FindById(ctx context.Context, id string) (*models.Webhook, error) {
+	_, span := startWebhookSpan(ctx, "FindById", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook, err := m.find("WebhookRepository.FindById", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyWebhook(webhook), nil
+}
+
+// Store - store webhook
+func (m *memoryWebhookRepository) Store(ctx context.Context, value *models.Webhook) (*models.Webhook, error) {
+	_, span := startWebhookSpan(ctx, "Store", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook := copyWebhook(value)
+	webhook.ID = primitive.NewObjectID()
+	webhook.CreatedAt = utils.GetTimeNow()
+	webhook.UpdatedAt = webhook.CreatedAt
+	m.webhooks[webhook.ID] = webhook
+
+	return copyWebhook(webhook), nil
+}
+
+// Update - update the url, events and secret of webhook by id
+func (m *memoryWebhookRepository) Update(ctx context.Context, id string, value *models.Webhook) (*models.Webhook, error) {
+	_, span := startWebhookSpan(ctx, "Update", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook, err := m.find("WebhookRepository.Update", id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = value.URL
+	webhook.Events = append([]models.EventType{}, value.Events...)
+	webhook.Secret = value.Secret
+	webhook.UpdatedAt = utils.GetTimeNow()
+
+	return copyWebhook(webhook), nil
+}
+
+// Delete - delete webhook by id and its deliveries
+func (m *memoryWebhookRepository) Delete(ctx context.Context, id string) error {
+	_, span := startWebhookSpan(ctx, "Delete", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook, err := m.find("WebhookRepository.Delete", id)
+	if err != nil {
+		return err
+	}
+
+	delete(m.webhooks, webhook.ID)
+
+	kept := m.deliveries[:0]
+	for _, delivery := range m.deliveries {
+		if delivery.WebhookID != webhook.ID {
+			kept = append(kept, delivery)
+		}
+	}
+	m.deliveries = kept
+
+	return nil
+}
+
+// StoreDeliveries - store deliveries, their ids are set
+func (m *memoryWebhookRepository) StoreDeliveries(ctx context.Context, deliveries []*models.Delivery) error {
+	_, span := startWebhookSpan(ctx, "StoreDeliveries", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		delivery.ID = primitive.NewObjectID()
+		m.deliveries = append(m.deliveries, copyDelivery(delivery))
+	}
+
+	return nil
+}
+
+// FindDeliveries - find the deliveries matching filter, newest first
+func (m *memoryWebhookRepository) FindDeliveries(ctx context.Context, filter models.DeliveryFilter, limit int, offset int) ([]*models.Delivery, error) {
+	_, span := startWebhookSpan(ctx, "FindDeliveries", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := []*models.Delivery{}
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		delivery := m.deliveries[i]
+		if !filter.WebhookID.IsZero() && delivery.WebhookID != filter.WebhookID {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(results) == limit {
+			break
+		}
+		results = append(results, copyDelivery(delivery))
+	}
+
+	return results, nil
+}
+
+// FindDeliveryById - find delivery by id
+func (m *memoryWebhookRepository) FindDeliveryById(ctx context.Context, id string) (*models.Delivery, error) {
+	_, span := startWebhookSpan(ctx, "FindDeliveryById", dbSystemMemory)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidID, "WebhookRepository.FindDeliveryById", id, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, delivery := range m.deliveries {
+		if delivery.ID == docID {
+			return copyDelivery(delivery), nil
+		}
+	}
+
+	return nil, models.NewError(models.ErrNotFound, "WebhookRepository.FindDeliveryById", id, nil)
+}
+
+// ClaimDeliveries - postpone the due deliveries, the ones due first are claimed first
+func (m *memoryWebhookRepository) ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Delivery, error) {
+	_, span := startWebhookSpan(ctx, "ClaimDeliveries", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*models.Delivery
+	for _, delivery := range m.deliveries {
+		if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	results := []*models.Delivery{}
+	for _, delivery := range due {
+		next := until
+		delivery.NextAttemptAt = &next
+		results = append(results, copyDelivery(delivery))
+	}
+
+	span.SetAttributes(attribute.Int("webhook.delivery.claimed", len(results)))
+
+	return results, nil
+}
+
+// UpdateDelivery - write the status, the attempts and the next attempt of delivery, the next attempt being
+// claim is the condition of the update
+func (m *memoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.Delivery, claim *time.Time) error {
+	_, span := startWebhookSpan(ctx, "UpdateDelivery", dbSystemMemory)
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, stored := range m.deliveries {
+		if stored.ID == delivery.ID {
+			if !sameTime(stored.NextAttemptAt, claim) {
+				return models.NewError(models.ErrConflict, "WebhookRepository.UpdateDelivery", delivery.ID.Hex(), errors.New("the delivery claim was lost"))
+			}
+
+			updated := copyDelivery(delivery)
+			updated.WebhookID = stored.WebhookID
+			updated.EventID = stored.EventID
+			updated.EventType = stored.EventType
+			updated.Payload = stored.Payload
+			updated.TraceParent = stored.TraceParent
+			updated.CreatedAt = stored.CreatedAt
+			m.deliveries[i] = updated
+			return nil
+		}
+	}
+
+	return models.NewError(models.ErrNotFound, "WebhookRepository.UpdateDelivery", delivery.ID.Hex(), nil)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"go-distributed-tracing/todo/models"
+)
+
+// outboxEvent - event stored in the outbox until it is published. Claims postpone AvailableAt, so another
+// relay only picks the event up again once the claim expired.
+type outboxEvent struct {
+	models.Event `bson:",inline"`
+	AvailableAt  time.Time `bson:"availableAt"`
+}
+
+// newOutboxEvent - outbox event of the write that changed todo from before to after, available right away.
+// It carries the trace context of ctx, so its consumers continue the trace of the write.
+func newOutboxEvent(ctx context.Context, action models.RevisionAction, before, after *models.Todo) *outboxEvent {
+	var event *models.Event
+	switch action {
+	case models.ActionCreate:
+		event = models.NewEvent(ctx, models.EventTodoCreated, after.ID, copyTodo(after), after.UpdatedAt)
+	case models.ActionDelete:
+		event = models.NewEvent(ctx, models.EventTodoDeleted, after.ID, nil, after.UpdatedAt)
+	default:
+		event = models.NewEvent(ctx, models.UpdateEventType(before, after), after.ID, copyTodo(after), after.UpdatedAt)
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	event.TraceParent = carrier.Get("traceparent")
+
+	return &outboxEvent{Event: *event, AvailableAt: after.UpdatedAt}
+}
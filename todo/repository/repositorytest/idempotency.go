@@ -0,0 +1,213 @@
+package repositorytest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// IdempotencyFactory - create an empty IdempotencyRepository for a single subtest
+type IdempotencyFactory func(t *testing.T) repository.IdempotencyRepository
+
+// RunIdempotency - run the IdempotencyRepository contract against repositories created by newRepo
+func RunIdempotency(t *testing.T, newRepo IdempotencyFactory) {
+	t.Run("Reserve", func(t *testing.T) { testReserve(t, newRepo(t)) })
+	t.Run("Complete", func(t *testing.T) { testComplete(t, newRepo(t)) })
+	t.Run("Release", func(t *testing.T) { testRelease(t, newRepo(t)) })
+	t.Run("expired", func(t *testing.T) { testExpired(t, newRepo(t)) })
+	t.Run("lock expired", func(t *testing.T) { testLockExpired(t, newRepo(t)) })
+	t.Run("concurrent reserve", func(t *testing.T) { testConcurrentReserve(t, newRepo(t)) })
+}
+
+func newRecord(key string, fingerprint string, ttl time.Duration) *models.IdempotencyRecord {
+	now := utils.GetTimeNow()
+	return &models.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+		LockedUntil: now.Add(time.Minute),
+	}
+}
+
+func testReserve(t *testing.T, repo repository.IdempotencyRepository) {
+	ctx := context.Background()
+
+	stored, err := repo.Reserve(ctx, newRecord("a", "1", time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// The first record is kept, whatever the fingerprint of the next ones
+	stored, err = repo.Reserve(ctx, newRecord("a", "2", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "a", stored.Key)
+	assert.Equal(t, "1", stored.Fingerprint)
+	assert.False(t, stored.Done())
+
+	stored, err = repo.Reserve(ctx, newRecord("b", "2", time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func testComplete(t *testing.T, repo repository.IdempotencyRepository) {
+	ctx := context.Background()
+
+	record := newRecord("a", "1", time.Hour)
+	_, err := repo.Reserve(ctx, record)
+	require.NoError(t, err)
+
+	record.Status = http.StatusCreated
+	record.Header = map[string]string{"Content-Type": "application/json", "Etag": `"1"`}
+	record.Body = []byte(`{"success":true}`)
+	require.NoError(t, repo.Complete(ctx, record))
+
+	stored, err := repo.Reserve(ctx, newRecord("a", "1", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.True(t, stored.Done())
+	assert.Equal(t, http.StatusCreated, stored.Status)
+	assert.Equal(t, record.Header, stored.Header)
+	assert.Equal(t, record.Body, stored.Body)
+	assert.WithinDuration(t, record.ExpiresAt, stored.ExpiresAt, timestampPrecision)
+
+	// Completing with another fingerprint leaves the record alone
+	other := newRecord("a", "2", time.Hour)
+	other.Status = http.StatusOK
+	require.NoError(t, repo.Complete(ctx, other))
+
+	stored, err = repo.Reserve(ctx, newRecord("a", "1", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, http.StatusCreated, stored.Status)
+}
+
+func testRelease(t *testing.T, repo repository.IdempotencyRepository) {
+	ctx := context.Background()
+
+	record := newRecord("a", "1", time.Hour)
+	_, err := repo.Reserve(ctx, record)
+	require.NoError(t, err)
+	require.NoError(t, repo.Release(ctx, record))
+
+	stored, err := repo.Reserve(ctx, newRecord("a", "2", time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// A completed record is not released
+	record = newRecord("b", "1", time.Hour)
+	_, err = repo.Reserve(ctx, record)
+	require.NoError(t, err)
+	record.Status = http.StatusCreated
+	require.NoError(t, repo.Complete(ctx, record))
+	require.NoError(t, repo.Release(ctx, record))
+
+	stored, err = repo.Reserve(ctx, newRecord("b", "1", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.True(t, stored.Done())
+
+	// Releasing an unknown key is a no-op
+	assert.NoError(t, repo.Release(ctx, newRecord("c", "1", time.Hour)))
+}
+
+func testExpired(t *testing.T, repo repository.IdempotencyRepository) {
+	ctx := context.Background()
+
+	record := newRecord("a", "1", -time.Minute)
+	_, err := repo.Reserve(ctx, record)
+	require.NoError(t, err)
+	record.Status = http.StatusCreated
+	require.NoError(t, repo.Complete(ctx, record))
+
+	stored, err := repo.Reserve(ctx, newRecord("a", "2", time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = repo.Reserve(ctx, newRecord("a", "3", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "2", stored.Fingerprint)
+}
+
+func testLockExpired(t *testing.T, repo repository.IdempotencyRepository) {
+	ctx := context.Background()
+
+	first := newRecord("a", "1", time.Hour)
+	first.LockedUntil = utils.GetTimeNow().Add(-time.Minute)
+	_, err := repo.Reserve(ctx, first)
+	require.NoError(t, err)
+
+	// A retry takes the key over once the first request no longer holds it
+	retry := newRecord("a", "1", time.Hour)
+	stored, err := repo.Reserve(ctx, retry)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = repo.Reserve(ctx, newRecord("a", "1", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.False(t, stored.Done())
+
+	// The first request can neither release nor complete the key of the retry
+	require.NoError(t, repo.Release(ctx, first))
+	first.Status = http.StatusOK
+	require.NoError(t, repo.Complete(ctx, first))
+
+	retry.Status = http.StatusCreated
+	require.NoError(t, repo.Complete(ctx, retry))
+
+	stored, err = repo.Reserve(ctx, newRecord("a", "1", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, http.StatusCreated, stored.Status)
+
+	// A completed record is never taken over
+	done := newRecord("b", "1", time.Hour)
+	done.LockedUntil = utils.GetTimeNow().Add(-time.Minute)
+	_, err = repo.Reserve(ctx, done)
+	require.NoError(t, err)
+	done.Status = http.StatusCreated
+	require.NoError(t, repo.Complete(ctx, done))
+
+	stored, err = repo.Reserve(ctx, newRecord("b", "1", time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.True(t, stored.Done())
+}
+
+func testConcurrentReserve(t *testing.T, repo repository.IdempotencyRepository) {
+	const requests = 10
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			stored, err := repo.Reserve(context.Background(), newRecord("a", "1", time.Hour))
+			assert.NoError(t, err)
+			if err == nil && stored == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, reserved)
+}
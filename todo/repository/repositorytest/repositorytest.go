@@ -0,0 +1,1171 @@
+// Package repositorytest provides the conformance suite every TodoRepository backend has to pass.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Factory - create an empty TodoRepository for a single subtest
+type Factory func(t *testing.T) repository.TodoRepository
+
+// timestampPrecision - backends may store timestamps truncated, e.g. Mongo keeps milliseconds
+const timestampPrecision = time.Millisecond
+
+// Run - run the TodoRepository contract against repositories created by newRepo
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("Store", func(t *testing.T) { testStore(t, newRepo(t)) })
+	t.Run("FindById", func(t *testing.T) { testFindByID(t, newRepo(t)) })
+	t.Run("FindAll keyword", func(t *testing.T) { testFindAllKeyword(t, newRepo(t)) })
+	t.Run("FindAll pagination", func(t *testing.T) { testFindAllPagination(t, newRepo(t)) })
+	t.Run("CountFindAll", func(t *testing.T) { testCountFindAll(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("Patch", func(t *testing.T) { testPatch(t, newRepo(t)) })
+	t.Run("version", func(t *testing.T) { testVersion(t, newRepo(t)) })
+	t.Run("status", func(t *testing.T) { testStatus(t, newRepo(t)) })
+	t.Run("due date, priority and tags", func(t *testing.T) { testPlanning(t, newRepo(t)) })
+	t.Run("sort", func(t *testing.T) { testSort(t, newRepo(t)) })
+	t.Run("search", func(t *testing.T) { testSearch(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+	t.Run("trash", func(t *testing.T) { testTrash(t, newRepo(t)) })
+	t.Run("revisions", func(t *testing.T) { testRevisions(t, newRepo(t)) })
+	t.Run("BulkWrite", func(t *testing.T) { testBulkWrite(t, newRepo(t)) })
+	t.Run("outbox", func(t *testing.T) { testOutbox(t, newRepo(t)) })
+	t.Run("FindAll ids", func(t *testing.T) { testFindAllIDs(t, newRepo(t)) })
+	t.Run("invalid id", func(t *testing.T) { testInvalidID(t, newRepo(t)) })
+	t.Run("concurrent writes", func(t *testing.T) { testConcurrentWrites(t, newRepo(t)) })
+}
+
+// store - store todos with the given titles, in order
+func store(t *testing.T, repo repository.TodoRepository, titles ...string) []*models.Todo {
+	t.Helper()
+
+	var todos []*models.Todo
+	for _, title := range titles {
+		todo, err := repo.Store(context.Background(), &models.Todo{
+			Title:       title,
+			Description: "description of " + title,
+		})
+		require.NoError(t, err)
+		todos = append(todos, todo)
+	}
+
+	return todos
+}
+
+func titles(todos []*models.Todo) []string {
+	var results []string
+	for _, todo := range todos {
+		results = append(results, todo.Title)
+	}
+
+	return results
+}
+
+func assertSameTodo(t *testing.T, expected, actual *models.Todo) {
+	t.Helper()
+
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.Title, actual.Title)
+	assert.Equal(t, expected.Description, actual.Description)
+	assert.Equal(t, expected.Status, actual.Status)
+	assertSameTime(t, expected.CompletedAt, actual.CompletedAt)
+	assertSameTime(t, expected.DueAt, actual.DueAt)
+	assert.Equal(t, expected.Priority, actual.Priority)
+	assert.Equal(t, expected.Tags, actual.Tags)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, timestampPrecision)
+	assert.WithinDuration(t, expected.UpdatedAt, actual.UpdatedAt, timestampPrecision)
+	assert.Equal(t, expected.Version, actual.Version)
+}
+
+func assertSameTime(t *testing.T, expected, actual *time.Time) {
+	t.Helper()
+
+	if expected == nil || actual == nil {
+		assert.Equal(t, expected == nil, actual == nil, "expected %v, got %v", expected, actual)
+		return
+	}
+	assert.WithinDuration(t, *expected, *actual, timestampPrecision)
+}
+
+func testStore(t *testing.T, repo repository.TodoRepository) {
+	before := time.Now()
+	todo, err := repo.Store(context.Background(), &models.Todo{
+		Title:       "Buy milk",
+		Description: "2 liters",
+	})
+	require.NoError(t, err)
+
+	assert.False(t, todo.ID.IsZero())
+	assert.Equal(t, "Buy milk", todo.Title)
+	assert.Equal(t, "2 liters", todo.Description)
+	assert.Equal(t, models.StatusTodo, todo.Status, "status defaults to todo")
+	assert.Nil(t, todo.CompletedAt)
+	assert.Nil(t, todo.DueAt)
+	assert.Equal(t, 0, todo.Priority)
+	assert.Equal(t, []string{}, todo.Tags, "tags are never null")
+	assert.WithinDuration(t, before, todo.CreatedAt, time.Minute)
+	assert.True(t, todo.CreatedAt.Equal(todo.UpdatedAt))
+
+	other := store(t, repo, "Walk dog")[0]
+	assert.NotEqual(t, todo.ID, other.ID)
+}
+
+func testFindByID(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todos := store(t, repo, "Buy milk", "Walk dog")
+
+	for _, todo := range todos {
+		found, err := repo.FindById(ctx, todo.ID.Hex())
+		require.NoError(t, err)
+		assertSameTodo(t, todo, found)
+	}
+
+	_, err := repo.FindById(ctx, primitive.NewObjectID().Hex())
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+}
+
+func testFindAllKeyword(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	store(t, repo, "Buy milk", "buy bread", "Walk dog", "Call BUYER")
+
+	t.Run("empty keyword matches all", func(t *testing.T) {
+		results, err := repo.FindAll(ctx, models.TodoFilter{}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Buy milk", "buy bread", "Walk dog", "Call BUYER"}, titles(results))
+	})
+
+	t.Run("case-insensitive substring", func(t *testing.T) {
+		results, err := repo.FindAll(ctx, models.TodoFilter{Keyword: "buy"}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Buy milk", "buy bread", "Call BUYER"}, titles(results))
+	})
+
+	t.Run("description is not searched", func(t *testing.T) {
+		results, err := repo.FindAll(ctx, models.TodoFilter{Keyword: "description"}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		results, err := repo.FindAll(ctx, models.TodoFilter{Keyword: "nothing"}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
+
+func testFindAllPagination(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	store(t, repo, "todo 1", "todo 2", "todo 3", "todo 4", "todo 5")
+
+	tests := []struct {
+		name     string
+		limit    int
+		offset   int
+		expected []string
+	}{
+		{name: "first page", limit: 2, offset: 0, expected: []string{"todo 1", "todo 2"}},
+		{name: "middle page", limit: 2, offset: 2, expected: []string{"todo 3", "todo 4"}},
+		{name: "last partial page", limit: 2, offset: 4, expected: []string{"todo 5"}},
+		{name: "offset at end", limit: 2, offset: 5, expected: nil},
+		{name: "offset past end", limit: 2, offset: 50, expected: nil},
+		{name: "limit larger than total", limit: 100, offset: 0, expected: []string{"todo 1", "todo 2", "todo 3", "todo 4", "todo 5"}},
+		{name: "zero limit means no limit", limit: 0, offset: 1, expected: []string{"todo 2", "todo 3", "todo 4", "todo 5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := repo.FindAll(ctx, models.TodoFilter{Keyword: "todo"}, nil, tt.limit, tt.offset)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, titles(results))
+		})
+	}
+}
+
+func testCountFindAll(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+
+	total, err := repo.CountFindAll(ctx, models.TodoFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+
+	store(t, repo, "Buy milk", "buy bread", "Walk dog")
+
+	total, err = repo.CountFindAll(ctx, models.TodoFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	// Counts ignore pagination and follow the FindAll keyword match
+	total, err = repo.CountFindAll(ctx, models.TodoFilter{Keyword: "BUY"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+
+	total, err = repo.CountFindAll(ctx, models.TodoFilter{Keyword: "nothing"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func testUpdate(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todos := store(t, repo, "Buy milk", "Walk dog")
+
+	res, err := repo.Update(ctx, todos[0].ID.Hex(), &models.Todo{
+		Title:       "Buy oat milk",
+		Description: "1 liter",
+	}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, todos[0].ID, res.ID)
+	assert.Equal(t, "Buy oat milk", res.Title)
+	assert.Equal(t, "1 liter", res.Description)
+	assert.Equal(t, models.StatusTodo, res.Status)
+	assert.Equal(t, []string{}, res.Tags)
+	assert.WithinDuration(t, todos[0].CreatedAt, res.CreatedAt, timestampPrecision)
+	assert.False(t, res.UpdatedAt.Before(res.CreatedAt))
+
+	// The updated todo is the stored one
+	found, err := repo.FindById(ctx, todos[0].ID.Hex())
+	require.NoError(t, err)
+	assertSameTodo(t, res, found)
+
+	// Other todos are left untouched
+	found, err = repo.FindById(ctx, todos[1].ID.Hex())
+	require.NoError(t, err)
+	assertSameTodo(t, todos[1], found)
+
+	_, err = repo.Update(ctx, primitive.NewObjectID().Hex(), &models.Todo{Title: "missing"}, 0)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+
+	total, err := repo.CountFindAll(ctx, models.TodoFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total, "updating a missing todo must not create it")
+}
+
+func testPatch(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todos := store(t, repo, "Buy milk", "Walk dog")
+
+	dueAt := time.Date(2022, 12, 31, 10, 0, 0, 0, time.UTC)
+	value := &models.Todo{
+		Title:       "ignored",
+		Description: "ignored",
+		Status:      models.StatusInProgress,
+		DueAt:       &dueAt,
+		Priority:    5,
+		Tags:        []string{"errand", "home"},
+	}
+	fields := []string{models.FieldStatus, models.FieldDueAt, models.FieldTags}
+
+	res, err := repo.Patch(ctx, todos[0].ID.Hex(), value, fields, 0)
+	require.NoError(t, err)
+
+	// Only the listed fields change
+	expected := *todos[0]
+	expected.Status = models.StatusInProgress
+	expected.DueAt = &dueAt
+	expected.Tags = []string{"errand", "home"}
+	expected.UpdatedAt = res.UpdatedAt
+	expected.Version = todos[0].Version + 1
+	assertSameTodo(t, &expected, res)
+	assert.False(t, res.UpdatedAt.Before(todos[0].UpdatedAt))
+
+	found, err := repo.FindById(ctx, todos[0].ID.Hex())
+	require.NoError(t, err)
+	assertSameTodo(t, res, found)
+
+	t.Run("clears fields", func(t *testing.T) {
+		res, err := repo.Patch(ctx, todos[0].ID.Hex(), &models.Todo{}, []string{models.FieldDueAt, models.FieldTags}, 0)
+		require.NoError(t, err)
+		assert.Nil(t, res.DueAt)
+		assert.Equal(t, []string{}, res.Tags)
+		assert.Equal(t, models.StatusInProgress, res.Status)
+	})
+
+	// Other todos are left untouched
+	found, err = repo.FindById(ctx, todos[1].ID.Hex())
+	require.NoError(t, err)
+	assertSameTodo(t, todos[1], found)
+
+	_, err = repo.Patch(ctx, primitive.NewObjectID().Hex(), value, fields, 0)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+}
+
+func testVersion(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todo := store(t, repo, "Buy milk")[0]
+	id := todo.ID.Hex()
+	assert.Equal(t, int64(1), todo.Version, "new todos start at version 1")
+
+	res, err := repo.Update(ctx, id, &models.Todo{Title: "Buy oat milk"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), res.Version, "any version matches 0")
+
+	_, err = repo.Update(ctx, id, &models.Todo{Title: "stale"}, 1)
+	assert.True(t, errors.Is(err, models.ErrPreconditionFailed), "expected ErrPreconditionFailed, got %v", err)
+
+	res, err = repo.Update(ctx, id, &models.Todo{Title: "Buy milk"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), res.Version)
+
+	_, err = repo.Patch(ctx, id, &models.Todo{Title: "stale"}, []string{models.FieldTitle}, 2)
+	assert.True(t, errors.Is(err, models.ErrPreconditionFailed), "expected ErrPreconditionFailed, got %v", err)
+
+	res, err = repo.Patch(ctx, id, &models.Todo{Priority: 2}, []string{models.FieldPriority}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), res.Version)
+
+	// Failed writes change nothing
+	found, err := repo.FindById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Buy milk", found.Title)
+	assert.Equal(t, int64(4), found.Version)
+
+	err = repo.Delete(ctx, id, 3)
+	assert.True(t, errors.Is(err, models.ErrPreconditionFailed), "expected ErrPreconditionFailed, got %v", err)
+
+	_, err = repo.Update(ctx, primitive.NewObjectID().Hex(), &models.Todo{Title: "missing"}, 1)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "a missing todo is not found at any version, got %v", err)
+
+	require.NoError(t, repo.Delete(ctx, id, 4))
+	_, err = repo.FindById(ctx, id)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+}
+
+func testStatus(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	completedAt := time.Now().Add(-time.Hour)
+
+	todos := store(t, repo, "Buy milk", "Walk dog")
+	done, err := repo.Store(ctx, &models.Todo{
+		Title:       "Call mom",
+		Status:      models.StatusDone,
+		CompletedAt: &completedAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDone, done.Status)
+
+	found, err := repo.FindById(ctx, done.ID.Hex())
+	require.NoError(t, err)
+	assertSameTodo(t, done, found)
+
+	_, err = repo.Update(ctx, todos[1].ID.Hex(), &models.Todo{
+		Title:  "Walk dog",
+		Status: models.StatusInProgress,
+	}, 0)
+	require.NoError(t, err)
+
+	// Update replaces the status and completed_at together with the other fields
+	_, err = repo.Update(ctx, done.ID.Hex(), &models.Todo{
+		Title:  "Call mom",
+		Status: models.StatusTodo,
+	}, 0)
+	require.NoError(t, err)
+
+	found, err = repo.FindById(ctx, done.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusTodo, found.Status)
+	assert.Nil(t, found.CompletedAt)
+
+	tests := []struct {
+		status   models.TodoStatus
+		expected []string
+	}{
+		{status: models.StatusTodo, expected: []string{"Buy milk", "Call mom"}},
+		{status: models.StatusInProgress, expected: []string{"Walk dog"}},
+		{status: models.StatusDone, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			results, err := repo.FindAll(ctx, models.TodoFilter{Status: tt.status}, nil, 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, titles(results))
+
+			total, err := repo.CountFindAll(ctx, models.TodoFilter{Status: tt.status})
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.expected), total)
+		})
+	}
+
+	results, err := repo.FindAll(ctx, models.TodoFilter{Keyword: "walk", Status: models.StatusTodo}, nil, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, results, "keyword and status must both match")
+}
+
+func testPlanning(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		value := now.Add(d)
+		return &value
+	}
+	priority := func(value int) *int {
+		return &value
+	}
+
+	var stored []*models.Todo
+	for _, value := range []*models.Todo{
+		{Title: "Pay rent", DueAt: at(-48 * time.Hour), Priority: 5, Tags: []string{"bills", "home"}},
+		{Title: "Buy milk", DueAt: at(48 * time.Hour), Priority: 2, Tags: []string{"errand", "home"}},
+		{Title: "Call mom", Tags: []string{}},
+		{Title: "File taxes", DueAt: at(-24 * time.Hour), Priority: 4, Tags: []string{"bills"}, Status: models.StatusDone, CompletedAt: at(-time.Hour)},
+		{Title: "Walk dog", DueAt: at(-time.Hour), Priority: 1, Status: models.StatusInProgress},
+	} {
+		todo, err := repo.Store(ctx, value)
+		require.NoError(t, err)
+		stored = append(stored, todo)
+	}
+
+	for _, todo := range stored {
+		found, err := repo.FindById(ctx, todo.ID.Hex())
+		require.NoError(t, err)
+		assertSameTodo(t, todo, found)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.TodoFilter
+		expected []string
+	}{
+		{name: "tag", filter: models.TodoFilter{Tags: []string{"home"}}, expected: []string{"Pay rent", "Buy milk"}},
+		{name: "every tag", filter: models.TodoFilter{Tags: []string{"bills", "home"}}, expected: []string{"Pay rent"}},
+		{name: "unknown tag", filter: models.TodoFilter{Tags: []string{"work"}}, expected: nil},
+		{name: "priority min", filter: models.TodoFilter{PriorityMin: priority(4)}, expected: []string{"Pay rent", "File taxes"}},
+		{name: "priority max", filter: models.TodoFilter{PriorityMax: priority(1)}, expected: []string{"Call mom", "Walk dog"}},
+		{name: "priority range", filter: models.TodoFilter{PriorityMin: priority(2), PriorityMax: priority(4)}, expected: []string{"Buy milk", "File taxes"}},
+		{name: "no priority", filter: models.TodoFilter{PriorityMax: priority(0)}, expected: []string{"Call mom"}},
+		{name: "overdue", filter: models.TodoFilter{Overdue: true}, expected: []string{"Pay rent", "Walk dog"}},
+		{name: "due before", filter: models.TodoFilter{DueBefore: &now}, expected: []string{"Pay rent", "File taxes", "Walk dog"}},
+		{name: "due after", filter: models.TodoFilter{DueAfter: &now}, expected: []string{"Buy milk"}},
+		{name: "due between", filter: models.TodoFilter{DueAfter: at(-30 * time.Hour), DueBefore: &now}, expected: []string{"File taxes", "Walk dog"}},
+		{name: "overdue with tag", filter: models.TodoFilter{Overdue: true, Tags: []string{"home"}}, expected: []string{"Pay rent"}},
+		{name: "keyword with priority", filter: models.TodoFilter{Keyword: "a", PriorityMin: priority(5)}, expected: []string{"Pay rent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := repo.FindAll(ctx, tt.filter, nil, 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, titles(results))
+
+			total, err := repo.CountFindAll(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.expected), total)
+		})
+	}
+
+	t.Run("update replaces tags and due date", func(t *testing.T) {
+		_, err := repo.Update(ctx, stored[1].ID.Hex(), &models.Todo{
+			Title:    "Buy milk",
+			Priority: 3,
+			Tags:     []string{"errand"},
+		}, 0)
+		require.NoError(t, err)
+
+		found, err := repo.FindById(ctx, stored[1].ID.Hex())
+		require.NoError(t, err)
+		assert.Nil(t, found.DueAt)
+		assert.Equal(t, 3, found.Priority)
+		assert.Equal(t, []string{"errand"}, found.Tags)
+
+		results, err := repo.FindAll(ctx, models.TodoFilter{Tags: []string{"home"}}, nil, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Pay rent"}, titles(results))
+	})
+}
+
+func testSort(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	now := time.Now()
+
+	at := func(offset time.Duration) *time.Time {
+		value := now.Add(offset)
+		return &value
+	}
+
+	for _, value := range []*models.Todo{
+		{Title: "Pay rent", DueAt: at(2 * time.Hour), Priority: 5},
+		{Title: "walk dog", Priority: 2},
+		{Title: "Buy milk", DueAt: at(time.Hour), Priority: 2},
+		{Title: "Call mom", DueAt: at(3 * time.Hour)},
+		{Title: "File taxes", Priority: 2},
+	} {
+		_, err := repo.Store(ctx, value)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		sort     string
+		expected []string
+	}{
+		{name: "default is creation order", sort: "", expected: []string{"Pay rent", "walk dog", "Buy milk", "Call mom", "File taxes"}},
+		{name: "title compares bytes", sort: "title", expected: []string{"Buy milk", "Call mom", "File taxes", "Pay rent", "walk dog"}},
+		{name: "descending title", sort: "-title", expected: []string{"walk dog", "Pay rent", "File taxes", "Call mom", "Buy milk"}},
+		{name: "ties keep creation order", sort: "-priority", expected: []string{"Pay rent", "walk dog", "Buy milk", "File taxes", "Call mom"}},
+		{name: "missing due date first", sort: "due_at", expected: []string{"walk dog", "File taxes", "Buy milk", "Pay rent", "Call mom"}},
+		{name: "missing due date last", sort: "-due_at", expected: []string{"Call mom", "Pay rent", "Buy milk", "walk dog", "File taxes"}},
+		{name: "several fields", sort: "-priority,title", expected: []string{"Pay rent", "Buy milk", "File taxes", "walk dog", "Call mom"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := repo.FindAll(ctx, models.TodoFilter{}, models.ParseTodoSort(tt.sort), 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, titles(results))
+		})
+	}
+
+	// Every sort and its reverse, the todos after each one are the rest of the sorted list
+	t.Run("keyset", func(t *testing.T) {
+		for _, tt := range tests {
+			for _, sort := range []models.TodoSort{models.ParseTodoSort(tt.sort), models.ParseTodoSort(tt.sort).Reverse()} {
+				all, err := repo.FindAll(ctx, models.TodoFilter{}, sort, 0, 0)
+				require.NoError(t, err)
+
+				for i, key := range all {
+					filter := models.TodoFilter{After: &models.Keyset{Sort: sort, Key: key}}
+
+					results, err := repo.FindAll(ctx, filter, sort, 0, 0)
+					require.NoError(t, err)
+					assert.Equal(t, titles(all[i+1:]), titles(results), "after %q in %q", key.Title, sort.String())
+
+					total, err := repo.CountFindAll(ctx, filter)
+					require.NoError(t, err)
+					assert.Equal(t, len(all)-i-1, total)
+				}
+			}
+		}
+
+		// The key only needs the sort fields and the id
+		all, err := repo.FindAll(ctx, models.TodoFilter{}, models.ParseTodoSort("-due_at"), 0, 0)
+		require.NoError(t, err)
+		key := &models.Todo{ID: all[1].ID, DueAt: all[1].DueAt}
+		results, err := repo.FindAll(ctx, models.TodoFilter{Keyword: "l", After: &models.Keyset{Sort: models.ParseTodoSort("-due_at"), Key: key}}, models.ParseTodoSort("-due_at"), 2, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Buy milk", "walk dog"}, titles(results))
+	})
+
+	t.Run("pages do not overlap on ties", func(t *testing.T) {
+		sort := models.ParseTodoSort("priority")
+
+		var pages []string
+		for offset := 0; offset < 5; offset += 2 {
+			results, err := repo.FindAll(ctx, models.TodoFilter{}, sort, 2, offset)
+			require.NoError(t, err)
+			pages = append(pages, titles(results)...)
+		}
+		assert.Equal(t, []string{"Call mom", "walk dog", "Buy milk", "File taxes", "Pay rent"}, pages)
+	})
+}
+
+// testSearch - words are chosen so that Mongo stemming and stop words don't change the results
+func testSearch(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+
+	for _, value := range []*models.Todo{
+		{Title: "Buy milk", Description: "Milk from the farm shop"},
+		{Title: "Farm visit", Description: "Visit the farm and buy eggs", Status: models.StatusDone},
+		{Title: "Call mom", Description: "Ask about the milk recipe"},
+		{Title: "Pay rent", Description: "Bank transfer"},
+		{Title: "Read C++ (book)", Description: "Chapter one"},
+	} {
+		_, err := repo.Store(ctx, value)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.TodoFilter
+		expected []string
+	}{
+		{name: "word in title or description", filter: models.TodoFilter{Search: "milk"}, expected: []string{"Buy milk", "Call mom"}},
+		{name: "any word", filter: models.TodoFilter{Search: "rent eggs"}, expected: []string{"Farm visit", "Pay rent"}},
+		{name: "case-insensitive", filter: models.TodoFilter{Search: "BANK"}, expected: []string{"Pay rent"}},
+		{name: "negation", filter: models.TodoFilter{Search: "milk -recipe"}, expected: []string{"Buy milk"}},
+		{name: "phrase", filter: models.TodoFilter{Search: `"farm shop"`}, expected: []string{"Buy milk"}},
+		{name: "phrase is required", filter: models.TodoFilter{Search: `"buy eggs" milk`}, expected: []string{"Farm visit"}},
+		{name: "negated phrase", filter: models.TodoFilter{Search: `farm -"farm shop"`}, expected: []string{"Farm visit"}},
+		{name: "only negations", filter: models.TodoFilter{Search: "-milk"}, expected: nil},
+		{name: "no match", filter: models.TodoFilter{Search: "holiday"}, expected: nil},
+		{name: "with other filters", filter: models.TodoFilter{Search: "farm", Status: models.StatusTodo}, expected: []string{"Buy milk"}},
+		{name: "literal keyword", filter: models.TodoFilter{Keyword: "c++ ("}, expected: []string{"Read C++ (book)"}},
+		{name: "keyword is not a regex", filter: models.TodoFilter{Keyword: "b.y"}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := repo.FindAll(ctx, tt.filter, nil, 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, titles(results))
+
+			total, err := repo.CountFindAll(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.expected), total)
+		})
+	}
+
+	t.Run("ranks title matches first", func(t *testing.T) {
+		results, err := repo.FindAll(ctx, models.TodoFilter{Search: "milk"}, models.ParseTodoSort("-score"), 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Buy milk", "Call mom"}, titles(results))
+		assert.Greater(t, results[0].Score, results[1].Score)
+		assert.Greater(t, results[1].Score, float64(0))
+	})
+
+	t.Run("follows the writes", func(t *testing.T) {
+		todo, err := repo.Store(ctx, &models.Todo{Title: "Plan holiday", Description: "Book the flights"})
+		require.NoError(t, err)
+
+		todo.Title = "Plan trip"
+		_, err = repo.Update(ctx, todo.ID.Hex(), todo, 0)
+		require.NoError(t, err)
+
+		results, err := repo.FindAll(ctx, models.TodoFilter{Search: "holiday"}, nil, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		results, err = repo.FindAll(ctx, models.TodoFilter{Search: "trip flights"}, nil, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Plan trip"}, titles(results))
+
+		require.NoError(t, repo.Delete(ctx, todo.ID.Hex(), 0))
+		results, err = repo.FindAll(ctx, models.TodoFilter{Search: "trip"}, nil, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("operators are words", func(t *testing.T) {
+		for _, search := range []string{"(", `mi"lk OR`, "mil*", "NOT"} {
+			_, err := repo.FindAll(ctx, models.TodoFilter{Search: search}, nil, 0, 0)
+			assert.NoError(t, err, search)
+		}
+	})
+
+	t.Run("keyset on the score", func(t *testing.T) {
+		filter := models.TodoFilter{Search: "milk farm eggs"}
+		sort := models.ParseTodoSort("-score")
+
+		all, err := repo.FindAll(ctx, filter, sort, 0, 0)
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+
+		var pages []string
+		page, err := repo.FindAll(ctx, filter, sort, 1, 0)
+		require.NoError(t, err)
+		for len(page) > 0 {
+			pages = append(pages, titles(page)...)
+
+			after := filter
+			after.After = &models.Keyset{Sort: sort.WithID(), Key: page[0]}
+			page, err = repo.FindAll(ctx, after, sort, 1, 0)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, titles(all), pages)
+
+		after := filter
+		after.After = &models.Keyset{Sort: sort.WithID(), Key: all[0]}
+		total, err := repo.CountFindAll(ctx, after)
+		require.NoError(t, err)
+		assert.Equal(t, 2, total)
+	})
+}
+
+func testDelete(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todos := store(t, repo, "Buy milk", "Walk dog", "Call mom")
+
+	require.NoError(t, repo.Delete(ctx, todos[1].ID.Hex(), 0))
+
+	_, err := repo.FindById(ctx, todos[1].ID.Hex())
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+
+	results, err := repo.FindAll(ctx, models.TodoFilter{}, nil, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Buy milk", "Call mom"}, titles(results))
+
+	// Deleting twice, or deleting an id that never existed, is not found
+	err = repo.Delete(ctx, todos[1].ID.Hex(), 0)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+
+	err = repo.Delete(ctx, primitive.NewObjectID().Hex(), 0)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+}
+
+func testTrash(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todos := store(t, repo, "Buy milk", "Walk dog", "Call mom")
+	id := todos[1].ID.Hex()
+
+	before := time.Now()
+	require.NoError(t, repo.Delete(ctx, id, 1))
+	require.NoError(t, repo.Delete(ctx, todos[2].ID.Hex(), 0))
+
+	trash, err := repo.FindAll(ctx, models.TodoFilter{Deleted: true}, models.TodoSort{{Field: models.SortDeletedAt}}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Walk dog", "Call mom"}, titles(trash))
+	require.NotNil(t, trash[0].DeletedAt)
+	assert.False(t, trash[0].DeletedAt.Before(before.Truncate(timestampPrecision)))
+	assert.Equal(t, int64(2), trash[0].Version, "deleting is a write")
+
+	total, err := repo.CountFindAll(ctx, models.TodoFilter{Deleted: true, Keyword: "dog"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	// Todos in the trash can't be written
+	_, err = repo.Update(ctx, id, &models.Todo{Title: "Walk cat"}, 0)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+	_, err = repo.Patch(ctx, id, &models.Todo{Title: "Walk cat"}, []string{models.FieldTitle}, 2)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+
+	restored, err := repo.Restore(ctx, id)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, "Walk dog", restored.Title)
+	assert.Equal(t, int64(3), restored.Version)
+
+	found, err := repo.FindById(ctx, id)
+	require.NoError(t, err)
+	assertSameTodo(t, restored, found)
+
+	// Only todos in the trash can be restored
+	_, err = repo.Restore(ctx, id)
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+	_, err = repo.Restore(ctx, primitive.NewObjectID().Hex())
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+
+	purged, err := repo.Purge(ctx, before.Truncate(timestampPrecision))
+	require.NoError(t, err)
+	assert.Equal(t, 0, purged, "todos deleted after before are kept")
+
+	purged, err = repo.Purge(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged, "only todos in the trash are purged")
+
+	// The history outlives the purged todo, it is the audit trail
+	revisions, err := repo.FindRevisions(ctx, todos[2].ID.Hex())
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	trash, err = repo.FindAll(ctx, models.TodoFilter{Deleted: true}, nil, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, trash)
+
+	results, err := repo.FindAll(ctx, models.TodoFilter{}, nil, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Buy milk", "Walk dog"}, titles(results))
+}
+
+func testRevisions(t *testing.T, repo repository.TodoRepository) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	ctx := trace.ContextWithSpanContext(
+		models.WithActor(context.Background(), "alice"),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}}),
+	)
+
+	todo, err := repo.Store(ctx, &models.Todo{Title: "Buy milk", Description: "At the farm shop", Tags: []string{"errand"}})
+	require.NoError(t, err)
+	id := todo.ID.Hex()
+
+	_, err = repo.Update(context.Background(), id, &models.Todo{Title: "Buy oat milk", Description: "At the farm shop", Tags: []string{"errand"}}, 0)
+	require.NoError(t, err)
+	_, err = repo.Patch(ctx, id, &models.Todo{Priority: 3}, []string{models.FieldPriority}, 0)
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, id, 0))
+	restored, err := repo.Restore(ctx, id)
+	require.NoError(t, err)
+
+	// Failed writes record nothing
+	_, err = repo.Update(ctx, id, &models.Todo{Title: "stale"}, 1)
+	require.Error(t, err)
+
+	revisions, err := repo.FindRevisions(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 5)
+
+	var actions []models.RevisionAction
+	for i, revision := range revisions {
+		assert.Equal(t, todo.ID, revision.TodoID)
+		assert.Equal(t, int64(i+1), revision.Rev, "revisions are numbered by version")
+		actions = append(actions, revision.Action)
+	}
+	assert.Equal(t, []models.RevisionAction{
+		models.ActionCreate, models.ActionUpdate, models.ActionPatch, models.ActionDelete, models.ActionRestore,
+	}, actions)
+
+	assert.Equal(t, "alice", revisions[0].Actor)
+	assert.Equal(t, traceID.String(), revisions[0].TraceID)
+	assert.WithinDuration(t, todo.CreatedAt, revisions[0].CreatedAt, timestampPrecision)
+	var fields []string
+	for _, change := range revisions[0].Changes {
+		assert.Nil(t, change.From, "a new todo has no previous value")
+		fields = append(fields, change.Field)
+	}
+	assert.Equal(t, []string{models.FieldTitle, models.FieldDescription, models.FieldStatus, models.FieldTags}, fields)
+
+	assert.Equal(t, models.AnonymousActor, revisions[1].Actor)
+	assert.Empty(t, revisions[1].TraceID)
+	require.Len(t, revisions[1].Changes, 1)
+	assert.Equal(t, models.FieldChange{Field: models.FieldTitle, From: "Buy milk", To: "Buy oat milk"}, revisions[1].Changes[0])
+
+	require.Len(t, revisions[2].Changes, 1)
+	assert.Equal(t, models.FieldPriority, revisions[2].Changes[0].Field)
+	assert.EqualValues(t, 0, revisions[2].Changes[0].From)
+	assert.EqualValues(t, 3, revisions[2].Changes[0].To)
+
+	require.Len(t, revisions[3].Changes, 1)
+	assert.Equal(t, models.FieldDeletedAt, revisions[3].Changes[0].Field)
+	assert.Nil(t, revisions[3].Changes[0].From)
+	assert.NotNil(t, revisions[3].Changes[0].To)
+	assert.Equal(t, models.FieldDeletedAt, revisions[4].Changes[0].Field)
+	assert.Nil(t, revisions[4].Changes[0].To)
+
+	// The revisions undo the writes back to the first one
+	first, err := models.Revert(restored, revisions[1:], 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Buy milk", first.Title)
+	assert.Equal(t, 0, first.Priority)
+	assert.Equal(t, []string{"errand"}, first.Tags)
+
+	revisions, err = repo.FindRevisions(ctx, primitive.NewObjectID().Hex())
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func testBulkWrite(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todos := store(t, repo, "Buy milk", "Walk dog", "Call mom")
+
+	results, err := repo.BulkWrite(ctx, []*models.TodoWrite{
+		{Op: models.BulkCreate, Value: &models.Todo{Title: "Feed cat", Description: "Twice", Tags: []string{"home"}}},
+		{Op: models.BulkUpdate, ID: todos[0].ID.Hex(), Value: &models.Todo{Title: "Buy oat milk", Description: "At the farm shop"}, Version: 1},
+		{Op: models.BulkDelete, ID: todos[1].ID.Hex()},
+		{Op: models.BulkUpdate, ID: todos[2].ID.Hex(), Value: &models.Todo{Title: "Call dad"}, Version: 5},
+		{Op: models.BulkDelete, ID: primitive.NewObjectID().Hex()},
+		{Op: models.BulkUpdate, ID: "abc", Value: &models.Todo{Title: "Nothing"}},
+	}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "Feed cat", results[0].Todo.Title)
+	assert.Equal(t, models.StatusTodo, results[0].Todo.Status)
+	assert.Equal(t, []string{"home"}, results[0].Todo.Tags)
+	assert.Equal(t, int64(1), results[0].Todo.Version)
+	assert.Equal(t, results[0].Todo.ID.Hex(), results[0].ID)
+
+	require.NoError(t, results[1].Err)
+	assert.Equal(t, "Buy oat milk", results[1].Todo.Title)
+	assert.Equal(t, int64(2), results[1].Todo.Version)
+
+	require.NoError(t, results[2].Err)
+	assert.Nil(t, results[2].Todo, "deleting answers no todo")
+	assert.Equal(t, todos[1].ID.Hex(), results[2].ID)
+
+	// A failed write doesn't stop the others
+	assert.True(t, errors.Is(results[3].Err, models.ErrPreconditionFailed), "expected ErrPreconditionFailed, got %v", results[3].Err)
+	assert.True(t, errors.Is(results[4].Err, models.ErrNotFound), "expected ErrNotFound, got %v", results[4].Err)
+	assert.True(t, errors.Is(results[5].Err, models.ErrInvalidID), "expected ErrInvalidID, got %v", results[5].Err)
+	for _, result := range results[3:] {
+		assert.Nil(t, result.Todo)
+	}
+
+	created, err := repo.FindById(ctx, results[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Feed cat", created.Title)
+	_, err = repo.FindById(ctx, todos[1].ID.Hex())
+	assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+	unchanged, err := repo.FindById(ctx, todos[2].ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, "Call mom", unchanged.Title)
+
+	// Bulk writes are recorded like the single ones
+	revisions, err := repo.FindRevisions(ctx, todos[0].ID.Hex())
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, models.ActionUpdate, revisions[1].Action)
+	revisions, err = repo.FindRevisions(ctx, todos[1].ID.Hex())
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, models.ActionDelete, revisions[1].Action)
+	revisions, err = repo.FindRevisions(ctx, results[0].ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, models.ActionCreate, revisions[0].Action)
+
+	// An atomic bulk writes nothing when a write fails
+	results, err = repo.BulkWrite(ctx, []*models.TodoWrite{
+		{Op: models.BulkCreate, Value: &models.Todo{Title: "Water plants", Description: "All of them"}},
+		{Op: models.BulkUpdate, ID: todos[2].ID.Hex(), Value: &models.Todo{Title: "Call dad", Description: "Sunday"}, Version: 1},
+		{Op: models.BulkDelete, ID: todos[0].ID.Hex(), Version: 1},
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results[:2] {
+		assert.True(t, errors.Is(result.Err, models.ErrAborted), "expected ErrAborted, got %v", result.Err)
+		assert.Nil(t, result.Todo)
+	}
+	assert.Empty(t, results[0].ID, "the created todo was rolled back")
+	assert.True(t, errors.Is(results[2].Err, models.ErrPreconditionFailed), "expected ErrPreconditionFailed, got %v", results[2].Err)
+
+	total, err := repo.CountFindAll(ctx, models.TodoFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	unchanged, err = repo.FindById(ctx, todos[2].ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, "Call mom", unchanged.Title)
+	assert.Equal(t, int64(1), unchanged.Version)
+	revisions, err = repo.FindRevisions(ctx, todos[2].ID.Hex())
+	require.NoError(t, err)
+	assert.Len(t, revisions, 1, "the revisions are rolled back too")
+
+	// An atomic bulk spans its batches
+	var writes []*models.TodoWrite
+	for i := 0; i <= models.BulkBatchSize; i++ {
+		writes = append(writes, &models.TodoWrite{Op: models.BulkCreate, Value: &models.Todo{Title: fmt.Sprintf("Todo %d", i)}})
+	}
+	writes = append(writes, &models.TodoWrite{Op: models.BulkDelete, ID: todos[2].ID.Hex(), Version: 1})
+	results, err = repo.BulkWrite(ctx, writes, true)
+	require.NoError(t, err)
+	require.Len(t, results, len(writes))
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+	}
+
+	total, err = repo.CountFindAll(ctx, models.TodoFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2+models.BulkBatchSize+1, total)
+}
+
+// eventTypes - types of events, in order
+func eventTypes(events []*models.Event) []models.EventType {
+	var result []models.EventType
+	for _, event := range events {
+		result = append(result, event.Type)
+	}
+	return result
+}
+
+func testOutbox(t *testing.T, repo repository.TodoRepository) {
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(models.WithActor(context.Background(), "alice"), spanContext)
+
+	todo, err := repo.Store(ctx, &models.Todo{Title: "Buy milk", Description: "At the farm shop"})
+	require.NoError(t, err)
+	id := todo.ID.Hex()
+
+	_, err = repo.Update(ctx, id, &models.Todo{Title: "Buy milk", Description: "At the farm shop", Status: models.StatusDone}, 0)
+	require.NoError(t, err)
+	_, err = repo.Patch(ctx, id, &models.Todo{Title: "Buy oat milk"}, []string{models.FieldTitle}, 0)
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, id, 0))
+	_, err = repo.Restore(ctx, id)
+	require.NoError(t, err)
+
+	// Failed writes store no event
+	_, err = repo.Update(ctx, id, &models.Todo{Title: "stale"}, 1)
+	require.Error(t, err)
+
+	now := time.Now()
+	until := now.Add(time.Minute)
+	events, err := repo.ClaimEvents(ctx, now, until, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+	assert.Equal(t, []models.EventType{
+		models.EventTodoCreated, models.EventTodoCompleted, models.EventTodoUpdated, models.EventTodoDeleted, models.EventTodoUpdated,
+	}, eventTypes(events))
+
+	for i, event := range events {
+		assert.False(t, event.ID.IsZero())
+		assert.Equal(t, todo.ID, event.TodoID)
+		assert.Equal(t, "alice", event.Actor)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", event.TraceParent, "event %d", i)
+	}
+	require.NotNil(t, events[0].Todo)
+	assert.Equal(t, "Buy milk", events[0].Todo.Title)
+	assert.WithinDuration(t, todo.CreatedAt, events[0].OccurredAt, timestampPrecision)
+	require.NotNil(t, events[2].Todo)
+	assert.Equal(t, "Buy oat milk", events[2].Todo.Title)
+	assert.Equal(t, int64(3), events[2].Todo.Version)
+	assert.Nil(t, events[3].Todo, "a deleted todo has no event todo")
+
+	// Claimed events are skipped until the claim expires
+	claimed, err := repo.ClaimEvents(ctx, now, until, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	claimed, err = repo.ClaimEvents(ctx, until, until.Add(time.Minute), 2)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2, "claims stop at the limit")
+	assert.Equal(t, events[0].ID, claimed[0].ID)
+	assert.Equal(t, events[1].ID, claimed[1].ID)
+
+	// Deleted events are never claimed again
+	require.NoError(t, repo.DeleteEvents(ctx, []primitive.ObjectID{events[0].ID, events[1].ID, events[2].ID}))
+	require.NoError(t, repo.DeleteEvents(ctx, nil))
+	claimed, err = repo.ClaimEvents(ctx, until.Add(time.Hour), until.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, events[3].ID, claimed[0].ID)
+	assert.Equal(t, events[4].ID, claimed[1].ID)
+	require.NoError(t, repo.DeleteEvents(ctx, []primitive.ObjectID{claimed[0].ID, claimed[1].ID}))
+
+	// Bulk writes store the events of the writes that succeeded, an atomic bulk that fails none
+	results, err := repo.BulkWrite(ctx, []*models.TodoWrite{
+		{Op: models.BulkCreate, Value: &models.Todo{Title: "Walk dog"}},
+		{Op: models.BulkDelete, ID: id, Version: 1},
+	}, true)
+	require.NoError(t, err)
+	assert.Error(t, results[0].Err)
+
+	results, err = repo.BulkWrite(ctx, []*models.TodoWrite{
+		{Op: models.BulkCreate, Value: &models.Todo{Title: "Walk dog"}},
+		{Op: models.BulkDelete, ID: id, Version: 1},
+		{Op: models.BulkDelete, ID: id},
+	}, false)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.Error(t, results[1].Err)
+	require.NoError(t, results[2].Err)
+
+	events, err = repo.ClaimEvents(ctx, time.Now(), until, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.EventType{models.EventTodoCreated, models.EventTodoDeleted}, eventTypes(events))
+	require.Len(t, events, 2)
+	assert.Equal(t, results[0].ID, events[0].TodoID.Hex())
+	assert.Equal(t, id, events[1].TodoID.Hex())
+	assert.Equal(t, "alice", events[0].Actor)
+}
+
+func testFindAllIDs(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	todos := store(t, repo, "Buy milk", "Walk dog", "Call mom")
+	require.NoError(t, repo.Delete(ctx, todos[2].ID.Hex(), 0))
+
+	results, err := repo.FindAll(ctx, models.TodoFilter{
+		IDs: []primitive.ObjectID{todos[0].ID, todos[2].ID, primitive.NewObjectID()},
+	}, nil, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Buy milk"}, titles(results), "the trash is left out")
+}
+
+func testInvalidID(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	store(t, repo, "Buy milk")
+
+	for _, id := range []string{"", "abc", "not-an-object-id-at-all!"} {
+		t.Run(fmt.Sprintf("id %q", id), func(t *testing.T) {
+			_, err := repo.FindById(ctx, id)
+			assert.True(t, errors.Is(err, models.ErrInvalidID), "FindById: expected ErrInvalidID, got %v", err)
+
+			_, err = repo.Update(ctx, id, &models.Todo{Title: "title"}, 0)
+			assert.True(t, errors.Is(err, models.ErrInvalidID), "Update: expected ErrInvalidID, got %v", err)
+
+			_, err = repo.Patch(ctx, id, &models.Todo{Title: "title"}, []string{models.FieldTitle}, 0)
+			assert.True(t, errors.Is(err, models.ErrInvalidID), "Patch: expected ErrInvalidID, got %v", err)
+
+			err = repo.Delete(ctx, id, 0)
+			assert.True(t, errors.Is(err, models.ErrInvalidID), "Delete: expected ErrInvalidID, got %v", err)
+
+			_, err = repo.Restore(ctx, id)
+			assert.True(t, errors.Is(err, models.ErrInvalidID), "Restore: expected ErrInvalidID, got %v", err)
+
+			_, err = repo.FindRevisions(ctx, id)
+			assert.True(t, errors.Is(err, models.ErrInvalidID), "FindRevisions: expected ErrInvalidID, got %v", err)
+		})
+	}
+}
+
+func testConcurrentWrites(t *testing.T, repo repository.TodoRepository) {
+	ctx := context.Background()
+	const writers = 20
+
+	var wg sync.WaitGroup
+	ids := make(chan primitive.ObjectID, writers)
+	errs := make(chan error, writers*3)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			todo, err := repo.Store(ctx, &models.Todo{Title: fmt.Sprintf("concurrent %d", i)})
+			if err != nil {
+				errs <- err
+				return
+			}
+			ids <- todo.ID
+
+			_, err = repo.Update(ctx, todo.ID.Hex(), &models.Todo{Title: fmt.Sprintf("concurrent %d updated", i)}, 0)
+			if err != nil {
+				errs <- err
+			}
+
+			if _, err := repo.FindAll(ctx, models.TodoFilter{Keyword: "concurrent"}, nil, 0, 0); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(ids)
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	for id := range ids {
+		assert.False(t, seen[id], "duplicate id %s", id.Hex())
+		seen[id] = true
+	}
+	assert.Len(t, seen, writers)
+
+	total, err := repo.CountFindAll(ctx, models.TodoFilter{Keyword: "updated"})
+	require.NoError(t, err)
+	assert.Equal(t, writers, total)
+
+	// Concurrent deletes of the same id succeed exactly once
+	var deleted sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for id := range seen {
+		for i := 0; i < 2; i++ {
+			deleted.Add(1)
+			go func(id primitive.ObjectID) {
+				defer deleted.Done()
+
+				err := repo.Delete(ctx, id.Hex(), 0)
+				if err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+					return
+				}
+				assert.True(t, errors.Is(err, models.ErrNotFound), "expected ErrNotFound, got %v", err)
+			}(id)
+		}
+	}
+	deleted.Wait()
+
+	assert.Equal(t, writers, succeeded)
+
+	total, err = repo.CountFindAll(ctx, models.TodoFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
@@ -0,0 +1,304 @@
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookFactory - create an empty WebhookRepository for a single subtest
+type WebhookFactory func(t *testing.T) repository.WebhookRepository
+
+// RunWebhook - run the WebhookRepository contract against repositories created by newRepo
+func RunWebhook(t *testing.T, newRepo WebhookFactory) {
+	t.Run("Store", func(t *testing.T) { testWebhookStore(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testWebhookUpdate(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testWebhookDelete(t, newRepo(t)) })
+	t.Run("deliveries", func(t *testing.T) { testDeliveries(t, newRepo(t)) })
+	t.Run("ClaimDeliveries", func(t *testing.T) { testClaimDeliveries(t, newRepo(t)) })
+	t.Run("concurrent claims", func(t *testing.T) { testConcurrentClaims(t, newRepo(t)) })
+}
+
+func storeWebhook(t *testing.T, repo repository.WebhookRepository, url string, events ...models.EventType) *models.Webhook {
+	t.Helper()
+
+	if events == nil {
+		events = []models.EventType{}
+	}
+	webhook, err := repo.Store(context.Background(), &models.Webhook{URL: url, Events: events, Secret: "secret of " + url})
+	require.NoError(t, err)
+
+	return webhook
+}
+
+// storeDeliveries - store a pending delivery to webhook per event type, due at due
+func storeDeliveries(t *testing.T, repo repository.WebhookRepository, webhook *models.Webhook, due time.Time, eventTypes ...models.EventType) []*models.Delivery {
+	t.Helper()
+
+	var deliveries []*models.Delivery
+	for _, eventType := range eventTypes {
+		next := due
+		deliveries = append(deliveries, &models.Delivery{
+			WebhookID:     webhook.ID,
+			EventID:       primitive.NewObjectID(),
+			EventType:     eventType,
+			Payload:       json.RawMessage(`{"type":"` + string(eventType) + `"}`),
+			TraceParent:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			Status:        models.DeliveryPending,
+			Attempts:      []models.DeliveryAttempt{},
+			NextAttemptAt: &next,
+			CreatedAt:     utils.GetTimeNow(),
+			UpdatedAt:     utils.GetTimeNow(),
+		})
+	}
+	require.NoError(t, repo.StoreDeliveries(context.Background(), deliveries))
+
+	return deliveries
+}
+
+func deliveryTypes(deliveries []*models.Delivery) []models.EventType {
+	var results []models.EventType
+	for _, delivery := range deliveries {
+		results = append(results, delivery.EventType)
+	}
+
+	return results
+}
+
+func testWebhookStore(t *testing.T, repo repository.WebhookRepository) {
+	ctx := context.Background()
+
+	first := storeWebhook(t, repo, "http://localhost/a", models.EventTodoCreated, models.EventTodoDeleted)
+	second := storeWebhook(t, repo, "http://localhost/b")
+	assert.False(t, first.ID.IsZero())
+	assert.False(t, first.CreatedAt.IsZero())
+
+	found, err := repo.FindById(ctx, first.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost/a", found.URL)
+	assert.Equal(t, []models.EventType{models.EventTodoCreated, models.EventTodoDeleted}, found.Events)
+	assert.Equal(t, "secret of http://localhost/a", found.Secret)
+	assert.WithinDuration(t, first.CreatedAt, found.CreatedAt, timestampPrecision)
+
+	all, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, first.ID, all[0].ID)
+	assert.Equal(t, second.ID, all[1].ID)
+	assert.Empty(t, all[1].Events)
+
+	_, err = repo.FindById(ctx, primitive.NewObjectID().Hex())
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+	_, err = repo.FindById(ctx, "abc")
+	assert.True(t, errors.Is(err, models.ErrInvalidID))
+}
+
+func testWebhookUpdate(t *testing.T, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	webhook := storeWebhook(t, repo, "http://localhost/a")
+
+	updated, err := repo.Update(ctx, webhook.ID.Hex(), &models.Webhook{
+		URL:    "https://localhost/b",
+		Events: []models.EventType{models.EventTodoCompleted},
+		Secret: "another secret",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, webhook.ID, updated.ID)
+	assert.Equal(t, "https://localhost/b", updated.URL)
+	assert.Equal(t, []models.EventType{models.EventTodoCompleted}, updated.Events)
+	assert.False(t, updated.UpdatedAt.Before(webhook.UpdatedAt))
+
+	found, err := repo.FindById(ctx, webhook.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, "https://localhost/b", found.URL)
+	assert.Equal(t, "another secret", found.Secret)
+
+	_, err = repo.Update(ctx, primitive.NewObjectID().Hex(), &models.Webhook{URL: "http://localhost"})
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+	_, err = repo.Update(ctx, "abc", &models.Webhook{URL: "http://localhost"})
+	assert.True(t, errors.Is(err, models.ErrInvalidID))
+}
+
+func testWebhookDelete(t *testing.T, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	deleted := storeWebhook(t, repo, "http://localhost/a")
+	kept := storeWebhook(t, repo, "http://localhost/b")
+	storeDeliveries(t, repo, deleted, utils.GetTimeNow(), models.EventTodoCreated)
+	storeDeliveries(t, repo, kept, utils.GetTimeNow(), models.EventTodoUpdated)
+
+	require.NoError(t, repo.Delete(ctx, deleted.ID.Hex()))
+
+	_, err := repo.FindById(ctx, deleted.ID.Hex())
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+
+	// The deliveries go with the webhook
+	deliveries, err := repo.FindDeliveries(ctx, models.DeliveryFilter{}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []models.EventType{models.EventTodoUpdated}, deliveryTypes(deliveries))
+
+	assert.True(t, errors.Is(repo.Delete(ctx, deleted.ID.Hex()), models.ErrNotFound))
+	assert.True(t, errors.Is(repo.Delete(ctx, "abc"), models.ErrInvalidID))
+}
+
+func testDeliveries(t *testing.T, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	first := storeWebhook(t, repo, "http://localhost/a")
+	second := storeWebhook(t, repo, "http://localhost/b")
+
+	stored := storeDeliveries(t, repo, first, utils.GetTimeNow(), models.EventTodoCreated, models.EventTodoUpdated, models.EventTodoCompleted)
+	storeDeliveries(t, repo, second, utils.GetTimeNow(), models.EventTodoDeleted)
+	assert.False(t, stored[0].ID.IsZero())
+
+	// Newest first
+	deliveries, err := repo.FindDeliveries(ctx, models.DeliveryFilter{WebhookID: first.ID}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []models.EventType{models.EventTodoCompleted, models.EventTodoUpdated, models.EventTodoCreated}, deliveryTypes(deliveries))
+
+	deliveries, err = repo.FindDeliveries(ctx, models.DeliveryFilter{WebhookID: first.ID}, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.EventType{models.EventTodoUpdated}, deliveryTypes(deliveries))
+
+	deliveries, err = repo.FindDeliveries(ctx, models.DeliveryFilter{}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 4)
+
+	found, err := repo.FindDeliveryById(ctx, stored[0].ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, found.WebhookID)
+	assert.Equal(t, stored[0].EventID, found.EventID)
+	assert.JSONEq(t, `{"type":"todo.created"}`, string(found.Payload))
+	assert.Equal(t, stored[0].TraceParent, found.TraceParent)
+	assert.Equal(t, models.DeliveryPending, found.Status)
+	assert.Empty(t, found.Attempts)
+	require.NotNil(t, found.NextAttemptAt)
+
+	// A failed attempt, then the delivery is dead
+	claim := found.NextAttemptAt
+	at := utils.GetTimeNow()
+	found.Status = models.DeliveryDead
+	found.Attempts = append(found.Attempts, models.DeliveryAttempt{At: at, StatusCode: http.StatusBadGateway, Error: "502 Bad Gateway", DurationMs: 12.5})
+	found.NextAttemptAt = nil
+	found.UpdatedAt = at
+	require.NoError(t, repo.UpdateDelivery(ctx, found, claim))
+
+	found, err = repo.FindDeliveryById(ctx, stored[0].ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryDead, found.Status)
+	require.Len(t, found.Attempts, 1)
+	assert.Equal(t, http.StatusBadGateway, found.Attempts[0].StatusCode)
+	assert.Equal(t, "502 Bad Gateway", found.Attempts[0].Error)
+	assert.Equal(t, 12.5, found.Attempts[0].DurationMs)
+	assert.WithinDuration(t, at, found.Attempts[0].At, timestampPrecision)
+	assert.Nil(t, found.NextAttemptAt)
+	assert.JSONEq(t, `{"type":"todo.created"}`, string(found.Payload))
+
+	deliveries, err = repo.FindDeliveries(ctx, models.DeliveryFilter{Status: models.DeliveryDead}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []models.EventType{models.EventTodoCreated}, deliveryTypes(deliveries))
+
+	_, err = repo.FindDeliveryById(ctx, primitive.NewObjectID().Hex())
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+	_, err = repo.FindDeliveryById(ctx, "abc")
+	assert.True(t, errors.Is(err, models.ErrInvalidID))
+	assert.True(t, errors.Is(repo.UpdateDelivery(ctx, &models.Delivery{ID: primitive.NewObjectID()}, nil), models.ErrNotFound))
+}
+
+func testClaimDeliveries(t *testing.T, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	webhook := storeWebhook(t, repo, "http://localhost/a")
+	now := utils.GetTimeNow()
+
+	storeDeliveries(t, repo, webhook, now.Add(-time.Minute), models.EventTodoUpdated)
+	storeDeliveries(t, repo, webhook, now.Add(-time.Hour), models.EventTodoCreated)
+	storeDeliveries(t, repo, webhook, now.Add(time.Hour), models.EventTodoDeleted)
+
+	// Due first, claimed first
+	claimed, err := repo.ClaimDeliveries(ctx, now, now.Add(time.Minute), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.EventType{models.EventTodoCreated}, deliveryTypes(claimed))
+	require.NotNil(t, claimed[0].NextAttemptAt)
+	assert.WithinDuration(t, now.Add(time.Minute), *claimed[0].NextAttemptAt, timestampPrecision)
+	expired := claimed[0]
+
+	claimed, err = repo.ClaimDeliveries(ctx, now, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.EventType{models.EventTodoUpdated}, deliveryTypes(claimed))
+
+	claimed, err = repo.ClaimDeliveries(ctx, now, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	// Claims expire, e.g. when the worker sending them died
+	claimed, err = repo.ClaimDeliveries(ctx, now.Add(2*time.Minute), now.Add(3*time.Minute), 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.EventType{models.EventTodoCreated, models.EventTodoUpdated}, deliveryTypes(claimed))
+
+	// The worker whose claim expired doesn't overwrite the new claim
+	update := *expired
+	update.Status = models.DeliverySucceeded
+	update.NextAttemptAt = nil
+	err = repo.UpdateDelivery(ctx, &update, expired.NextAttemptAt)
+	assert.True(t, errors.Is(err, models.ErrConflict), err)
+
+	// Only pending deliveries are claimed
+	claim := claimed[0].NextAttemptAt
+	claimed[0].Status = models.DeliverySucceeded
+	claimed[0].NextAttemptAt = nil
+	require.NoError(t, repo.UpdateDelivery(ctx, claimed[0], claim))
+
+	claimed, err = repo.ClaimDeliveries(ctx, now.Add(2*time.Hour), now.Add(3*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+}
+
+func testConcurrentClaims(t *testing.T, repo repository.WebhookRepository) {
+	const (
+		workers    = 5
+		deliveries = 20
+	)
+
+	webhook := storeWebhook(t, repo, "http://localhost/a")
+	now := utils.GetTimeNow()
+	for i := 0; i < deliveries; i++ {
+		storeDeliveries(t, repo, webhook, now.Add(-time.Minute), models.EventTodoCreated)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed = map[primitive.ObjectID]int{}
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			results, err := repo.ClaimDeliveries(context.Background(), now, now.Add(time.Minute), deliveries)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, delivery := range results {
+				claimed[delivery.ID]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, deliveries)
+	for id, count := range claimed {
+		assert.Equal(t, 1, count, "delivery %s claimed %d times", id.Hex(), count)
+	}
+}
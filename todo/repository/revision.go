@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"go-distributed-tracing/todo/models"
+)
+
+// newRevision - revision of the write that changed todo from before to after, before is nil when created.
+// The actor and the trace come from ctx.
+func newRevision(ctx context.Context, action models.RevisionAction, before, after *models.Todo) (*models.Revision, error) {
+	changes, err := models.Diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &models.Revision{
+		TodoID:    after.ID,
+		Rev:       after.Version,
+		Action:    action,
+		Changes:   changes,
+		Actor:     models.ActorFromContext(ctx),
+		CreatedAt: after.UpdatedAt,
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		revision.TraceID = spanContext.TraceID().String()
+	}
+
+	return revision, nil
+}
+
+// newTodo - todo stored with the fields of value at timeNow, without an id
+func newTodo(value *models.Todo, timeNow time.Time) *models.Todo {
+	return &models.Todo{
+		Title:       value.Title,
+		Description: value.Description,
+		Status:      status(value),
+		CompletedAt: value.CompletedAt,
+		DueAt:       value.DueAt,
+		Priority:    value.Priority,
+		Tags:        tags(value),
+		CreatedAt:   timeNow,
+		UpdatedAt:   timeNow,
+		Version:     1,
+	}
+}
+
+// updated - copy of todo updated with value at timeNow
+func updated(todo *models.Todo, value *models.Todo, timeNow time.Time) *models.Todo {
+	result := copyTodo(todo)
+	applyUpdate(result, value)
+	result.UpdatedAt = timeNow
+	result.Version++
+
+	return result
+}
+
+// deleted - copy of todo moved to the trash at timeNow
+func deleted(todo *models.Todo, timeNow time.Time) *models.Todo {
+	result := copyTodo(todo)
+	result.DeletedAt = &timeNow
+	result.UpdatedAt = timeNow
+	result.Version++
+
+	return result
+}
+
+// applyUpdate - set the writable fields of todo to the ones of value
+func applyUpdate(todo *models.Todo, value *models.Todo) {
+	updated := copyTodo(value)
+	todo.Title = updated.Title
+	todo.Description = updated.Description
+	todo.Status = updated.Status
+	if todo.Status == "" {
+		todo.Status = models.StatusTodo
+	}
+	todo.CompletedAt = updated.CompletedAt
+	todo.DueAt = updated.DueAt
+	todo.Priority = updated.Priority
+	todo.Tags = updated.Tags
+}
+
+// applyPatch - set the fields of todo to the ones of value
+func applyPatch(todo *models.Todo, value *models.Todo, fields []string) {
+	patched := copyTodo(value)
+	for _, field := range fields {
+		switch field {
+		case models.FieldTitle:
+			todo.Title = patched.Title
+		case models.FieldDescription:
+			todo.Description = patched.Description
+		case models.FieldStatus:
+			todo.Status = patched.Status
+			if todo.Status == "" {
+				todo.Status = models.StatusTodo
+			}
+		case models.FieldCompletedAt:
+			todo.CompletedAt = patched.CompletedAt
+		case models.FieldDueAt:
+			todo.DueAt = patched.DueAt
+		case models.FieldPriority:
+			todo.Priority = patched.Priority
+		case models.FieldTags:
+			todo.Tags = patched.Tags
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"go-distributed-tracing/todo/models"
+)
+
+// sortTodos - order todos by order, then by id like the other repositories
+func sortTodos(todos []*models.Todo, order models.TodoSort) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		return compareTodos(todos[i], todos[j], order) < 0
+	})
+}
+
+// compareTodos - compare a and b in order, the id breaks ties
+func compareTodos(a, b *models.Todo, order models.TodoSort) int {
+	for _, field := range order.WithID() {
+		c := compareField(a, b, field.Field)
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compareField - compare a and b on a sort field, a missing date sorts before any date
+func compareField(a, b *models.Todo, field string) int {
+	switch field {
+	case models.SortTitle:
+		return strings.Compare(a.Title, b.Title)
+	case models.SortStatus:
+		return strings.Compare(string(a.Status), string(b.Status))
+	case models.SortPriority:
+		return a.Priority - b.Priority
+	case models.SortDueAt:
+		return compareTime(a.DueAt, b.DueAt)
+	case models.SortCompletedAt:
+		return compareTime(a.CompletedAt, b.CompletedAt)
+	case models.SortCreatedAt:
+		return compareTime(&a.CreatedAt, &b.CreatedAt)
+	case models.SortUpdatedAt:
+		return compareTime(&a.UpdatedAt, &b.UpdatedAt)
+	case models.SortDeletedAt:
+		return compareTime(a.DeletedAt, b.DeletedAt)
+	case models.SortScore:
+		switch {
+		case a.Score < b.Score:
+			return -1
+		case a.Score > b.Score:
+			return 1
+		}
+	case models.SortID:
+		return strings.Compare(a.ID.Hex(), b.ID.Hex())
+	}
+
+	return 0
+}
+
+func compareTime(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	case a.Before(*b):
+		return -1
+	case a.After(*b):
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	pkg_sqldb "go-distributed-tracing/pkg/sqldb"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+type sqlIdempotencyRepository struct {
+	db *pkg_sqldb.DB
+}
+
+// NewSQLIdempotencyRepository will create an IdempotencyRepository backed by PostgreSQL or SQLite, run MigrateSQL
+// first
+func NewSQLIdempotencyRepository(db *pkg_sqldb.DB) IdempotencyRepository {
+	return &sqlIdempotencyRepository{
+		db: db,
+	}
+}
+
+// Reserve - insert record, the expired records are deleted first as SQL has no TTL
+func (m *sqlIdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	ctx, span := startIdempotencySpan(ctx, "Reserve", m.db.Dialect.System)
+	defer span.End()
+
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		_, err := m.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE expires_at <= ?", utils.GetTimeNow())
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		_, err = m.db.ExecContext(ctx,
+			"INSERT INTO idempotency_key (key, fingerprint, status, header, body, created_at, expires_at, locked_until) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			record.Key, record.Fingerprint, record.Status, string(header), string(record.Body), record.CreatedAt, record.ExpiresAt, record.LockedUntil,
+		)
+		if err == nil {
+			return nil, nil
+		}
+		if !pkg_sqldb.IsUniqueViolation(err) {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		stored, err := m.find(ctx, record.Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		now := utils.GetTimeNow()
+		if stored.Done() || stored.Locked(now) {
+			return stored, nil
+		}
+
+		// The request holding the key is gone, the first retry to get here takes it over
+		res, err := m.db.ExecContext(ctx,
+			"UPDATE idempotency_key SET fingerprint = ?, header = ?, body = ?, created_at = ?, expires_at = ?, locked_until = ? WHERE key = ? AND status = 0 AND locked_until <= ?",
+			record.Fingerprint, string(header), string(record.Body), record.CreatedAt, record.ExpiresAt, record.LockedUntil, record.Key, now,
+		)
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+		if affected, err := res.RowsAffected(); err == nil && affected == 1 {
+			return nil, nil
+		}
+	}
+
+	pkg_tracing.RecordError(span, errReserve)
+	return nil, errReserve
+}
+
+func (m *sqlIdempotencyRepository) find(ctx context.Context, key string) (*models.IdempotencyRecord, error) {
+	record := &models.IdempotencyRecord{Key: key}
+	var header, body string
+	err := m.db.QueryRowContext(ctx,
+		"SELECT fingerprint, status, header, body, created_at, expires_at, locked_until FROM idempotency_key WHERE key = ?",
+		key,
+	).Scan(&record.Fingerprint, &record.Status, &header, &body, &record.CreatedAt, &record.ExpiresAt, &record.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+		return nil, err
+	}
+	record.Body = []byte(body)
+
+	return record, nil
+}
+
+// Complete - set the response of the reserved record
+func (m *sqlIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := startIdempotencySpan(ctx, "Complete", m.db.Dialect.System)
+	defer span.End()
+
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx,
+		"UPDATE idempotency_key SET status = ?, header = ?, body = ? WHERE key = ? AND fingerprint = ? AND status = 0 AND locked_until = ?",
+		record.Status, string(header), string(record.Body), record.Key, record.Fingerprint, record.LockedUntil,
+	)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Release - delete the reserved record while it has no response
+func (m *sqlIdempotencyRepository) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := startIdempotencySpan(ctx, "Release", m.db.Dialect.System)
+	defer span.End()
+
+	_, err := m.db.ExecContext(ctx,
+		"DELETE FROM idempotency_key WHERE key = ? AND status = 0 AND locked_until = ?",
+		record.Key, record.LockedUntil,
+	)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,1464 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	pkg_sqldb "go-distributed-tracing/pkg/sqldb"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+// sqlTodoMigrations - schema of the todo table, append new versions, never edit applied ones
+var sqlTodoMigrations = []pkg_sqldb.Migration{
+	{
+		Version:     1,
+		Description: "create todo table",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				fmt.Sprintf(`CREATE TABLE todo (
+					id CHAR(24) PRIMARY KEY,
+					title TEXT NOT NULL,
+					description TEXT NOT NULL,
+					created_at %[1]s NOT NULL,
+					updated_at %[1]s NOT NULL
+				)`, dialect.Timestamp),
+			}
+		},
+	},
+	{
+		Version:     2,
+		Description: "add todo status",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				"ALTER TABLE todo ADD COLUMN status TEXT NOT NULL DEFAULT 'todo'",
+				"ALTER TABLE todo ADD COLUMN completed_at " + dialect.Timestamp,
+				"CREATE INDEX todo_status_idx ON todo (status)",
+			}
+		},
+	},
+	{
+		Version:     3,
+		Description: "add todo due date, priority and tags",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				"ALTER TABLE todo ADD COLUMN due_at " + dialect.Timestamp,
+				"ALTER TABLE todo ADD COLUMN priority INTEGER NOT NULL DEFAULT 0",
+				"CREATE INDEX todo_due_at_idx ON todo (due_at)",
+				`CREATE TABLE todo_tag (
+					todo_id CHAR(24) NOT NULL,
+					tag TEXT NOT NULL,
+					PRIMARY KEY (todo_id, tag)
+				)`,
+				"CREATE INDEX todo_tag_tag_idx ON todo_tag (tag)",
+			}
+		},
+	},
+	{
+		Version:     4,
+		Description: "add todo version",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				"ALTER TABLE todo ADD COLUMN version INTEGER NOT NULL DEFAULT 1",
+			}
+		},
+	},
+	{
+		Version:     5,
+		Description: "add todo soft delete",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				"ALTER TABLE todo ADD COLUMN deleted_at " + dialect.Timestamp,
+				"CREATE INDEX todo_deleted_at_idx ON todo (deleted_at)",
+			}
+		},
+	},
+	{
+		Version:     6,
+		Description: "create todo revision table",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				fmt.Sprintf(`CREATE TABLE todo_revision (
+					todo_id CHAR(24) NOT NULL,
+					rev INTEGER NOT NULL,
+					action TEXT NOT NULL,
+					changes TEXT NOT NULL,
+					actor TEXT NOT NULL,
+					trace_id TEXT NOT NULL,
+					created_at %s NOT NULL,
+					PRIMARY KEY (todo_id, rev)
+				)`, dialect.Timestamp),
+			}
+		},
+	},
+	{
+		Version:     7,
+		Description: "create idempotency key table",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				fmt.Sprintf(`CREATE TABLE idempotency_key (
+					key TEXT PRIMARY KEY,
+					fingerprint TEXT NOT NULL,
+					status INTEGER NOT NULL,
+					header TEXT NOT NULL,
+					body TEXT NOT NULL,
+					created_at %[1]s NOT NULL,
+					expires_at %[1]s NOT NULL
+				)`, dialect.Timestamp),
+				"CREATE INDEX idempotency_key_expires_at_idx ON idempotency_key (expires_at)",
+			}
+		},
+	},
+	{
+		Version:     8,
+		Description: "create webhook tables",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				fmt.Sprintf(`CREATE TABLE webhook (
+					id CHAR(24) PRIMARY KEY,
+					url TEXT NOT NULL,
+					events TEXT NOT NULL,
+					secret TEXT NOT NULL,
+					created_at %[1]s NOT NULL,
+					updated_at %[1]s NOT NULL
+				)`, dialect.Timestamp),
+				fmt.Sprintf(`CREATE TABLE webhook_delivery (
+					id CHAR(24) PRIMARY KEY,
+					webhook_id CHAR(24) NOT NULL,
+					event_id CHAR(24) NOT NULL,
+					event_type TEXT NOT NULL,
+					payload TEXT NOT NULL,
+					trace_parent TEXT NOT NULL,
+					status TEXT NOT NULL,
+					attempts TEXT NOT NULL,
+					next_attempt_at %[1]s,
+					created_at %[1]s NOT NULL,
+					updated_at %[1]s NOT NULL
+				)`, dialect.Timestamp),
+				"CREATE INDEX webhook_delivery_due_idx ON webhook_delivery (status, next_attempt_at)",
+				"CREATE INDEX webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created_at)",
+			}
+		},
+	},
+	{
+		Version:     9,
+		Description: "create todo outbox table",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				fmt.Sprintf(`CREATE TABLE todo_outbox (
+					id CHAR(24) PRIMARY KEY,
+					type TEXT NOT NULL,
+					todo_id CHAR(24) NOT NULL,
+					todo TEXT,
+					actor TEXT NOT NULL,
+					trace_parent TEXT NOT NULL,
+					occurred_at %[1]s NOT NULL,
+					available_at %[1]s NOT NULL
+				)`, dialect.Timestamp),
+				"CREATE INDEX todo_outbox_available_at_idx ON todo_outbox (available_at, id)",
+			}
+		},
+	},
+	{
+		Version:     10,
+		Description: "add todo full-text search index",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			if dialect.DriverName == pkg_sqldb.Postgres.DriverName {
+				return []string{
+					fmt.Sprintf(`ALTER TABLE todo ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+						setweight(to_tsvector('%[1]s', title), 'A') || setweight(to_tsvector('%[1]s', description), 'B')
+					) STORED`, sqlSearchConfig),
+					"CREATE INDEX todo_search_idx ON todo USING GIN (search_vector)",
+				}
+			}
+
+			// The driver is built without FTS5, FTS4 is. The index reads the words from todo and the
+			// triggers keep it in sync.
+			return []string{
+				`CREATE VIRTUAL TABLE todo_fts USING fts4(content="todo", title, description, tokenize=porter)`,
+				`CREATE TRIGGER todo_fts_insert AFTER INSERT ON todo BEGIN
+					INSERT INTO todo_fts (docid, title, description) VALUES (new.rowid, new.title, new.description);
+				END`,
+				`CREATE TRIGGER todo_fts_before_update BEFORE UPDATE OF title, description ON todo BEGIN
+					DELETE FROM todo_fts WHERE docid = old.rowid;
+				END`,
+				`CREATE TRIGGER todo_fts_after_update AFTER UPDATE OF title, description ON todo BEGIN
+					INSERT INTO todo_fts (docid, title, description) VALUES (new.rowid, new.title, new.description);
+				END`,
+				`CREATE TRIGGER todo_fts_delete BEFORE DELETE ON todo BEGIN
+					DELETE FROM todo_fts WHERE docid = old.rowid;
+				END`,
+				"INSERT INTO todo_fts (todo_fts) VALUES ('rebuild')",
+			}
+		},
+	},
+	{
+		Version:     11,
+		Description: "add idempotency key lock",
+		Statements: func(dialect pkg_sqldb.Dialect) []string {
+			return []string{
+				"ALTER TABLE idempotency_key ADD COLUMN locked_until " + dialect.Timestamp,
+				"UPDATE idempotency_key SET locked_until = created_at",
+			}
+		},
+	},
+}
+
+// sqlSearchConfig - Postgres text search configuration, stemming English words like the Mongo text index
+const sqlSearchConfig = "english"
+
+// sqlTodoColumns - columns scanned by scanTodo, in order
+const sqlTodoColumns = "id, title, description, status, completed_at, due_at, priority, created_at, updated_at, version, deleted_at"
+
+type sqlTodoRepository struct {
+	db *pkg_sqldb.DB
+}
+
+// NewSQLTodoRepository will create a TodoRepository backed by PostgreSQL or SQLite, run MigrateSQL first
+func NewSQLTodoRepository(db *pkg_sqldb.DB) TodoRepository {
+	return &sqlTodoRepository{
+		db: db,
+	}
+}
+
+// MigrateSQL - create or upgrade the todo schema
+func MigrateSQL(ctx context.Context, db *pkg_sqldb.DB) error {
+	return db.Migrate(ctx, sqlTodoMigrations)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTodo - scan the sqlTodoColumns of row, then the extra columns into extra
+func scanTodo(row rowScanner, extra ...interface{}) (*models.Todo, error) {
+	var (
+		id          string
+		completedAt sql.NullTime
+		dueAt       sql.NullTime
+		deletedAt   sql.NullTime
+		todo        models.Todo
+	)
+	err := row.Scan(append([]interface{}{
+		&id, &todo.Title, &todo.Description, &todo.Status, &completedAt, &dueAt, &todo.Priority,
+		&todo.CreatedAt, &todo.UpdatedAt, &todo.Version, &deletedAt,
+	}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if completedAt.Valid {
+		todo.CompletedAt = &completedAt.Time
+	}
+	if dueAt.Valid {
+		todo.DueAt = &dueAt.Time
+	}
+	if deletedAt.Valid {
+		todo.DeletedAt = &deletedAt.Time
+	}
+	// Tags live in todo_tag, loaded by loadTags
+	todo.Tags = []string{}
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	todo.ID = docID
+
+	return &todo, nil
+}
+
+// sqlSortColumns - column of every sort field, text columns are compared byte by byte like in Mongo
+var sqlSortColumns = map[string]struct {
+	column string
+	text   bool
+}{
+	models.SortTitle:       {column: "title", text: true},
+	models.SortStatus:      {column: "status", text: true},
+	models.SortPriority:    {column: "priority"},
+	models.SortDueAt:       {column: "due_at"},
+	models.SortCompletedAt: {column: "completed_at"},
+	models.SortCreatedAt:   {column: "created_at"},
+	models.SortUpdatedAt:   {column: "updated_at"},
+	models.SortDeletedAt:   {column: "deleted_at"},
+	models.SortID:          {column: "id", text: true},
+	// Only a column of the search queries, see search
+	models.SortScore: {column: "score"},
+}
+
+// sortKeyColumn - column of a sort field, the score is left out when not searching
+func sortKeyColumn(field string, search bool) (column string, text bool, ok bool) {
+	if field == models.SortScore && !search {
+		return "", false, false
+	}
+
+	key, ok := sqlSortColumns[field]
+	return key.column, key.text, ok
+}
+
+// orderBy - ORDER BY clause of a todo list, NULL sorts first like in Mongo and id breaks ties
+func (m *sqlTodoRepository) orderBy(sort models.TodoSort, search bool) string {
+	var keys []string
+	for _, field := range sort.WithID() {
+		column, text, ok := sortKeyColumn(field.Field, search)
+		if !ok {
+			continue
+		}
+
+		key := m.sortColumn(column, text)
+		if field.Desc {
+			key += " DESC NULLS LAST"
+		} else {
+			key += " ASC NULLS FIRST"
+		}
+		keys = append(keys, key)
+	}
+
+	return " ORDER BY " + strings.Join(keys, ", ")
+}
+
+func (m *sqlTodoRepository) sortColumn(column string, text bool) string {
+	if text {
+		return column + m.db.Dialect.Binary
+	}
+
+	return column
+}
+
+// keyset - condition matching the todos after the keyset key, NULL sorts before any value like in orderBy
+func (m *sqlTodoRepository) keyset(keyset *models.Keyset, search bool) (string, []interface{}) {
+	var (
+		branches  []string
+		args      []interface{}
+		equal     []string
+		equalArgs []interface{}
+	)
+	for _, field := range keyset.Sort.WithID() {
+		column, text, ok := sortKeyColumn(field.Field, search)
+		if !ok {
+			continue
+		}
+		key := m.sortColumn(column, text)
+
+		value := sortValue(keyset.Key, field.Field)
+		if id, ok := value.(primitive.ObjectID); ok {
+			value = id.Hex()
+		}
+
+		var (
+			after     string
+			afterArgs []interface{}
+		)
+		switch {
+		case !field.Desc && value == nil:
+			after = column + " IS NOT NULL"
+		case !field.Desc:
+			after, afterArgs = key+" > ?", []interface{}{value}
+		case value != nil:
+			after, afterArgs = "("+key+" < ? OR "+column+" IS NULL)", []interface{}{value}
+		}
+		// Nothing comes after NULL in descending order
+		if after != "" {
+			branches = append(branches, "("+strings.Join(append(append([]string{}, equal...), after), " AND ")+")")
+			args = append(append(args, equalArgs...), afterArgs...)
+		}
+
+		if value == nil {
+			equal = append(equal, column+" IS NULL")
+		} else {
+			equal = append(equal, key+" = ?")
+			equalArgs = append(equalArgs, value)
+		}
+	}
+
+	return "(" + strings.Join(branches, " OR ") + ")", args
+}
+
+// where - WHERE clause of a todo list, the keyword is case-insensitive like the Mongo $regex filter
+func (m *sqlTodoRepository) where(filter models.TodoFilter) (string, []interface{}) {
+	var (
+		conditions = []string{"deleted_at IS NULL"}
+		args       []interface{}
+	)
+	if filter.Deleted {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+
+	if filter.Keyword != "" {
+		conditions = append(conditions, fmt.Sprintf(`title %s ? ESCAPE '\'`, m.db.Dialect.ILike))
+		args = append(args, "%"+pkg_sqldb.EscapeLike(filter.Keyword)+"%")
+	}
+
+	if filter.Search != "" {
+		condition, searchArgs := m.searchCondition(models.ParseSearch(filter.Search))
+		conditions = append(conditions, condition)
+		args = append(args, searchArgs...)
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM todo_tag WHERE todo_tag.todo_id = todo.id AND todo_tag.tag = ?)")
+		args = append(args, tag)
+	}
+
+	if len(filter.IDs) > 0 {
+		conditions = append(conditions, "id IN (?"+strings.Repeat(", ?", len(filter.IDs)-1)+")")
+		for _, id := range filter.IDs {
+			args = append(args, id.Hex())
+		}
+	}
+
+	if filter.PriorityMin != nil {
+		conditions = append(conditions, "priority >= ?")
+		args = append(args, *filter.PriorityMin)
+	}
+	if filter.PriorityMax != nil {
+		conditions = append(conditions, "priority <= ?")
+		args = append(args, *filter.PriorityMax)
+	}
+
+	if filter.Overdue {
+		conditions = append(conditions, "due_at < ? AND status IN (?, ?)")
+		args = append(args, utils.GetTimeNow(), models.StatusTodo, models.StatusInProgress)
+	}
+	if filter.DueBefore != nil {
+		conditions = append(conditions, "due_at < ?")
+		args = append(args, *filter.DueBefore)
+	}
+	if filter.DueAfter != nil {
+		conditions = append(conditions, "due_at > ?")
+		args = append(args, *filter.DueAfter)
+	}
+
+	// The keyset of a search is applied by search, on the score too
+	if filter.After != nil {
+		condition, keysetArgs := m.keyset(filter.After, false)
+		conditions = append(conditions, condition)
+		args = append(args, keysetArgs...)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// searchCondition - todos matching a full-text search, through the tsvector column on Postgres and the FTS4
+// table on SQLite. Words are stemmed like in Mongo.
+func (m *sqlTodoRepository) searchCondition(query models.SearchQuery) (string, []interface{}) {
+	if len(query.Phrases) == 0 && len(query.Terms) == 0 {
+		// Only negations, like Mongo nothing matches
+		return "1 = 0", nil
+	}
+
+	if m.db.Dialect.DriverName != pkg_sqldb.Postgres.DriverName {
+		return "rowid IN (SELECT docid FROM todo_fts WHERE todo_fts MATCH ?)", []interface{}{ftsMatch(query)}
+	}
+
+	condition, args := tsQuery(query)
+	conditions := []string{"search_vector @@ (" + condition + ")"}
+	for _, text := range query.Excluded {
+		conditions = append(conditions, fmt.Sprintf("NOT search_vector @@ phraseto_tsquery('%s', ?)", sqlSearchConfig))
+		args = append(args, text)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// tsQuery - Postgres tsquery of the phrases, or of any term when there is no phrase
+func tsQuery(query models.SearchQuery) (string, []interface{}) {
+	var (
+		queries []string
+		args    []interface{}
+	)
+	if len(query.Phrases) > 0 {
+		for _, text := range query.Phrases {
+			queries = append(queries, fmt.Sprintf("phraseto_tsquery('%s', ?)", sqlSearchConfig))
+			args = append(args, text)
+		}
+
+		return strings.Join(queries, " && "), args
+	}
+
+	for _, text := range query.Terms {
+		queries = append(queries, fmt.Sprintf("plainto_tsquery('%s', ?)", sqlSearchConfig))
+		args = append(args, text)
+	}
+
+	return strings.Join(queries, " || "), args
+}
+
+// ftsMatch - SQLite FTS4 MATCH expression of a search with phrases or terms. Every text is quoted as a
+// phrase, so the FTS operators in it are words.
+func ftsMatch(query models.SearchQuery) string {
+	// A * in a phrase is a prefix query
+	quote := func(text string) string {
+		return `"` + strings.NewReplacer(`"`, " ", "*", " ").Replace(text) + `"`
+	}
+
+	var texts []string
+	separator := " OR "
+	if len(query.Phrases) > 0 {
+		texts, separator = query.Phrases, " "
+	} else {
+		texts = query.Terms
+	}
+
+	quoted := make([]string, len(texts))
+	for i, text := range texts {
+		quoted[i] = quote(text)
+	}
+
+	match := "(" + strings.Join(quoted, separator) + ")"
+	for _, text := range query.Excluded {
+		match += " NOT " + quote(text)
+	}
+
+	return match
+}
+
+// searchScore - relevance of a todo to a search. Postgres ranks with ts_rank, title words weigh more. SQLite
+// has no rank function in FTS4, so the score is the one of the memory repository, the weighted number of
+// occurrences of the terms and phrases.
+func (m *sqlTodoRepository) searchScore(query models.SearchQuery) (string, []interface{}) {
+	if m.db.Dialect.DriverName == pkg_sqldb.Postgres.DriverName {
+		if len(query.Phrases) == 0 && len(query.Terms) == 0 {
+			return "0", nil
+		}
+
+		// float8 so the score of a cursor compares equal to the ranked one
+		condition, args := tsQuery(query)
+		return "ts_rank(search_vector, " + condition + ")::float8", args
+	}
+
+	var (
+		counts []string
+		args   []interface{}
+	)
+	for _, text := range append(append([]string{}, query.Terms...), query.Phrases...) {
+		counts = append(counts, "(? * (LENGTH(LOWER(title)) - LENGTH(REPLACE(LOWER(title), ?, ''))) + "+
+			"? * (LENGTH(LOWER(description)) - LENGTH(REPLACE(LOWER(description), ?, '')))) / ?")
+		args = append(args, models.TitleWeight, text, models.DescriptionWeight, text, utf8.RuneCountInString(text))
+	}
+	if len(counts) == 0 {
+		return "0", nil
+	}
+
+	return "(" + strings.Join(counts, " + ") + ")", args
+}
+
+// nullTime - NULL for nil timestamps
+func nullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: *value, Valid: true}
+}
+
+// status - status to store, todo when unset
+func status(value *models.Todo) models.TodoStatus {
+	if value.Status == "" {
+		return models.StatusTodo
+	}
+
+	return value.Status
+}
+
+// loadTags - fill the tags of todos with a single query
+func loadTags(ctx context.Context, q pkg_sqldb.Queryer, todos []*models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Todo, len(todos))
+	placeholders := make([]string, 0, len(todos))
+	args := make([]interface{}, 0, len(todos))
+	for _, todo := range todos {
+		byID[todo.ID.Hex()] = todo
+		placeholders = append(placeholders, "?")
+		args = append(args, todo.ID.Hex())
+	}
+
+	rows, err := q.QueryContext(ctx,
+		"SELECT todo_id, tag FROM todo_tag WHERE todo_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY tag",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+
+		if todo, ok := byID[id]; ok {
+			todo.Tags = append(todo.Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+// saveTags - replace the tags of the todo id
+func saveTags(ctx context.Context, q pkg_sqldb.Queryer, id string, tags []string) error {
+	if _, err := q.ExecContext(ctx, "DELETE FROM todo_tag WHERE todo_id = ?", id); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := q.ExecContext(ctx, "INSERT INTO todo_tag (todo_id, tag) VALUES (?, ?)", id, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectTodo - todo by id with its tags, read with q. lock is Dialect.ForUpdate to lock the row, or empty.
+func selectTodo(ctx context.Context, q pkg_sqldb.Queryer, id string, lock string) (*models.Todo, error) {
+	todo, err := scanTodo(q.QueryRowContext(ctx, "SELECT "+sqlTodoColumns+" FROM todo WHERE id = ?"+lock, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadTags(ctx, q, []*models.Todo{todo}); err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// versionCondition - condition on the id and the version of a live todo, any version when 0
+func versionCondition(id string, version int64) (string, []interface{}) {
+	if version > 0 {
+		return " WHERE id = ? AND version = ? AND deleted_at IS NULL", []interface{}{id, version}
+	}
+
+	return " WHERE id = ? AND deleted_at IS NULL", []interface{}{id}
+}
+
+// writeError - error of a write that matched no row, ErrPreconditionFailed when the todo exists at another version
+func writeError(ctx context.Context, q pkg_sqldb.Queryer, op string, id string, version int64) error {
+	if version > 0 {
+		var total int
+		if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM todo WHERE id = ? AND deleted_at IS NULL", id).Scan(&total); err != nil {
+			return err
+		}
+
+		if total > 0 {
+			return models.NewError(models.ErrPreconditionFailed, op, id, nil)
+		}
+	}
+
+	return models.NewError(models.ErrNotFound, op, id, nil)
+}
+
+// lockTodo - todo by id before a write, locked until the transaction ends, nil when there is none
+func (m *sqlTodoRepository) lockTodo(ctx context.Context, tx *pkg_sqldb.Tx, id string) (*models.Todo, error) {
+	todo, err := selectTodo(ctx, tx, id, m.db.Dialect.ForUpdate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return todo, err
+}
+
+// record - insert the revision and the outbox event of a write that changed todo from before to after, in the
+// transaction of the write
+func record(ctx context.Context, q pkg_sqldb.Queryer, action models.RevisionAction, before, after *models.Todo) error {
+	revision, err := newRevision(ctx, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx,
+		"INSERT INTO todo_revision (todo_id, rev, action, changes, actor, trace_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		revision.TodoID.Hex(), revision.Rev, revision.Action, string(changes), revision.Actor, revision.TraceID,
+		revision.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	event := newOutboxEvent(ctx, action, before, after)
+	var todo sql.NullString
+	if event.Todo != nil {
+		value, err := json.Marshal(event.Todo)
+		if err != nil {
+			return err
+		}
+		todo = sql.NullString{String: string(value), Valid: true}
+	}
+
+	_, err = q.ExecContext(ctx,
+		"INSERT INTO todo_outbox (id, type, todo_id, todo, actor, trace_parent, occurred_at, available_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		event.ID.Hex(), event.Type, event.TodoID.Hex(), todo, event.Actor, event.TraceParent, event.OccurredAt,
+		event.AvailableAt,
+	)
+
+	return err
+}
+
+// inTx - run fn in a transaction, committed when fn returns nil
+func (m *sqlTodoRepository) inTx(ctx context.Context, fn func(tx *pkg_sqldb.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindAll - find all todo
+func (m *sqlTodoRepository) FindAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, limit int, offset int) ([]*models.Todo, error) {
+	ctx, span := startSpan(ctx, "FindAll", m.db.Dialect.System)
+	defer span.End()
+
+	// A zero limit means no limit, like options.Find().SetLimit(0)
+	sqlLimit := int64(limit)
+	if limit <= 0 {
+		sqlLimit = math.MaxInt64
+	}
+
+	var (
+		results []*models.Todo
+		err     error
+	)
+	if filter.Search != "" {
+		query, args := m.search(filter, sqlTodoColumns+", score")
+		query += m.orderBy(sort, true) + " LIMIT ? OFFSET ?"
+		results, err = m.queryTodos(ctx, true, query, append(args, sqlLimit, offset)...)
+	} else {
+		where, args := m.where(filter)
+		// Object ids grow with insertion, ordering by id keeps the Mongo natural order
+		query := "SELECT " + sqlTodoColumns + " FROM todo" + where + m.orderBy(sort, false) + " LIMIT ? OFFSET ?"
+		results, err = m.query(ctx, query, append(args, sqlLimit, offset)...)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return []*models.Todo{}, err
+	}
+
+	return results, nil
+}
+
+// search - SELECT of columns of the todos matching a full-text search. The matches are scored in a subquery,
+// so the keyset, the order and the limit apply to the score like to the other columns.
+func (m *sqlTodoRepository) search(filter models.TodoFilter, columns string) (string, []interface{}) {
+	after := filter.After
+	filter.After = nil
+
+	score, args := m.searchScore(models.ParseSearch(filter.Search))
+	where, whereArgs := m.where(filter)
+	args = append(args, whereArgs...)
+
+	query := "SELECT " + columns + " FROM (SELECT " + sqlTodoColumns + ", " + score + " AS score FROM todo" + where + ") AS todo"
+	if after != nil {
+		condition, keysetArgs := m.keyset(after, true)
+		query += " WHERE " + condition
+		args = append(args, keysetArgs...)
+	}
+
+	return query, args
+}
+
+// query - todos selected by query, with their tags
+func (m *sqlTodoRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Todo, error) {
+	return m.queryTodos(ctx, false, query, args...)
+}
+
+// queryTodos - todos selected by query, with their tags. The score is the last column of a scored query.
+func (m *sqlTodoRepository) queryTodos(ctx context.Context, scored bool, query string, args ...interface{}) ([]*models.Todo, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*models.Todo
+	for rows.Next() {
+		var (
+			score float64
+			extra []interface{}
+		)
+		if scored {
+			extra = append(extra, &score)
+		}
+
+		todo, err := scanTodo(rows, extra...)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		todo.Score = score
+		results = append(results, todo)
+	}
+
+	// Release the connection before loading tags, SQLite has a single one
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadTags(ctx, m.db, results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// CountFindAll - count find all todo
+func (m *sqlTodoRepository) CountFindAll(ctx context.Context, filter models.TodoFilter) (int, error) {
+	ctx, span := startSpan(ctx, "CountFindAll", m.db.Dialect.System)
+	defer span.End()
+
+	var (
+		query string
+		args  []interface{}
+	)
+	if filter.Search != "" {
+		query, args = m.search(filter, "COUNT(*)")
+	} else {
+		var where string
+		where, args = m.where(filter)
+		query = "SELECT COUNT(*) FROM todo" + where
+	}
+
+	var total int
+	err := m.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// FindById - find todo by id
+func (m *sqlTodoRepository) FindById(ctx context.Context, id string) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "FindById", m.db.Dialect.System)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.FindById", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	results, err := m.query(ctx, "SELECT "+sqlTodoColumns+" FROM todo WHERE id = ? AND deleted_at IS NULL", docID.Hex())
+	if err == nil && len(results) == 0 {
+		err = models.NewError(models.ErrNotFound, "TodoRepository.FindById", id, sql.ErrNoRows)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return &models.Todo{}, err
+	}
+	result := results[0]
+
+	return result, nil
+}
+
+// Store - store todo
+func (m *sqlTodoRepository) Store(ctx context.Context, value *models.Todo) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Store", m.db.Dialect.System)
+	defer span.End()
+
+	var result *models.Todo
+	err := m.inTx(ctx, func(tx *pkg_sqldb.Tx) (err error) {
+		result, err = store(ctx, tx, value)
+		return err
+	})
+	if pkg_sqldb.IsUniqueViolation(err) {
+		err = models.NewError(models.ErrConflict, "TodoRepository.Store", "", err)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return &models.Todo{}, err
+	}
+
+	return result, nil
+}
+
+// store - insert a new todo with the fields of value in tx
+func store(ctx context.Context, tx *pkg_sqldb.Tx, value *models.Todo) (*models.Todo, error) {
+	result := newTodo(value, utils.GetTimeNow())
+	result.ID = primitive.NewObjectID()
+
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO todo ("+sqlTodoColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		result.ID.Hex(), result.Title, result.Description, result.Status, nullTime(result.CompletedAt),
+		nullTime(result.DueAt), result.Priority, result.CreatedAt, result.UpdatedAt, result.Version,
+		nullTime(result.DeletedAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveTags(ctx, tx, result.ID.Hex(), result.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := record(ctx, tx, models.ActionCreate, nil, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Update - update todo by id, and return the updated todo
+func (m *sqlTodoRepository) Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Update", m.db.Dialect.System)
+	defer span.End()
+
+	var result *models.Todo
+	err := m.inTx(ctx, func(tx *pkg_sqldb.Tx) (err error) {
+		result, err = m.update(ctx, tx, id, value, version)
+		return err
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// update - update todo by id in tx, and return the updated todo
+func (m *sqlTodoRepository) update(ctx context.Context, tx *pkg_sqldb.Tx, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidID, "TodoRepository.Update", id, err)
+	}
+
+	before, err := m.lockTodo(ctx, tx, docID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	where, whereArgs := versionCondition(docID.Hex(), version)
+	args := []interface{}{
+		value.Title, value.Description, status(value), nullTime(value.CompletedAt), nullTime(value.DueAt),
+		value.Priority, utils.GetTimeNow(),
+	}
+	res, err := tx.ExecContext(ctx,
+		`UPDATE todo SET title = ?, description = ?, status = ?, completed_at = ?, due_at = ?, priority = ?, updated_at = ?,
+		version = version + 1`+where,
+		append(args, whereArgs...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	matched, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if matched <= 0 {
+		return nil, writeError(ctx, tx, "TodoRepository.Update", id, version)
+	}
+
+	if err := saveTags(ctx, tx, docID.Hex(), tags(value)); err != nil {
+		return nil, err
+	}
+
+	result, err := selectTodo(ctx, tx, docID.Hex(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := record(ctx, tx, models.ActionUpdate, before, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// sqlPatchColumns - column of every models.Field* a patch can set
+var sqlPatchColumns = map[string]string{
+	models.FieldTitle:       "title",
+	models.FieldDescription: "description",
+	models.FieldStatus:      "status",
+	models.FieldCompletedAt: "completed_at",
+	models.FieldDueAt:       "due_at",
+	models.FieldPriority:    "priority",
+}
+
+// sqlPatchValue - value of the column of field, see sqlPatchColumns
+func sqlPatchValue(value *models.Todo, field string) interface{} {
+	switch field {
+	case models.FieldTitle:
+		return value.Title
+	case models.FieldDescription:
+		return value.Description
+	case models.FieldStatus:
+		return status(value)
+	case models.FieldCompletedAt:
+		return nullTime(value.CompletedAt)
+	case models.FieldDueAt:
+		return nullTime(value.DueAt)
+	case models.FieldPriority:
+		return value.Priority
+	}
+
+	return nil
+}
+
+// Patch - set the fields of todo by id to the ones of value, and return the patched todo
+func (m *sqlTodoRepository) Patch(ctx context.Context, id string, value *models.Todo, fields []string, version int64) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Patch", m.db.Dialect.System)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Patch", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	assignments := []string{}
+	args := []interface{}{}
+	patchTags := false
+	for _, field := range fields {
+		if field == models.FieldTags {
+			patchTags = true
+			continue
+		}
+
+		if column, ok := sqlPatchColumns[field]; ok {
+			assignments = append(assignments, column+" = ?")
+			args = append(args, sqlPatchValue(value, field))
+		}
+	}
+	assignments = append(assignments, "updated_at = ?", "version = version + 1")
+	args = append(args, utils.GetTimeNow())
+
+	where, whereArgs := versionCondition(docID.Hex(), version)
+	args = append(args, whereArgs...)
+
+	var result *models.Todo
+	err = m.inTx(ctx, func(tx *pkg_sqldb.Tx) error {
+		before, err := m.lockTodo(ctx, tx, docID.Hex())
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, "UPDATE todo SET "+strings.Join(assignments, ", ")+where, args...)
+		if err != nil {
+			return err
+		}
+
+		matched, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if matched <= 0 {
+			return writeError(ctx, tx, "TodoRepository.Patch", id, version)
+		}
+
+		if patchTags {
+			if err := saveTags(ctx, tx, docID.Hex(), tags(value)); err != nil {
+				return err
+			}
+		}
+
+		result, err = selectTodo(ctx, tx, docID.Hex(), "")
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, models.ActionPatch, before, result)
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete - move todo by id to the trash
+func (m *sqlTodoRepository) Delete(ctx context.Context, id string, version int64) error {
+	ctx, span := startSpan(ctx, "Delete", m.db.Dialect.System)
+	defer span.End()
+
+	err := m.inTx(ctx, func(tx *pkg_sqldb.Tx) error {
+		return m.delete(ctx, tx, id, version)
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// delete - move todo by id to the trash in tx
+func (m *sqlTodoRepository) delete(ctx context.Context, tx *pkg_sqldb.Tx, id string, version int64) error {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.NewError(models.ErrInvalidID, "TodoRepository.Delete", id, err)
+	}
+
+	before, err := m.lockTodo(ctx, tx, docID.Hex())
+	if err != nil {
+		return err
+	}
+
+	where, whereArgs := versionCondition(docID.Hex(), version)
+	timeNow := utils.GetTimeNow()
+	res, err := tx.ExecContext(ctx,
+		"UPDATE todo SET deleted_at = ?, updated_at = ?, version = version + 1"+where,
+		append([]interface{}{timeNow, timeNow}, whereArgs...)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted <= 0 {
+		return writeError(ctx, tx, "TodoRepository.Delete", id, version)
+	}
+
+	after, err := selectTodo(ctx, tx, docID.Hex(), "")
+	if err != nil {
+		return err
+	}
+
+	return record(ctx, tx, models.ActionDelete, before, after)
+}
+
+// Restore - move todo by id out of the trash, and return the restored todo
+func (m *sqlTodoRepository) Restore(ctx context.Context, id string) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Restore", m.db.Dialect.System)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Restore", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	var result *models.Todo
+	err = m.inTx(ctx, func(tx *pkg_sqldb.Tx) error {
+		before, err := m.lockTodo(ctx, tx, docID.Hex())
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"UPDATE todo SET deleted_at = NULL, updated_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL",
+			utils.GetTimeNow(), docID.Hex(),
+		)
+		if err != nil {
+			return err
+		}
+
+		restored, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if restored <= 0 {
+			return models.NewError(models.ErrNotFound, "TodoRepository.Restore", id, nil)
+		}
+
+		result, err = selectTodo(ctx, tx, docID.Hex(), "")
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, models.ActionRestore, before, result)
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Purge - permanently delete the todos deleted before before with their tags, and return how many
+func (m *sqlTodoRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := startSpan(ctx, "Purge", m.db.Dialect.System)
+	defer span.End()
+
+	var purged int64
+	err := m.inTx(ctx, func(tx *pkg_sqldb.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"DELETE FROM todo_tag WHERE todo_id IN (SELECT id FROM todo WHERE deleted_at < ?)", before,
+		)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM todo WHERE deleted_at < ?", before)
+		if err != nil {
+			return err
+		}
+
+		purged, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	return int(purged), nil
+}
+
+// FindRevisions - find the revisions of todo by id, oldest first
+func (m *sqlTodoRepository) FindRevisions(ctx context.Context, id string) ([]*models.Revision, error) {
+	ctx, span := startSpan(ctx, "FindRevisions", m.db.Dialect.System)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.FindRevisions", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	results, err := m.queryRevisions(ctx, docID)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (m *sqlTodoRepository) queryRevisions(ctx context.Context, docID primitive.ObjectID) ([]*models.Revision, error) {
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT rev, action, changes, actor, trace_id, created_at FROM todo_revision WHERE todo_id = ? ORDER BY rev",
+		docID.Hex(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.Revision{}
+	for rows.Next() {
+		revision := &models.Revision{TodoID: docID}
+		var changes string
+		err := rows.Scan(&revision.Rev, &revision.Action, &changes, &revision.Actor, &revision.TraceID, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(changes), &revision.Changes); err != nil {
+			return nil, err
+		}
+		results = append(results, revision)
+	}
+
+	return results, rows.Err()
+}
+
+// BulkWrite - create, update and delete todos like Store, Update and Delete. Every batch is a transaction,
+// a single one for an atomic bulk. A failed write leaves the transaction usable, the other errors fail the batch.
+func (m *sqlTodoRepository) BulkWrite(ctx context.Context, writes []*models.TodoWrite, atomic bool) ([]*models.BulkResult, error) {
+	ctx, span := startBulkSpan(ctx, m.db.Dialect.System, writes, atomic)
+	defer span.End()
+
+	write := func(ctx context.Context, tx *pkg_sqldb.Tx, writes []*models.TodoWrite, results []*models.BulkResult) error {
+		for i, write := range writes {
+			var err error
+			switch write.Op {
+			case models.BulkCreate:
+				results[i].Todo, err = store(ctx, tx, write.Value)
+				if err == nil {
+					results[i].ID = results[i].Todo.ID.Hex()
+				}
+			case models.BulkUpdate:
+				results[i].Todo, err = m.update(ctx, tx, write.ID, write.Value, write.Version)
+			case models.BulkDelete:
+				err = m.delete(ctx, tx, write.ID, write.Version)
+			}
+
+			var domainErr *models.Error
+			if err != nil && !errors.As(err, &domainErr) {
+				return err
+			}
+			results[i].Err = err
+		}
+
+		return nil
+	}
+
+	results := newBulkResults(writes)
+	var err error
+	if atomic {
+		err = m.inTx(ctx, func(tx *pkg_sqldb.Tx) error {
+			return inBatches(ctx, m.db.Dialect.System, writes, results, true, func(ctx context.Context, writes []*models.TodoWrite, results []*models.BulkResult) error {
+				return write(ctx, tx, writes, results)
+			})
+		})
+	} else {
+		err = inBatches(ctx, m.db.Dialect.System, writes, results, false, func(ctx context.Context, writes []*models.TodoWrite, results []*models.BulkResult) error {
+			return m.inTx(ctx, func(tx *pkg_sqldb.Tx) error {
+				return write(ctx, tx, writes, results)
+			})
+		})
+	}
+	if errors.Is(err, errBulkFailed) {
+		abortBulk(results)
+		return results, nil
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// sqlEventColumns - columns scanned by scanEvent, in order
+const sqlEventColumns = "id, type, todo_id, todo, actor, trace_parent, occurred_at"
+
+// scanEvent - scan an outbox row selected with sqlEventColumns
+func scanEvent(row rowScanner) (*models.Event, error) {
+	var (
+		id, todoID string
+		todo       sql.NullString
+		event      models.Event
+	)
+	err := row.Scan(&id, &event.Type, &todoID, &todo, &event.Actor, &event.TraceParent, &event.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if todo.Valid {
+		if err := json.Unmarshal([]byte(todo.String), &event.Todo); err != nil {
+			return nil, err
+		}
+	}
+
+	if event.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, err
+	}
+	if event.TodoID, err = primitive.ObjectIDFromHex(todoID); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// ClaimEvents - postpone the available events one by one, an event another relay claimed first is no longer
+// available and is skipped
+func (m *sqlTodoRepository) ClaimEvents(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Event, error) {
+	ctx, span := startSpan(ctx, "ClaimEvents", m.db.Dialect.System)
+	defer span.End()
+
+	results, err := m.claimEvents(ctx, now, until, limit)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("outbox.event.claimed", len(results)))
+
+	return results, nil
+}
+
+func (m *sqlTodoRepository) claimEvents(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Event, error) {
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT "+sqlEventColumns+" FROM todo_outbox WHERE available_at <= ? ORDER BY available_at, id LIMIT ?",
+		now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*models.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := []*models.Event{}
+	for _, event := range events {
+		res, err := m.db.ExecContext(ctx,
+			"UPDATE todo_outbox SET available_at = ? WHERE id = ? AND available_at <= ?",
+			until, event.ID.Hex(), now,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+
+		results = append(results, event)
+	}
+
+	return results, nil
+}
+
+// DeleteEvents - delete the events by id from the outbox
+func (m *sqlTodoRepository) DeleteEvents(ctx context.Context, ids []primitive.ObjectID) error {
+	ctx, span := startSpan(ctx, "DeleteEvents", m.db.Dialect.System)
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		args = append(args, id.Hex())
+	}
+
+	_, err := m.db.ExecContext(ctx, "DELETE FROM todo_outbox WHERE id IN ("+strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
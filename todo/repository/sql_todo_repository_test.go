@@ -0,0 +1,151 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkg_sqldb "go-distributed-tracing/pkg/sqldb"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/todo/repository/repositorytest"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+func openSQL(t *testing.T, dialect pkg_sqldb.Dialect, dsn string) *pkg_sqldb.DB {
+	t.Helper()
+
+	db, err := pkg_sqldb.Open(dialect, dsn, "todo", global.Meter("test"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+func newSQLTodoRepository(t *testing.T, dialect pkg_sqldb.Dialect, dsn string) repository.TodoRepository {
+	t.Helper()
+
+	db := openSQL(t, dialect, dsn)
+	require.NoError(t, repository.MigrateSQL(context.Background(), db))
+
+	return repository.NewSQLTodoRepository(db)
+}
+
+func TestSQLiteTodoRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.TodoRepository {
+		return newSQLTodoRepository(t, pkg_sqldb.SQLite, filepath.Join(t.TempDir(), "todo.db"))
+	})
+}
+
+func TestSQLiteIdempotencyRepository(t *testing.T) {
+	repositorytest.RunIdempotency(t, func(t *testing.T) repository.IdempotencyRepository {
+		db := openSQL(t, pkg_sqldb.SQLite, filepath.Join(t.TempDir(), "todo.db"))
+		require.NoError(t, repository.MigrateSQL(context.Background(), db))
+
+		return repository.NewSQLIdempotencyRepository(db)
+	})
+}
+
+func TestSQLiteWebhookRepository(t *testing.T) {
+	repositorytest.RunWebhook(t, func(t *testing.T) repository.WebhookRepository {
+		db := openSQL(t, pkg_sqldb.SQLite, filepath.Join(t.TempDir(), "todo.db"))
+		require.NoError(t, repository.MigrateSQL(context.Background(), db))
+
+		return repository.NewSQLWebhookRepository(db)
+	})
+}
+
+func TestSQLiteMigrateSQL(t *testing.T) {
+	ctx := context.Background()
+	db := openSQL(t, pkg_sqldb.SQLite, filepath.Join(t.TempDir(), "todo.db"))
+
+	versions := func() int {
+		var total int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&total))
+		return total
+	}
+
+	require.NoError(t, repository.MigrateSQL(ctx, db))
+	applied := versions()
+	require.NotZero(t, applied)
+
+	// Migrating an up to date schema is a no-op
+	require.NoError(t, repository.MigrateSQL(ctx, db))
+	require.Equal(t, applied, versions())
+}
+
+// openPostgres - open TEST_POSTGRES_URL in a new schema dropped after the test, skip when it is not set
+func openPostgres(t *testing.T, schemas *int) *pkg_sqldb.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	// Every subtest gets its own schema through the search_path of a dedicated pool
+	*schemas++
+	schema := fmt.Sprintf("todo_conformance_%d_%d", os.Getpid(), *schemas)
+
+	admin := openSQL(t, pkg_sqldb.Postgres, dsn)
+	_, err := admin.ExecContext(context.Background(), "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		admin.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	db := openSQL(t, pkg_sqldb.Postgres, withSearchPath(dsn, schema))
+	require.NoError(t, repository.MigrateSQL(context.Background(), db))
+
+	return db
+}
+
+// TestPostgresTodoRepository - runs against a real server, e.g. TEST_POSTGRES_URL=postgres://postgres@localhost/postgres?sslmode=disable
+func TestPostgresTodoRepository(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES_URL") == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	schemas := 0
+	repositorytest.Run(t, func(t *testing.T) repository.TodoRepository {
+		return repository.NewSQLTodoRepository(openPostgres(t, &schemas))
+	})
+}
+
+func TestPostgresIdempotencyRepository(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES_URL") == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	schemas := 0
+	repositorytest.RunIdempotency(t, func(t *testing.T) repository.IdempotencyRepository {
+		return repository.NewSQLIdempotencyRepository(openPostgres(t, &schemas))
+	})
+}
+
+// withSearchPath - lib/pq sends unknown URL parameters as run-time parameters
+func withSearchPath(dsn string, schema string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	return dsn + separator + "search_path=" + schema
+}
+
+func TestPostgresWebhookRepository(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES_URL") == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	schemas := 0
+	repositorytest.RunWebhook(t, func(t *testing.T) repository.WebhookRepository {
+		return repository.NewSQLWebhookRepository(openPostgres(t, &schemas))
+	})
+}
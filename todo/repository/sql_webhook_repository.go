@@ -0,0 +1,508 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	pkg_sqldb "go-distributed-tracing/pkg/sqldb"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+// sqlWebhookColumns - columns scanned by scanWebhook, in order
+const sqlWebhookColumns = "id, url, events, secret, created_at, updated_at"
+
+// sqlDeliveryColumns - columns scanned by scanDelivery, in order
+const sqlDeliveryColumns = "id, webhook_id, event_id, event_type, payload, trace_parent, status, attempts, next_attempt_at, created_at, updated_at"
+
+type sqlWebhookRepository struct {
+	db *pkg_sqldb.DB
+}
+
+// NewSQLWebhookRepository will create a WebhookRepository backed by PostgreSQL or SQLite, run MigrateSQL first
+func NewSQLWebhookRepository(db *pkg_sqldb.DB) WebhookRepository {
+	return &sqlWebhookRepository{
+		db: db,
+	}
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var (
+		id      string
+		events  string
+		webhook models.Webhook
+	)
+	err := row.Scan(&id, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return nil, err
+	}
+	if webhook.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func scanDelivery(row rowScanner) (*models.Delivery, error) {
+	var (
+		id, webhookID, eventID string
+		payload, attempts      string
+		nextAttemptAt          sql.NullTime
+		delivery               models.Delivery
+	)
+	err := row.Scan(
+		&id, &webhookID, &eventID, &delivery.EventType, &payload, &delivery.TraceParent, &delivery.Status,
+		&attempts, &nextAttemptAt, &delivery.CreatedAt, &delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = json.RawMessage(payload)
+	if err := json.Unmarshal([]byte(attempts), &delivery.Attempts); err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+
+	if delivery.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, err
+	}
+	if delivery.WebhookID, err = primitive.ObjectIDFromHex(webhookID); err != nil {
+		return nil, err
+	}
+	if delivery.EventID, err = primitive.ObjectIDFromHex(eventID); err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// FindAll - find every webhook
+func (m *sqlWebhookRepository) FindAll(ctx context.Context) ([]*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "FindAll", m.db.Dialect.System)
+	defer span.End()
+
+	results, err := m.queryWebhooks(ctx)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (m *sqlWebhookRepository) queryWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT "+sqlWebhookColumns+" FROM webhook ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, webhook)
+	}
+
+	return results, rows.Err()
+}
+
+// FindById - find webhook by id
+func (m *sqlWebhookRepository) FindById(ctx context.Context, id string) (*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "FindById", m.db.Dialect.System)
+	defer span.End()
+
+	webhook, err := m.find(ctx, "WebhookRepository.FindById", id)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (m *sqlWebhookRepository) find(ctx context.Context, op string, id string) (*models.Webhook, error) {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidID, op, id, err)
+	}
+
+	webhook, err := scanWebhook(m.db.QueryRowContext(ctx, "SELECT "+sqlWebhookColumns+" FROM webhook WHERE id = ?", docID.Hex()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewError(models.ErrNotFound, op, id, err)
+	}
+
+	return webhook, err
+}
+
+// Store - store webhook
+func (m *sqlWebhookRepository) Store(ctx context.Context, value *models.Webhook) (*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "Store", m.db.Dialect.System)
+	defer span.End()
+
+	webhook := *value
+	webhook.ID = primitive.NewObjectID()
+	webhook.CreatedAt = utils.GetTimeNow()
+	webhook.UpdatedAt = webhook.CreatedAt
+	if webhook.Events == nil {
+		webhook.Events = []models.EventType{}
+	}
+
+	events, err := json.Marshal(webhook.Events)
+	if err == nil {
+		_, err = m.db.ExecContext(ctx,
+			"INSERT INTO webhook ("+sqlWebhookColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+			webhook.ID.Hex(), webhook.URL, string(events), webhook.Secret, webhook.CreatedAt, webhook.UpdatedAt,
+		)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// Update - update the url, events and secret of webhook by id
+func (m *sqlWebhookRepository) Update(ctx context.Context, id string, value *models.Webhook) (*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "Update", m.db.Dialect.System)
+	defer span.End()
+
+	webhook, err := m.update(ctx, id, value)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (m *sqlWebhookRepository) update(ctx context.Context, id string, value *models.Webhook) (*models.Webhook, error) {
+	webhook, err := m.find(ctx, "WebhookRepository.Update", id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = value.URL
+	webhook.Events = append([]models.EventType{}, value.Events...)
+	webhook.Secret = value.Secret
+	webhook.UpdatedAt = utils.GetTimeNow()
+
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := m.db.ExecContext(ctx,
+		"UPDATE webhook SET url = ?, events = ?, secret = ?, updated_at = ? WHERE id = ?",
+		webhook.URL, string(events), webhook.Secret, webhook.UpdatedAt, webhook.ID.Hex(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return nil, models.NewError(models.ErrNotFound, "WebhookRepository.Update", id, err)
+	}
+
+	return webhook, nil
+}
+
+// Delete - delete webhook by id and its deliveries
+func (m *sqlWebhookRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := startWebhookSpan(ctx, "Delete", m.db.Dialect.System)
+	defer span.End()
+
+	err := m.delete(ctx, id)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *sqlWebhookRepository) delete(ctx context.Context, id string) error {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.NewError(models.ErrInvalidID, "WebhookRepository.Delete", id, err)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhook WHERE id = ?", docID.Hex())
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return models.NewError(models.ErrNotFound, "WebhookRepository.Delete", id, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_delivery WHERE webhook_id = ?", docID.Hex()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// StoreDeliveries - store deliveries in a single transaction, their ids are set
+func (m *sqlWebhookRepository) StoreDeliveries(ctx context.Context, deliveries []*models.Delivery) error {
+	ctx, span := startWebhookSpan(ctx, "StoreDeliveries", m.db.Dialect.System)
+	defer span.End()
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	err := m.storeDeliveries(ctx, deliveries)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *sqlWebhookRepository) storeDeliveries(ctx context.Context, deliveries []*models.Delivery) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, delivery := range deliveries {
+		delivery.ID = primitive.NewObjectID()
+		if delivery.Attempts == nil {
+			delivery.Attempts = []models.DeliveryAttempt{}
+		}
+
+		attempts, err := json.Marshal(delivery.Attempts)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO webhook_delivery ("+sqlDeliveryColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			delivery.ID.Hex(), delivery.WebhookID.Hex(), delivery.EventID.Hex(), delivery.EventType, string(delivery.Payload),
+			delivery.TraceParent, delivery.Status, string(attempts), nullTime(delivery.NextAttemptAt), delivery.CreatedAt,
+			delivery.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindDeliveries - find the deliveries matching filter, newest first
+func (m *sqlWebhookRepository) FindDeliveries(ctx context.Context, filter models.DeliveryFilter, limit int, offset int) ([]*models.Delivery, error) {
+	ctx, span := startWebhookSpan(ctx, "FindDeliveries", m.db.Dialect.System)
+	defer span.End()
+
+	results, err := m.queryDeliveries(ctx, filter, limit, offset)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (m *sqlWebhookRepository) queryDeliveries(ctx context.Context, filter models.DeliveryFilter, limit int, offset int) ([]*models.Delivery, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if !filter.WebhookID.IsZero() {
+		conditions = append(conditions, "webhook_id = ?")
+		args = append(args, filter.WebhookID.Hex())
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	args = append(args, limit, offset)
+
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT "+sqlDeliveryColumns+" FROM webhook_delivery WHERE "+strings.Join(conditions, " AND ")+
+			" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, delivery)
+	}
+
+	return results, rows.Err()
+}
+
+// FindDeliveryById - find delivery by id
+func (m *sqlWebhookRepository) FindDeliveryById(ctx context.Context, id string) (*models.Delivery, error) {
+	ctx, span := startWebhookSpan(ctx, "FindDeliveryById", m.db.Dialect.System)
+	defer span.End()
+
+	delivery, err := m.findDelivery(ctx, id)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (m *sqlWebhookRepository) findDelivery(ctx context.Context, id string) (*models.Delivery, error) {
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidID, "WebhookRepository.FindDeliveryById", id, err)
+	}
+
+	delivery, err := scanDelivery(m.db.QueryRowContext(ctx,
+		"SELECT "+sqlDeliveryColumns+" FROM webhook_delivery WHERE id = ?", docID.Hex(),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewError(models.ErrNotFound, "WebhookRepository.FindDeliveryById", id, err)
+	}
+
+	return delivery, err
+}
+
+// ClaimDeliveries - postpone the due deliveries one by one, a delivery another worker claimed first is no longer
+// due and is skipped
+func (m *sqlWebhookRepository) ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Delivery, error) {
+	ctx, span := startWebhookSpan(ctx, "ClaimDeliveries", m.db.Dialect.System)
+	defer span.End()
+
+	results, err := m.claimDeliveries(ctx, now, until, limit)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("webhook.delivery.claimed", len(results)))
+
+	return results, nil
+}
+
+func (m *sqlWebhookRepository) claimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Delivery, error) {
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT id FROM webhook_delivery WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?",
+		models.DeliveryPending, now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := []*models.Delivery{}
+	for _, id := range ids {
+		res, err := m.db.ExecContext(ctx,
+			"UPDATE webhook_delivery SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?",
+			until, id, models.DeliveryPending, now,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+
+		delivery, err := m.findDelivery(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, delivery)
+	}
+
+	return results, nil
+}
+
+// UpdateDelivery - write the status, the attempts and the next attempt of delivery, the next attempt being
+// claim is the condition of the update
+func (m *sqlWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.Delivery, claim *time.Time) error {
+	ctx, span := startWebhookSpan(ctx, "UpdateDelivery", m.db.Dialect.System)
+	defer span.End()
+
+	err := m.updateDelivery(ctx, delivery, claim)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *sqlWebhookRepository) updateDelivery(ctx context.Context, delivery *models.Delivery, claim *time.Time) error {
+	attempts, err := json.Marshal(delivery.Attempts)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE webhook_delivery SET status = ?, attempts = ?, next_attempt_at = ?, updated_at = ? WHERE id = ?"
+	args := []interface{}{delivery.Status, string(attempts), nullTime(delivery.NextAttemptAt), delivery.UpdatedAt, delivery.ID.Hex()}
+	if claim == nil {
+		query += " AND next_attempt_at IS NULL"
+	} else {
+		query += " AND next_attempt_at = ?"
+		args = append(args, *claim)
+	}
+
+	res, err := m.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	// Nothing matched, the delivery is gone or another worker changed it
+	if _, err := m.findDelivery(ctx, delivery.ID.Hex()); err != nil {
+		return models.NewError(models.ErrNotFound, "WebhookRepository.UpdateDelivery", delivery.ID.Hex(), err)
+	}
+
+	return models.NewError(models.ErrConflict, "WebhookRepository.UpdateDelivery", delivery.ID.Hex(), errors.New("the delivery claim was lost"))
+}
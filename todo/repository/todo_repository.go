@@ -4,29 +4,61 @@ import (
 	"context"
 	"errors"
 	"os"
+	"regexp"
+	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
 	"go-distributed-tracing/todo/models"
 	"go-distributed-tracing/utils"
 )
 
 // TodoRepository represent the todo repository contract
 type TodoRepository interface {
-	FindAll(ctx context.Context, keyword string, limit int, offset int) ([]*models.Todo, error)
-	CountFindAll(ctx context.Context, keyword string) (int, error)
+	FindAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, limit int, offset int) ([]*models.Todo, error)
+	CountFindAll(ctx context.Context, filter models.TodoFilter) (int, error)
 	FindById(ctx context.Context, id string) (*models.Todo, error)
-	CountFindByID(ctx context.Context, id string) (int, error)
 	Store(ctx context.Context, value *models.Todo) (*models.Todo, error)
-	Update(ctx context.Context, id string, value *models.Todo) (*models.Todo, error)
-	Delete(ctx context.Context, id string) error
+	// Update, Patch and Delete only write todo at version, 0 matches any version
+	Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error)
+	Patch(ctx context.Context, id string, value *models.Todo, fields []string, version int64) (*models.Todo, error)
+	// Delete moves todo to the trash, the other methods but Restore and Purge ignore the todos in the trash
+	Delete(ctx context.Context, id string, version int64) error
+	Restore(ctx context.Context, id string) (*models.Todo, error)
+	// Purge permanently deletes the todos moved to the trash before before, their revisions are kept
+	Purge(ctx context.Context, before time.Time) (int, error)
+	// FindRevisions finds the revisions Store, Update, Patch, Delete and Restore recorded for todo, oldest first
+	FindRevisions(ctx context.Context, id string) ([]*models.Revision, error)
+	// BulkWrite creates, updates and deletes todos like Store, Update and Delete, with a result per write in
+	// order. A failed write doesn't stop the others unless atomic, then none is written.
+	BulkWrite(ctx context.Context, writes []*models.TodoWrite, atomic bool) ([]*models.BulkResult, error)
+	// ClaimEvents finds up to limit events of the outbox available at now, oldest first, and postpones them
+	// to until, so other relays skip them while they are published. Every write above stores its event with it.
+	ClaimEvents(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Event, error)
+	// DeleteEvents removes published events by id from the outbox
+	DeleteEvents(ctx context.Context, ids []primitive.ObjectID) error
 }
 
 type mongoTodoRepository struct {
 	client *mongo.Client
+
+	mu sync.Mutex
+	// transactions tells whether the deployment has transactions, nil until the server answered
+	transactions *bool
+}
+
+// startSpan - start a TodoRepository span tagged with the database system
+func startSpan(ctx context.Context, name string, system attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer("TodoRepository").Start(ctx, "TodoRepository."+name, trace.WithAttributes(system))
 }
 
 // NewMongoTodoRepository will create an object that represent the TodoRepository interface
@@ -36,18 +68,330 @@ func NewMongoTodoRepository(client *mongo.Client) TodoRepository {
 	}
 }
 
+// openStatuses - statuses of todos that are not finished, todo also matches documents without a status
+var openStatuses = bson.A{models.StatusTodo, models.StatusInProgress, nil}
+
+// todoFilter - Mongo filter of a todo list
+func todoFilter(filter models.TodoFilter) bson.M {
+	conditions := bson.A{
+		// The keyword is literal, users can't send regular expressions
+		bson.M{"title": bson.M{"$regex": regexp.QuoteMeta(filter.Keyword), "$options": "i"}},
+		deletedFilter(filter.Deleted),
+	}
+
+	switch filter.Status {
+	case "":
+	case models.StatusTodo:
+		// Todos stored before the status field existed are todo
+		conditions = append(conditions, bson.M{"status": bson.M{"$in": bson.A{models.StatusTodo, nil}}})
+	default:
+		conditions = append(conditions, bson.M{"status": filter.Status})
+	}
+
+	if len(filter.Tags) > 0 {
+		conditions = append(conditions, bson.M{"tags": bson.M{"$all": filter.Tags}})
+	}
+
+	if len(filter.IDs) > 0 {
+		conditions = append(conditions, bson.M{"_id": bson.M{"$in": filter.IDs}})
+	}
+
+	// Documents without a priority have none, a zero lower bound must still match them
+	if filter.PriorityMin != nil && *filter.PriorityMin > 0 {
+		conditions = append(conditions, bson.M{"priority": bson.M{"$gte": *filter.PriorityMin}})
+	}
+	if filter.PriorityMax != nil {
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"priority": bson.M{"$lte": *filter.PriorityMax}},
+			bson.M{"priority": nil},
+		}})
+	}
+
+	if filter.Overdue {
+		conditions = append(conditions,
+			bson.M{"dueAt": bson.M{"$lt": utils.GetTimeNow()}},
+			bson.M{"status": bson.M{"$in": openStatuses}},
+		)
+	}
+	if filter.DueBefore != nil {
+		conditions = append(conditions, bson.M{"dueAt": bson.M{"$lt": *filter.DueBefore}})
+	}
+	if filter.DueAfter != nil {
+		conditions = append(conditions, bson.M{"dueAt": bson.M{"$gt": *filter.DueAfter}})
+	}
+
+	if filter.After != nil {
+		conditions = append(conditions, keysetFilter(filter.After))
+	}
+
+	if filter.Search != "" {
+		return bson.M{"$and": conditions, "$text": bson.M{"$search": filter.Search}}
+	}
+
+	return bson.M{"$and": conditions}
+}
+
+// deletedFilter - filter of the todos in the trash, or of the live ones. Documents stored before soft delete
+// have no deletedAt and are live.
+func deletedFilter(deleted bool) bson.M {
+	if deleted {
+		return bson.M{"deletedAt": bson.M{"$ne": nil}}
+	}
+
+	return bson.M{"deletedAt": nil}
+}
+
+// todoSortFields - document field of every sort field
+var todoSortFields = map[string]string{
+	models.SortTitle:       "title",
+	models.SortStatus:      "status",
+	models.SortPriority:    "priority",
+	models.SortDueAt:       "dueAt",
+	models.SortCompletedAt: "completedAt",
+	models.SortCreatedAt:   "createdAt",
+	models.SortUpdatedAt:   "updatedAt",
+	models.SortDeletedAt:   "deletedAt",
+	models.SortScore:       "score",
+	models.SortID:          "_id",
+}
+
+// todoSort - Mongo sort of a todo list, _id breaks ties so pages do not drift between requests
+func todoSort(sort models.TodoSort) bson.D {
+	var result bson.D
+	for _, field := range sort.WithID() {
+		key, ok := todoSortFields[field.Field]
+		if !ok {
+			continue
+		}
+
+		direction := 1
+		if field.Desc {
+			direction = -1
+		}
+		result = append(result, bson.E{Key: key, Value: direction})
+	}
+
+	return result
+}
+
+// sortValue - value of a sort field of todo, nil for a missing date
+func sortValue(todo *models.Todo, field string) interface{} {
+	switch field {
+	case models.SortTitle:
+		return todo.Title
+	case models.SortStatus:
+		return string(todo.Status)
+	case models.SortPriority:
+		return todo.Priority
+	case models.SortDueAt:
+		if todo.DueAt == nil {
+			return nil
+		}
+		return *todo.DueAt
+	case models.SortCompletedAt:
+		if todo.CompletedAt == nil {
+			return nil
+		}
+		return *todo.CompletedAt
+	case models.SortCreatedAt:
+		return todo.CreatedAt
+	case models.SortUpdatedAt:
+		return todo.UpdatedAt
+	case models.SortDeletedAt:
+		if todo.DeletedAt == nil {
+			return nil
+		}
+		return *todo.DeletedAt
+	case models.SortScore:
+		return todo.Score
+	case models.SortID:
+		return todo.ID
+	}
+
+	return nil
+}
+
+// keysetFilter - Mongo filter of the todos after the keyset key, a missing value sorts before any value
+func keysetFilter(keyset *models.Keyset) bson.M {
+	var (
+		branches bson.A
+		equal    bson.A
+	)
+	for _, field := range keyset.Sort.WithID() {
+		key, ok := todoSortFields[field.Field]
+		if !ok {
+			continue
+		}
+		value := sortValue(keyset.Key, field.Field)
+
+		var after bson.M
+		switch {
+		case !field.Desc && value == nil:
+			after = bson.M{key: bson.M{"$ne": nil}}
+		case !field.Desc:
+			after = bson.M{key: bson.M{"$gt": value}}
+		case value != nil:
+			after = bson.M{"$or": bson.A{bson.M{key: bson.M{"$lt": value}}, bson.M{key: nil}}}
+		}
+		// Nothing comes after a missing value in descending order
+		if after != nil {
+			branch := append(append(bson.A{}, equal...), after)
+			branches = append(branches, bson.M{"$and": branch})
+		}
+
+		equal = append(equal, bson.M{key: value})
+	}
+
+	return bson.M{"$or": branches}
+}
+
+// searchPipeline - stages matching a full-text search and adding its score as a field, so that the keyset
+// and the sort use it like any other field
+func searchPipeline(filter models.TodoFilter) mongo.Pipeline {
+	after := filter.After
+	filter.After = nil
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: todoFilter(filter)}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+	}
+	if after != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: keysetFilter(after)}})
+	}
+
+	return pipeline
+}
+
+// MigrateMongo - create the indexes of the todo, todo_revision and todo_outbox collections and set the version of older documents. The text
+// index weights match models.TitleWeight and models.DescriptionWeight
+func MigrateMongo(ctx context.Context, client *mongo.Client) error {
+	collection := client.Database(os.Getenv("DB_NAME")).Collection("todo")
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+		Options: options.Index().
+			SetName("todo_text").
+			SetWeights(bson.M{"title": models.TitleWeight, "description": models.DescriptionWeight}),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+		Options: options.Index().SetName("todo_deleted_at"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// A todo has a single revision per version
+	_, err = client.Database(os.Getenv("DB_NAME")).Collection("todo_revision").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "todoId", Value: 1}, {Key: "rev", Value: 1}},
+		Options: options.Index().SetName("todo_revision_rev").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Idempotency keys are deleted by the TTL monitor once they expire
+	_, err = client.Database(os.Getenv("DB_NAME")).Collection("idempotency_key").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetName("idempotency_key_expires_at").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Due deliveries are claimed oldest first, the delivery log lists those of a webhook newest first
+	_, err = client.Database(os.Getenv("DB_NAME")).Collection("webhook_delivery").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+			Options: options.Index().SetName("webhook_delivery_due"),
+		},
+		{
+			Keys:    bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("webhook_delivery_webhook"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The relay claims the available events oldest first. Creating the collection also lets transactions
+	// insert into it on servers older than 4.4.
+	_, err = client.Database(os.Getenv("DB_NAME")).Collection("todo_outbox").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "availableAt", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("todo_outbox_available_at"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Documents stored before versioning start at version 1, like new ones
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": 1}},
+	)
+
+	return err
+}
+
+// withDefaults - fill the fields missing from documents stored by older versions
+func withDefaults(todo *models.Todo) *models.Todo {
+	if todo.Status == "" {
+		todo.Status = models.StatusTodo
+	}
+	if todo.Tags == nil {
+		todo.Tags = []string{}
+	}
+
+	return todo
+}
+
+// tags - tags to store, an empty array rather than null
+func tags(value *models.Todo) []string {
+	if value.Tags == nil {
+		return []string{}
+	}
+
+	return value.Tags
+}
+
 // FindAll - find all todo
-func (m *mongoTodoRepository) FindAll(ctx context.Context, keyword string, limit int, offset int) ([]*models.Todo, error) {
+func (m *mongoTodoRepository) FindAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, limit int, offset int) ([]*models.Todo, error) {
+	ctx, span := startSpan(ctx, "FindAll", semconv.DBSystemMongoDB)
+	defer span.End()
+
 	var results []*models.Todo
 
 	// Pass these options to the Find method
 	findOptions := options.Find()
 	findOptions.SetLimit(int64(limit))
 	findOptions.SetSkip(int64(offset))
+	findOptions.SetSort(todoSort(sort))
 
 	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
-	cur, err := collection.Find(ctx, bson.M{"title": bson.M{"$regex": keyword, "$options": "i"}}, findOptions)
+
+	var (
+		cur *mongo.Cursor
+		err error
+	)
+	if filter.Search != "" {
+		pipeline := append(searchPipeline(filter), bson.D{{Key: "$sort", Value: todoSort(sort)}})
+		if offset > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$skip", Value: offset}})
+		}
+		if limit > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+		}
+
+		cur, err = collection.Aggregate(ctx, pipeline)
+	} else {
+		cur, err = collection.Find(ctx, todoFilter(filter), findOptions)
+	}
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return []*models.Todo{}, err
 	}
 
@@ -59,13 +403,15 @@ func (m *mongoTodoRepository) FindAll(ctx context.Context, keyword string, limit
 		var elem models.Todo
 		err := cur.Decode(&elem)
 		if err != nil {
+			pkg_tracing.RecordError(span, err)
 			return []*models.Todo{}, err
 		}
 
-		results = append(results, &elem)
+		results = append(results, withDefaults(&elem))
 	}
 
 	if err := cur.Err(); err != nil {
+		pkg_tracing.RecordError(span, err)
 		return []*models.Todo{}, err
 	}
 
@@ -76,11 +422,38 @@ func (m *mongoTodoRepository) FindAll(ctx context.Context, keyword string, limit
 }
 
 // CountFindAll - count find all todo
-func (m *mongoTodoRepository) CountFindAll(ctx context.Context, keyword string) (int, error) {
+func (m *mongoTodoRepository) CountFindAll(ctx context.Context, filter models.TodoFilter) (int, error) {
+	ctx, span := startSpan(ctx, "CountFindAll", semconv.DBSystemMongoDB)
+	defer span.End()
+
 	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
 
-	total, err := collection.CountDocuments(ctx, bson.M{"title": bson.M{"$regex": keyword, "$options": "i"}})
+	// A keyset after a score needs the score field of the search pipeline
+	if filter.Search != "" {
+		cur, err := collection.Aggregate(ctx, append(searchPipeline(filter), bson.D{{Key: "$count", Value: "total"}}))
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return 0, err
+		}
+		defer cur.Close(ctx)
+
+		var counts []struct {
+			Total int `bson:"total"`
+		}
+		if err := cur.All(ctx, &counts); err != nil {
+			pkg_tracing.RecordError(span, err)
+			return 0, err
+		}
+		if len(counts) == 0 {
+			return 0, nil
+		}
+
+		return counts[0].Total, nil
+	}
+
+	total, err := collection.CountDocuments(ctx, todoFilter(filter))
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return int(total), err
 	}
 
@@ -89,115 +462,773 @@ func (m *mongoTodoRepository) CountFindAll(ctx context.Context, keyword string)
 
 // FindById - find todo by id
 func (m *mongoTodoRepository) FindById(ctx context.Context, id string) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "FindById", semconv.DBSystemMongoDB)
+	defer span.End()
+
 	docID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("not found")
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.FindById", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
 
 	result := &models.Todo{}
-	err = collection.FindOne(ctx, bson.M{"_id": docID}).Decode(&result)
+	err = collection.FindOne(ctx, bson.M{"_id": docID, "deletedAt": nil}).Decode(&result)
 	if err != nil {
-		if err.Error() == "mongo: no documents in result" {
-			return result, errors.New("not found")
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			err = models.NewError(models.ErrNotFound, "TodoRepository.FindById", id, err)
 		}
 
+		pkg_tracing.RecordError(span, err)
 		return result, err
 	}
 
-	return result, nil
+	return withDefaults(result), nil
 }
 
-// CountFindByID - find count todo by id
-func (m *mongoTodoRepository) CountFindByID(ctx context.Context, id string) (int, error) {
-	docID, err := primitive.ObjectIDFromHex(id)
+// versionFilter - filter of live todo docID at version, any version when 0
+func versionFilter(docID primitive.ObjectID, version int64) bson.M {
+	filter := bson.M{"_id": docID, "deletedAt": nil}
+	if version > 0 {
+		filter["version"] = version
+	}
+
+	return filter
+}
+
+// writeError - error of a write that matched no document, ErrPreconditionFailed when the todo exists at
+// another version
+func (m *mongoTodoRepository) writeError(ctx context.Context, op string, id string, docID primitive.ObjectID, version int64, err error) error {
+	if version > 0 {
+		collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
+		total, countErr := collection.CountDocuments(ctx, bson.M{"_id": docID, "deletedAt": nil})
+		if countErr != nil {
+			return countErr
+		}
+
+		if total > 0 {
+			return models.NewError(models.ErrPreconditionFailed, op, id, nil)
+		}
+	}
+
+	return models.NewError(models.ErrNotFound, op, id, err)
+}
+
+// record - insert the revision and the outbox event of a write that changed todo from before to after. Run
+// by transact, they are stored with the write.
+func (m *mongoTodoRepository) record(ctx context.Context, action models.RevisionAction, before, after *models.Todo) error {
+	revision, err := newRevision(ctx, action, before, after)
 	if err != nil {
-		return 0, errors.New("not found")
+		return err
 	}
 
-	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
-	total, err := collection.CountDocuments(ctx, bson.M{"_id": docID})
+	db := m.client.Database(os.Getenv("DB_NAME"))
+	if _, err := db.Collection("todo_revision").InsertOne(ctx, revision); err != nil {
+		return err
+	}
+
+	_, err = db.Collection("todo_outbox").InsertOne(ctx, newOutboxEvent(ctx, action, before, after))
+
+	return err
+}
+
+// ErrNoTransactions - the MongoDB deployment is a standalone server, the todo writes need transactions to
+// store their outbox events with them
+var ErrNoTransactions = errors.New("mongodb transactions need a replica set or a sharded cluster")
+
+// CheckMongoTransactions - ErrNoTransactions unless the deployment of client has transactions, check it at
+// startup as every todo write fails without them
+func CheckMongoTransactions(ctx context.Context, client *mongo.Client) error {
+	transactions, err := mongoHasTransactions(ctx, client)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	if !transactions {
+		return ErrNoTransactions
 	}
 
-	if total <= 0 {
-		return 0, errors.New("not found")
+	return nil
+}
+
+// mongoHasTransactions - the deployment of client is a replica set or a sharded cluster
+func mongoHasTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false, err
 	}
 
-	return int(total), nil
+	// mongos answers isdbgrid
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// transact - run fn in a transaction, so a write, its revision and its event are stored together or not at
+// all. It fails with ErrNoTransactions on a standalone server rather than risk losing events.
+func (m *mongoTodoRepository) transact(ctx context.Context, fn func(ctx context.Context) error) error {
+	transactions, err := m.hasTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !transactions {
+		return ErrNoTransactions
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// Transient errors run fn again
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+
+	return err
+}
+
+// hasTransactions - the deployment is a replica set or a sharded cluster, asked to the server once
+func (m *mongoTodoRepository) hasTransactions(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.transactions != nil {
+		return *m.transactions, nil
+	}
+
+	transactions, err := mongoHasTransactions(ctx, m.client)
+	if err != nil {
+		return false, err
+	}
+	m.transactions = &transactions
+
+	return transactions, nil
+}
+
+// insertDocument - document of a new todo, with its id when set
+func insertDocument(todo *models.Todo) bson.M {
+	doc := bson.M{
+		"title":       todo.Title,
+		"description": todo.Description,
+		"status":      todo.Status,
+		"completedAt": todo.CompletedAt,
+		"dueAt":       todo.DueAt,
+		"priority":    todo.Priority,
+		"tags":        tags(todo),
+		"createdAt":   todo.CreatedAt,
+		"updatedAt":   todo.UpdatedAt,
+		"version":     todo.Version,
+	}
+	if !todo.ID.IsZero() {
+		doc["_id"] = todo.ID
+	}
+
+	return doc
+}
+
+// updateDocument - update of a todo to value at timeNow
+func updateDocument(value *models.Todo, timeNow time.Time) bson.D {
+	bsonValue := bson.D{
+		{Key: "title", Value: value.Title},
+		{Key: "description", Value: value.Description},
+		{Key: "status", Value: status(value)},
+		{Key: "completedAt", Value: value.CompletedAt},
+		{Key: "dueAt", Value: value.DueAt},
+		{Key: "priority", Value: value.Priority},
+		{Key: "tags", Value: tags(value)},
+		{Key: "updatedAt", Value: timeNow},
+	}
+
+	return bson.D{{Key: "$set", Value: bsonValue}, {Key: "$inc", Value: bson.M{"version": 1}}}
+}
+
+// deleteDocument - update of a todo moved to the trash at timeNow
+func deleteDocument(timeNow time.Time) bson.D {
+	return bson.D{
+		{Key: "$set", Value: bson.M{"deletedAt": timeNow, "updatedAt": timeNow}},
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}
 }
 
 // Store - store todo
 func (m *mongoTodoRepository) Store(ctx context.Context, value *models.Todo) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Store", semconv.DBSystemMongoDB)
+	defer span.End()
+
 	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
 
-	timeNow := utils.GetTimeNow()
-	res, err := collection.InsertOne(ctx, bson.M{
-		"title":       value.Title,
-		"description": value.Description,
-		"createdAt":   timeNow,
-		"updatedAt":   timeNow,
+	result := newTodo(value, utils.GetTimeNow())
+	err := m.transact(ctx, func(ctx context.Context) error {
+		res, err := collection.InsertOne(ctx, insertDocument(result))
+		if mongo.IsDuplicateKeyError(err) {
+			err = models.NewError(models.ErrConflict, "TodoRepository.Store", "", err)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.ID = res.InsertedID.(primitive.ObjectID)
+
+		return m.record(ctx, models.ActionCreate, nil, result)
 	})
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return &models.Todo{}, err
 	}
 
-	result := &models.Todo{
-		ID:          res.InsertedID.(primitive.ObjectID),
-		Title:       value.Title,
-		Description: value.Description,
-		CreatedAt:   timeNow,
-		UpdatedAt:   timeNow,
+	return result, nil
+}
+
+// Update - update todo by id, and return the updated todo
+func (m *mongoTodoRepository) Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Update", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Update", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
+
+	timeNow := utils.GetTimeNow()
+	update := updateDocument(value, timeNow)
+
+	// The document before the update gives the revision, the result is the same update applied to it
+	var result *models.Todo
+	err = m.transact(ctx, func(ctx context.Context) error {
+		before := &models.Todo{}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+		err := collection.FindOneAndUpdate(ctx, versionFilter(docID, version), update, opts).Decode(before)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				err = m.writeError(ctx, "TodoRepository.Update", id, docID, version, err)
+			}
+			return err
+		}
+
+		before = withDefaults(before)
+		result = updated(before, value, timeNow)
+
+		return m.record(ctx, models.ActionUpdate, before, result)
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	return result, nil
 }
 
-// Update - update todo by id
-func (m *mongoTodoRepository) Update(ctx context.Context, id string, value *models.Todo) (*models.Todo, error) {
+// patchValue - bson key and value of a models.Field* of value
+func patchValue(value *models.Todo, field string) (string, interface{}) {
+	switch field {
+	case models.FieldTitle:
+		return "title", value.Title
+	case models.FieldDescription:
+		return "description", value.Description
+	case models.FieldStatus:
+		return "status", value.Status
+	case models.FieldCompletedAt:
+		return "completedAt", value.CompletedAt
+	case models.FieldDueAt:
+		return "dueAt", value.DueAt
+	case models.FieldPriority:
+		return "priority", value.Priority
+	case models.FieldTags:
+		return "tags", tags(value)
+	}
+
+	return "", nil
+}
+
+// Patch - set the fields of todo by id to the ones of value, and return the patched todo
+func (m *mongoTodoRepository) Patch(ctx context.Context, id string, value *models.Todo, fields []string, version int64) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Patch", semconv.DBSystemMongoDB)
+	defer span.End()
+
 	docID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("not found")
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Patch", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
 
+	bsonValue := bson.D{}
+	for _, field := range fields {
+		if key, fieldValue := patchValue(value, field); key != "" {
+			bsonValue = append(bsonValue, bson.E{Key: key, Value: fieldValue})
+		}
+	}
 	timeNow := utils.GetTimeNow()
-	bsonValue := bson.D{
-		{Key: "title", Value: value.Title},
-		{Key: "description", Value: value.Description},
-		{Key: "updatedAt", Value: timeNow},
+	bsonValue = append(bsonValue, bson.E{Key: "updatedAt", Value: timeNow})
+
+	update := bson.D{{Key: "$set", Value: bsonValue}, {Key: "$inc", Value: bson.M{"version": 1}}}
+
+	var result *models.Todo
+	err = m.transact(ctx, func(ctx context.Context) error {
+		before := &models.Todo{}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+		err := collection.FindOneAndUpdate(ctx, versionFilter(docID, version), update, opts).Decode(before)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				err = m.writeError(ctx, "TodoRepository.Patch", id, docID, version, err)
+			}
+			return err
+		}
+
+		before = withDefaults(before)
+		result = copyTodo(before)
+		applyPatch(result, value, fields)
+		result.UpdatedAt = timeNow
+		result.Version++
+
+		return m.record(ctx, models.ActionPatch, before, result)
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete - move todo by id to the trash
+func (m *mongoTodoRepository) Delete(ctx context.Context, id string, version int64) error {
+	ctx, span := startSpan(ctx, "Delete", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Delete", id, err)
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	timeNow := utils.GetTimeNow()
+	update := deleteDocument(timeNow)
+
+	err = m.transact(ctx, func(ctx context.Context) error {
+		before := &models.Todo{}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+		err := collection.FindOneAndUpdate(ctx, versionFilter(docID, version), update, opts).Decode(before)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				err = m.writeError(ctx, "TodoRepository.Delete", id, docID, version, nil)
+			}
+			return err
+		}
+
+		before = withDefaults(before)
+
+		return m.record(ctx, models.ActionDelete, before, deleted(before, timeNow))
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
 	}
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": docID}, bson.D{{Key: "$set", Value: bsonValue}})
+
+	return nil
+}
+
+// Restore - move todo by id out of the trash, and return the restored todo
+func (m *mongoTodoRepository) Restore(ctx context.Context, id string) (*models.Todo, error) {
+	ctx, span := startSpan(ctx, "Restore", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.Restore", id, err)
+		pkg_tracing.RecordError(span, err)
 		return nil, err
 	}
 
-	result := &models.Todo{
-		ID: docID,
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
+
+	timeNow := utils.GetTimeNow()
+	update := bson.D{
+		{Key: "$set", Value: bson.M{"deletedAt": nil, "updatedAt": timeNow}},
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}
+
+	var result *models.Todo
+	err = m.transact(ctx, func(ctx context.Context) error {
+		before := &models.Todo{}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+		err := collection.FindOneAndUpdate(ctx, bson.M{"_id": docID, "deletedAt": bson.M{"$ne": nil}}, update, opts).Decode(before)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				err = models.NewError(models.ErrNotFound, "TodoRepository.Restore", id, err)
+			}
+			return err
+		}
+
+		before = withDefaults(before)
+		result = copyTodo(before)
+		result.DeletedAt = nil
+		result.UpdatedAt = timeNow
+		result.Version++
+
+		return m.record(ctx, models.ActionRestore, before, result)
+	})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	return result, nil
 }
 
-// Delete - delete todo by id
-func (m *mongoTodoRepository) Delete(ctx context.Context, id string) error {
+// Purge - permanently delete the todos deleted before before, and return how many
+func (m *mongoTodoRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := startSpan(ctx, "Purge", semconv.DBSystemMongoDB)
+	defer span.End()
+
 	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
 
+	result, err := collection.DeleteMany(ctx, bson.M{"deletedAt": bson.M{"$ne": nil, "$lt": before}})
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+// FindRevisions - find the revisions of todo by id, oldest first
+func (m *mongoTodoRepository) FindRevisions(ctx context.Context, id string) ([]*models.Revision, error) {
+	ctx, span := startSpan(ctx, "FindRevisions", semconv.DBSystemMongoDB)
+	defer span.End()
+
 	docID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("not found")
+		err = models.NewError(models.ErrInvalidID, "TodoRepository.FindRevisions", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo_revision")
+
+	opts := options.Find().SetSort(bson.D{{Key: "rev", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"todoId": docID}, opts)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	results := []*models.Revision{}
+	if err := cursor.All(ctx, &results); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// BulkWrite - create, update and delete todos like Store, Update and Delete, with a BulkWrite per batch. An
+// atomic bulk runs in a single transaction, the other bulks in a transaction per batch.
+func (m *mongoTodoRepository) BulkWrite(ctx context.Context, writes []*models.TodoWrite, atomic bool) ([]*models.BulkResult, error) {
+	ctx, span := startBulkSpan(ctx, semconv.DBSystemMongoDB, writes, atomic)
+	defer span.End()
+
+	if !atomic {
+		results := newBulkResults(writes)
+		err := inBatches(ctx, semconv.DBSystemMongoDB, writes, results, false, func(ctx context.Context, writes []*models.TodoWrite, results []*models.BulkResult) error {
+			return m.transact(ctx, func(ctx context.Context) error {
+				// Transient errors run the batch again
+				copy(results, newBulkResults(writes))
+				return m.writeBatch(ctx, writes, results)
+			})
+		})
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		return results, nil
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
 	}
+	defer session.EndSession(ctx)
 
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": docID})
+	var results []*models.BulkResult
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// Transient errors run the whole transaction again
+		results = newBulkResults(writes)
+		return nil, inBatches(sc, semconv.DBSystemMongoDB, writes, results, true, m.writeBatch)
+	})
+	if errors.Is(err, errBulkFailed) {
+		abortBulk(results)
+		return results, nil
+	}
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// bulkModel - write model of a batch, with the todo before and after the write to record its revision
+type bulkModel struct {
+	index  int
+	action models.RevisionAction
+	before *models.Todo
+	after  *models.Todo
+}
+
+// writeBatch - write a batch of a bulk with a single BulkWrite. The documents are read first to check their
+// version and to record the revisions, the writes only match them at that version.
+func (m *mongoTodoRepository) writeBatch(ctx context.Context, writes []*models.TodoWrite, results []*models.BulkResult) error {
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
+
+	docIDs := make([]primitive.ObjectID, len(writes))
+	var ids []primitive.ObjectID
+	for i, write := range writes {
+		if write.Op == models.BulkCreate {
+			continue
+		}
+
+		docID, err := primitive.ObjectIDFromHex(write.ID)
+		if err != nil {
+			results[i].Err = models.NewError(models.ErrInvalidID, "TodoRepository.BulkWrite", write.ID, err)
+			continue
+		}
+		docIDs[i] = docID
+		ids = append(ids, docID)
+	}
+
+	current, err := m.findByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	timeNow := utils.GetTimeNow()
+	var (
+		writeModels []mongo.WriteModel
+		pending     []bulkModel
+	)
+	for i, write := range writes {
+		if results[i].Err != nil {
+			continue
+		}
+
+		if write.Op == models.BulkCreate {
+			todo := newTodo(write.Value, timeNow)
+			todo.ID = primitive.NewObjectID()
+			writeModels = append(writeModels, mongo.NewInsertOneModel().SetDocument(insertDocument(todo)))
+			pending = append(pending, bulkModel{index: i, action: models.ActionCreate, after: todo})
+			continue
+		}
+
+		before, ok := current[docIDs[i]]
+		if !ok {
+			results[i].Err = models.NewError(models.ErrNotFound, "TodoRepository.BulkWrite", write.ID, nil)
+			continue
+		}
+		if write.Version > 0 && before.Version != write.Version {
+			results[i].Err = models.NewError(models.ErrPreconditionFailed, "TodoRepository.BulkWrite", write.ID, nil)
+			continue
+		}
+
+		model := bulkModel{index: i, before: before}
+		update := mongo.NewUpdateOneModel().SetFilter(versionFilter(docIDs[i], before.Version))
+		if write.Op == models.BulkUpdate {
+			model.action, model.after = models.ActionUpdate, updated(before, write.Value, timeNow)
+			update.SetUpdate(updateDocument(write.Value, timeNow))
+		} else {
+			model.action, model.after = models.ActionDelete, deleted(before, timeNow)
+			update.SetUpdate(deleteDocument(timeNow))
+		}
+		writeModels = append(writeModels, update)
+		pending = append(pending, model)
+	}
+
+	if len(writeModels) == 0 {
+		return nil
+	}
+
+	failed := map[int]error{}
+	res, err := collection.BulkWrite(ctx, writeModels, options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = writeErr
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// The version changed between the read and the write, only the documents tell which
+	var updates int64
+	for k, model := range pending {
+		if _, ok := failed[k]; !ok && model.before != nil {
+			updates++
+		}
+	}
+	if res == nil || res.MatchedCount < updates {
+		if err := m.checkWritten(ctx, pending, failed); err != nil {
+			return err
+		}
+	}
+
+	var revisions, events []interface{}
+	for k, model := range pending {
+		result := results[model.index]
+		if err, ok := failed[k]; ok {
+			result.Err = err
+			continue
+		}
+
+		revision, err := newRevision(ctx, model.action, model.before, model.after)
+		if err != nil {
+			return err
+		}
+		revisions = append(revisions, revision)
+		events = append(events, newOutboxEvent(ctx, model.action, model.before, model.after))
+
+		result.ID = model.after.ID.Hex()
+		if model.action != models.ActionDelete {
+			result.Todo = model.after
+		}
+	}
+
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	db := m.client.Database(os.Getenv("DB_NAME"))
+	if _, err := db.Collection("todo_revision").InsertMany(ctx, revisions); err != nil {
 		return err
 	}
+	_, err = db.Collection("todo_outbox").InsertMany(ctx, events)
 
-	if result.DeletedCount <= 0 {
-		return errors.New("not found")
+	return err
+}
+
+// findByIds - live todos by id, with their defaults
+func (m *mongoTodoRepository) findByIds(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*models.Todo, error) {
+	results := map[primitive.ObjectID]*models.Todo{}
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
+	cur, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "deletedAt": nil})
+	if err != nil {
+		return nil, err
+	}
+
+	var todos []*models.Todo
+	if err := cur.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+	for _, todo := range todos {
+		results[todo.ID] = withDefaults(todo)
+	}
+
+	return results, nil
+}
+
+// checkWritten - fail the updates of pending that didn't match their document with ErrPreconditionFailed, the
+// documents written by the bulk are at the version and the time of the bulk
+func (m *mongoTodoRepository) checkWritten(ctx context.Context, pending []bulkModel, failed map[int]error) error {
+	var ids []primitive.ObjectID
+	for _, model := range pending {
+		if model.before != nil {
+			ids = append(ids, model.before.ID)
+		}
+	}
+
+	// Deleted todos are in the trash by now
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo")
+	cur, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+
+	var todos []*models.Todo
+	if err := cur.All(ctx, &todos); err != nil {
+		return err
+	}
+	written := map[primitive.ObjectID]*models.Todo{}
+	for _, todo := range todos {
+		written[todo.ID] = todo
+	}
+
+	for k, model := range pending {
+		if _, ok := failed[k]; ok || model.before == nil {
+			continue
+		}
+
+		todo, ok := written[model.before.ID]
+		if !ok || todo.Version != model.after.Version || !todo.UpdatedAt.Equal(model.after.UpdatedAt.Truncate(time.Millisecond)) {
+			failed[k] = models.NewError(models.ErrPreconditionFailed, "TodoRepository.BulkWrite", model.before.ID.Hex(), nil)
+		}
+	}
+
+	return nil
+}
+
+// ClaimEvents - postpone the available events one by one, each claim is atomic
+func (m *mongoTodoRepository) ClaimEvents(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Event, error) {
+	ctx, span := startSpan(ctx, "ClaimEvents", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo_outbox")
+
+	results := []*models.Event{}
+	for len(results) < limit {
+		event := &outboxEvent{}
+		err := collection.FindOneAndUpdate(ctx,
+			bson.M{"availableAt": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"availableAt": until}},
+			options.FindOneAndUpdate().SetSort(bson.D{{Key: "availableAt", Value: 1}, {Key: "_id", Value: 1}}),
+		).Decode(event)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		results = append(results, &event.Event)
+	}
+
+	span.SetAttributes(attribute.Int("outbox.event.claimed", len(results)))
+
+	return results, nil
+}
+
+// DeleteEvents - delete the events by id from the outbox
+func (m *mongoTodoRepository) DeleteEvents(ctx context.Context, ids []primitive.ObjectID) error {
+	ctx, span := startSpan(ctx, "DeleteEvents", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	collection := m.client.Database(os.Getenv("DB_NAME")).Collection("todo_outbox")
+	if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
 	}
 
 	return nil
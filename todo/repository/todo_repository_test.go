@@ -0,0 +1,86 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/todo/repository/repositorytest"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// connectMongo - connect to TEST_DB_URL, skip when it is not set
+func connectMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+	require.NoError(t, err)
+	require.NoError(t, client.Ping(ctx, nil))
+	t.Cleanup(func() {
+		client.Disconnect(context.Background())
+	})
+
+	return client
+}
+
+// useMongoDatabase - give a subtest its own migrated database, the repositories read DB_NAME on each call
+func useMongoDatabase(t *testing.T, client *mongo.Client, databases *int) {
+	t.Helper()
+
+	*databases++
+	name := fmt.Sprintf("todo_conformance_%d_%d", time.Now().UnixNano(), *databases)
+	t.Setenv("DB_NAME", name)
+	t.Cleanup(func() {
+		client.Database(name).Drop(context.Background())
+	})
+	require.NoError(t, repository.MigrateMongo(context.Background(), client))
+}
+
+// TestMongoTodoRepository - runs against a real server, e.g. TEST_DB_URL=mongodb://localhost:27017/?replicaSet=rs0.
+// It must be a replica set, atomic bulk writes need transactions.
+func TestMongoTodoRepository(t *testing.T) {
+	client := connectMongo(t)
+
+	databases := 0
+	repositorytest.Run(t, func(t *testing.T) repository.TodoRepository {
+		useMongoDatabase(t, client, &databases)
+
+		return repository.NewMongoTodoRepository(client)
+	})
+}
+
+func TestMongoIdempotencyRepository(t *testing.T) {
+	client := connectMongo(t)
+
+	databases := 0
+	repositorytest.RunIdempotency(t, func(t *testing.T) repository.IdempotencyRepository {
+		useMongoDatabase(t, client, &databases)
+
+		return repository.NewMongoIdempotencyRepository(client)
+	})
+}
+
+func TestMongoWebhookRepository(t *testing.T) {
+	client := connectMongo(t)
+
+	databases := 0
+	repositorytest.RunWebhook(t, func(t *testing.T) repository.WebhookRepository {
+		useMongoDatabase(t, client, &databases)
+
+		return repository.NewMongoWebhookRepository(client)
+	})
+}
@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/utils"
+)
+
+// WebhookRepository represent the webhook repository contract, webhooks are listed oldest first and
+// deliveries newest first
+type WebhookRepository interface {
+	FindAll(ctx context.Context) ([]*models.Webhook, error)
+	FindById(ctx context.Context, id string) (*models.Webhook, error)
+	Store(ctx context.Context, value *models.Webhook) (*models.Webhook, error)
+	Update(ctx context.Context, id string, value *models.Webhook) (*models.Webhook, error)
+	// Delete deletes webhook and its deliveries
+	Delete(ctx context.Context, id string) error
+	StoreDeliveries(ctx context.Context, deliveries []*models.Delivery) error
+	FindDeliveries(ctx context.Context, filter models.DeliveryFilter, limit int, offset int) ([]*models.Delivery, error)
+	FindDeliveryById(ctx context.Context, id string) (*models.Delivery, error)
+	// ClaimDeliveries finds up to limit pending deliveries due at now and postpones them to until, so other
+	// workers skip them while they are sent
+	ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Delivery, error)
+	// UpdateDelivery writes the status, the attempts and the next attempt of delivery when its stored next
+	// attempt is still claim, models.ErrConflict when another worker changed it since
+	UpdateDelivery(ctx context.Context, delivery *models.Delivery, claim *time.Time) error
+}
+
+type mongoWebhookRepository struct {
+	client *mongo.Client
+}
+
+// startWebhookSpan - start a WebhookRepository span tagged with the database system
+func startWebhookSpan(ctx context.Context, name string, system attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer("WebhookRepository").Start(ctx, "WebhookRepository."+name, trace.WithAttributes(system))
+}
+
+// NewMongoWebhookRepository will create an object that represent the WebhookRepository interface
+func NewMongoWebhookRepository(client *mongo.Client) WebhookRepository {
+	return &mongoWebhookRepository{
+		client: client,
+	}
+}
+
+func (m *mongoWebhookRepository) webhooks() *mongo.Collection {
+	return m.client.Database(os.Getenv("DB_NAME")).Collection("webhook")
+}
+
+func (m *mongoWebhookRepository) deliveries() *mongo.Collection {
+	return m.client.Database(os.Getenv("DB_NAME")).Collection("webhook_delivery")
+}
+
+// FindAll - find every webhook
+func (m *mongoWebhookRepository) FindAll(ctx context.Context) ([]*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "FindAll", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	cursor, err := m.webhooks().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	results := []*models.Webhook{}
+	if err := cursor.All(ctx, &results); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// FindById - find webhook by id
+func (m *mongoWebhookRepository) FindById(ctx context.Context, id string) (*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "FindById", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "WebhookRepository.FindById", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	webhook := &models.Webhook{}
+	err = m.webhooks().FindOne(ctx, bson.M{"_id": docID}).Decode(webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = models.NewError(models.ErrNotFound, "WebhookRepository.FindById", id, err)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// Store - store webhook
+func (m *mongoWebhookRepository) Store(ctx context.Context, value *models.Webhook) (*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "Store", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	webhook := *value
+	webhook.ID = primitive.NewObjectID()
+	webhook.CreatedAt = utils.GetTimeNow()
+	webhook.UpdatedAt = webhook.CreatedAt
+
+	if _, err := m.webhooks().InsertOne(ctx, &webhook); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// Update - update the url, events and secret of webhook by id
+func (m *mongoWebhookRepository) Update(ctx context.Context, id string, value *models.Webhook) (*models.Webhook, error) {
+	ctx, span := startWebhookSpan(ctx, "Update", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "WebhookRepository.Update", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	webhook := &models.Webhook{}
+	err = m.webhooks().FindOneAndUpdate(ctx,
+		bson.M{"_id": docID},
+		bson.M{"$set": bson.M{
+			"url":       value.URL,
+			"events":    value.Events,
+			"secret":    value.Secret,
+			"updatedAt": utils.GetTimeNow(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = models.NewError(models.ErrNotFound, "WebhookRepository.Update", id, err)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// Delete - delete webhook by id and its deliveries
+func (m *mongoWebhookRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := startWebhookSpan(ctx, "Delete", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "WebhookRepository.Delete", id, err)
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	res, err := m.webhooks().DeleteOne(ctx, bson.M{"_id": docID})
+	if err == nil && res.DeletedCount == 0 {
+		err = models.NewError(models.ErrNotFound, "WebhookRepository.Delete", id, nil)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	if _, err := m.deliveries().DeleteMany(ctx, bson.M{"webhookId": docID}); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// StoreDeliveries - store deliveries, their ids are set
+func (m *mongoWebhookRepository) StoreDeliveries(ctx context.Context, deliveries []*models.Delivery) error {
+	ctx, span := startWebhookSpan(ctx, "StoreDeliveries", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(deliveries))
+	for i, delivery := range deliveries {
+		delivery.ID = primitive.NewObjectID()
+		documents[i] = delivery
+	}
+
+	if _, err := m.deliveries().InsertMany(ctx, documents); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// deliveryFilter - Mongo filter of a delivery list
+func deliveryFilter(filter models.DeliveryFilter) bson.M {
+	conditions := bson.M{}
+	if !filter.WebhookID.IsZero() {
+		conditions["webhookId"] = filter.WebhookID
+	}
+	if filter.Status != "" {
+		conditions["status"] = filter.Status
+	}
+
+	return conditions
+}
+
+// FindDeliveries - find the deliveries matching filter, newest first
+func (m *mongoWebhookRepository) FindDeliveries(ctx context.Context, filter models.DeliveryFilter, limit int, offset int) ([]*models.Delivery, error) {
+	ctx, span := startWebhookSpan(ctx, "FindDeliveries", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+	cursor, err := m.deliveries().Find(ctx, deliveryFilter(filter), opts)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	results := []*models.Delivery{}
+	if err := cursor.All(ctx, &results); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// FindDeliveryById - find delivery by id
+func (m *mongoWebhookRepository) FindDeliveryById(ctx context.Context, id string) (*models.Delivery, error) {
+	ctx, span := startWebhookSpan(ctx, "FindDeliveryById", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		err = models.NewError(models.ErrInvalidID, "WebhookRepository.FindDeliveryById", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	delivery := &models.Delivery{}
+	err = m.deliveries().FindOne(ctx, bson.M{"_id": docID}).Decode(delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = models.NewError(models.ErrNotFound, "WebhookRepository.FindDeliveryById", id, err)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// ClaimDeliveries - postpone the due deliveries one by one, each claim is atomic
+func (m *mongoWebhookRepository) ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]*models.Delivery, error) {
+	ctx, span := startWebhookSpan(ctx, "ClaimDeliveries", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	results := []*models.Delivery{}
+	for len(results) < limit {
+		delivery := &models.Delivery{}
+		err := m.deliveries().FindOneAndUpdate(ctx,
+			bson.M{"status": models.DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"nextAttemptAt": until}},
+			options.FindOneAndUpdate().
+				SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+				SetReturnDocument(options.After),
+		).Decode(delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+
+		results = append(results, delivery)
+	}
+
+	span.SetAttributes(attribute.Int("webhook.delivery.claimed", len(results)))
+
+	return results, nil
+}
+
+// UpdateDelivery - write the status, the attempts and the next attempt of delivery, the next attempt being
+// claim is the condition of the update
+func (m *mongoWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.Delivery, claim *time.Time) error {
+	ctx, span := startWebhookSpan(ctx, "UpdateDelivery", semconv.DBSystemMongoDB)
+	defer span.End()
+
+	// A nil claim matches the deliveries without a next attempt
+	res, err := m.deliveries().UpdateOne(ctx,
+		bson.M{"_id": delivery.ID, "nextAttemptAt": claim},
+		bson.M{"$set": bson.M{
+			"status":        delivery.Status,
+			"attempts":      delivery.Attempts,
+			"nextAttemptAt": delivery.NextAttemptAt,
+			"updatedAt":     delivery.UpdatedAt,
+		}},
+	)
+	if err == nil && res.MatchedCount == 0 {
+		err = m.lostClaim(ctx, delivery.ID)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// lostClaim - error of an update of delivery by id which matched nothing, the delivery is gone or another
+// worker changed it
+func (m *mongoWebhookRepository) lostClaim(ctx context.Context, id primitive.ObjectID) error {
+	count, err := m.deliveries().CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.NewError(models.ErrNotFound, "WebhookRepository.UpdateDelivery", id.Hex(), nil)
+	}
+
+	return models.NewError(models.ErrConflict, "WebhookRepository.UpdateDelivery", id.Hex(), errors.New("the delivery claim was lost"))
+}
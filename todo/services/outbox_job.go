@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go-distributed-tracing/pkg/log"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"go.opentelemetry.io/otel"
+)
+
+// OutboxConfig - configuration of the relay of the todo outbox
+type OutboxConfig struct {
+	// PollInterval is the time between two looks for new events, zero disables the job
+	PollInterval time.Duration
+	// RetryDelay is how long an event is claimed, a failed event is published again after it
+	RetryDelay time.Duration
+	// BatchSize is how many events are claimed at once
+	BatchSize int
+}
+
+// defaultOutboxBatchSize - events claimed at once when the batch size isn't set
+const defaultOutboxBatchSize = 100
+
+// OutboxConfigFromEnv - read outbox relay configuration from environment
+func OutboxConfigFromEnv() (OutboxConfig, error) {
+	cfg := OutboxConfig{
+		PollInterval: time.Second,
+		RetryDelay:   30 * time.Second,
+		BatchSize:    defaultOutboxBatchSize,
+	}
+
+	var err error
+	if value := os.Getenv("OUTBOX_POLL_INTERVAL"); value != "" {
+		if cfg.PollInterval, err = time.ParseDuration(value); err != nil {
+			return cfg, fmt.Errorf("invalid OUTBOX_POLL_INTERVAL %q: %w", value, err)
+		}
+	}
+	if value := os.Getenv("OUTBOX_RETRY_DELAY"); value != "" {
+		if cfg.RetryDelay, err = time.ParseDuration(value); err != nil {
+			return cfg, fmt.Errorf("invalid OUTBOX_RETRY_DELAY %q: %w", value, err)
+		}
+		if cfg.RetryDelay <= 0 {
+			return cfg, fmt.Errorf("invalid OUTBOX_RETRY_DELAY %q: must be positive", value)
+		}
+	}
+	if value := os.Getenv("OUTBOX_BATCH_SIZE"); value != "" {
+		if cfg.BatchSize, err = strconv.Atoi(value); err != nil || cfg.BatchSize <= 0 {
+			return cfg, fmt.Errorf("invalid OUTBOX_BATCH_SIZE %q: must be a positive integer", value)
+		}
+	}
+
+	return cfg, nil
+}
+
+// RunOutboxJob - relay the events of the todo outbox every poll interval, until ctx is done. It returns right
+// away when the poll interval is zero, a zero batch size falls back to defaultOutboxBatchSize.
+func RunOutboxJob(ctx context.Context, relay OutboxRelay, cfg OutboxConfig) {
+	if cfg.PollInterval <= 0 {
+		return
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultOutboxBatchSize
+	}
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// A full batch means more events may be waiting already
+		claimed := relayEvents(ctx, relay)
+		for claimed >= cfg.BatchSize && ctx.Err() == nil {
+			claimed = relayEvents(ctx, relay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayEvents - a single run of the outbox job, traced on its own. It returns how many events were claimed.
+func relayEvents(ctx context.Context, relay OutboxRelay) int {
+	ctx, span := otel.Tracer("OutboxJob").Start(ctx, "OutboxJob.Run")
+	defer span.End()
+
+	claimed, err := relay.Relay(ctx)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		log.FromContext(ctx).WithError(err).Error("relaying outbox events")
+		return 0
+	}
+
+	return claimed
+}
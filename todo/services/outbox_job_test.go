@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockServices "go-distributed-tracing/todo/mocks/services"
+	"go-distributed-tracing/todo/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOutboxConfigFromEnv(t *testing.T) {
+	clear := func() {
+		t.Setenv("OUTBOX_POLL_INTERVAL", "")
+		t.Setenv("OUTBOX_RETRY_DELAY", "")
+		t.Setenv("OUTBOX_BATCH_SIZE", "")
+	}
+
+	t.Run("success with defaults", func(t *testing.T) {
+		clear()
+
+		cfg, err := services.OutboxConfigFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, services.OutboxConfig{PollInterval: time.Second, RetryDelay: 30 * time.Second, BatchSize: 100}, cfg)
+	})
+
+	t.Run("success with values", func(t *testing.T) {
+		clear()
+		t.Setenv("OUTBOX_POLL_INTERVAL", "0")
+		t.Setenv("OUTBOX_RETRY_DELAY", "1m")
+		t.Setenv("OUTBOX_BATCH_SIZE", "20")
+
+		cfg, err := services.OutboxConfigFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, services.OutboxConfig{PollInterval: 0, RetryDelay: time.Minute, BatchSize: 20}, cfg)
+	})
+
+	t.Run("error when invalid", func(t *testing.T) {
+		for _, env := range [][2]string{
+			{"OUTBOX_POLL_INTERVAL", "often"},
+			{"OUTBOX_RETRY_DELAY", "0"},
+			{"OUTBOX_RETRY_DELAY", "later"},
+			{"OUTBOX_BATCH_SIZE", "0"},
+			{"OUTBOX_BATCH_SIZE", "many"},
+		} {
+			clear()
+			t.Setenv(env[0], env[1])
+
+			_, err := services.OutboxConfigFromEnv()
+			assert.Error(t, err, env[0])
+		}
+	})
+}
+
+func TestRunOutboxJob(t *testing.T) {
+	t.Run("relay again right away after a full batch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockRelay := new(mockServices.OutboxRelay)
+		mockRelay.On("Relay", mock.Anything).Return(10, nil).Twice()
+		mockRelay.On("Relay", mock.Anything).Return(0, nil).Once().Run(func(mock.Arguments) {
+			cancel()
+		})
+
+		done := make(chan struct{})
+		go func() {
+			services.RunOutboxJob(ctx, mockRelay, services.OutboxConfig{PollInterval: time.Hour, BatchSize: 10})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the outbox job didn't stop")
+		}
+		mockRelay.AssertNumberOfCalls(t, "Relay", 3)
+	})
+
+	t.Run("stop after a run when the batch size isn't set", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockRelay := new(mockServices.OutboxRelay)
+		mockRelay.On("Relay", mock.Anything).Return(0, nil).Once().Run(func(mock.Arguments) {
+			cancel()
+		})
+
+		done := make(chan struct{})
+		go func() {
+			services.RunOutboxJob(ctx, mockRelay, services.OutboxConfig{PollInterval: time.Hour})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the outbox job didn't stop")
+		}
+		mockRelay.AssertNumberOfCalls(t, "Relay", 1)
+	})
+
+	t.Run("success when disabled", func(t *testing.T) {
+		mockRelay := new(mockServices.OutboxRelay)
+
+		services.RunOutboxJob(context.Background(), mockRelay, services.OutboxConfig{})
+
+		mockRelay.AssertNotCalled(t, "Relay", mock.Anything)
+	})
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+
+	"go-distributed-tracing/pkg/log"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EventPublisher - publishes the events of the todo outbox. An event whose publication failed is published
+// again later, to every publisher, so publishers get an event at least once and maybe more.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+// OutboxRelay represent the relay of the todo outbox to the event publishers
+type OutboxRelay interface {
+	// Relay publishes the events available now, it returns how many were claimed
+	Relay(ctx context.Context) (int, error)
+}
+
+type outboxRelay struct {
+	todoRepo   repository.TodoRepository
+	cfg        OutboxConfig
+	publishers []EventPublisher
+}
+
+// NewOutboxRelay will create new an OutboxRelay object representation of OutboxRelay interface, publishers
+// get the events of the outbox of a
+func NewOutboxRelay(a repository.TodoRepository, cfg OutboxConfig, publishers ...EventPublisher) OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultOutboxBatchSize
+	}
+
+	return &outboxRelay{
+		todoRepo:   a,
+		cfg:        cfg,
+		publishers: publishers,
+	}
+}
+
+// Relay - publish the available events oldest first, and delete the published ones from the outbox. They are
+// claimed for the retry delay, a failed event is published again once its claim expired.
+func (a *outboxRelay) Relay(ctx context.Context) (int, error) {
+	ctx, span := otel.Tracer("OutboxRelay").Start(ctx, "OutboxRelay.Relay")
+	defer span.End()
+
+	now := utils.GetTimeNow()
+	events, err := a.todoRepo.ClaimEvents(ctx, now, now.Add(a.cfg.RetryDelay), a.cfg.BatchSize)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	// A failed event doesn't hold back the others
+	published := []primitive.ObjectID{}
+	for _, event := range events {
+		if err := a.publish(ctx, event); err == nil {
+			published = append(published, event.ID)
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("outbox.event.claimed", len(events)),
+		attribute.Int("outbox.event.published", len(published)),
+	)
+
+	// The events not deleted are published again, like failed ones
+	if err := a.todoRepo.DeleteEvents(ctx, published); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	return len(events), nil
+}
+
+// publish - hand event to every publisher, until one fails. The span continues the trace of the write of the
+// event and links to the relay.
+func (a *outboxRelay) publish(ctx context.Context, event *models.Event) error {
+	parent := propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": event.TraceParent})
+	parent, span := otel.Tracer("OutboxRelay").Start(parent, "OutboxRelay.Publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(
+			attribute.String("event.id", event.ID.Hex()),
+			attribute.String("event.type", string(event.Type)),
+			attribute.String("todo.id", event.TodoID.Hex()),
+		),
+	)
+	defer span.End()
+
+	for _, publisher := range a.publishers {
+		if err := publisher.Publish(parent, event); err != nil {
+			pkg_tracing.RecordError(span, err)
+			log.FromContext(parent).WithError(err).WithField("event.id", event.ID.Hex()).Warn("publishing outbox event failed")
+			return err
+		}
+	}
+
+	return nil
+}
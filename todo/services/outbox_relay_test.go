@@ -0,0 +1,128 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockRepositories "go-distributed-tracing/todo/mocks/repository"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/todo/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var outboxConfig = services.OutboxConfig{
+	PollInterval: time.Second,
+	RetryDelay:   time.Minute,
+	BatchSize:    10,
+}
+
+// eventRecorder - EventPublisher keeping the events it gets with their trace, failing the first fails ones
+type eventRecorder struct {
+	fails  int
+	events []*models.Event
+	traces []trace.TraceID
+}
+
+func (r *eventRecorder) Publish(ctx context.Context, event *models.Event) error {
+	if r.fails > 0 {
+		r.fails--
+		return errors.New("broker unavailable")
+	}
+
+	r.events = append(r.events, event)
+	r.traces = append(r.traces, trace.SpanContextFromContext(ctx).TraceID())
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	t.Run("success publishes to every publisher and empties the outbox", func(t *testing.T) {
+		repo := repository.NewMemoryTodoRepository()
+		first, second := &eventRecorder{}, &eventRecorder{}
+		relay := services.NewOutboxRelay(repo, outboxConfig, first, second)
+
+		todo, err := repo.Store(ctx, &models.Todo{Title: "Buy milk"})
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(ctx, todo.ID.Hex(), 0))
+
+		claimed, err := relay.Relay(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, claimed)
+
+		for _, recorder := range []*eventRecorder{first, second} {
+			require.Len(t, recorder.events, 2)
+			assert.Equal(t, models.EventTodoCreated, recorder.events[0].Type)
+			assert.Equal(t, models.EventTodoDeleted, recorder.events[1].Type)
+			// The publishers continue the trace of the write
+			assert.Equal(t, []trace.TraceID{traceID, traceID}, recorder.traces)
+		}
+
+		claimed, err = relay.Relay(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, claimed)
+	})
+
+	t.Run("failed events are published again after the retry delay", func(t *testing.T) {
+		repo := repository.NewMemoryTodoRepository()
+		recorder := &eventRecorder{fails: 1}
+		relay := services.NewOutboxRelay(repo, services.OutboxConfig{RetryDelay: time.Millisecond, BatchSize: 10}, recorder)
+
+		_, err := repo.Store(ctx, &models.Todo{Title: "Buy milk"})
+		require.NoError(t, err)
+		_, err = repo.Store(ctx, &models.Todo{Title: "Walk dog"})
+		require.NoError(t, err)
+
+		claimed, err := relay.Relay(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, claimed)
+		require.Len(t, recorder.events, 1, "a failed event doesn't hold back the others")
+		assert.Equal(t, "Walk dog", recorder.events[0].Todo.Title)
+
+		time.Sleep(5 * time.Millisecond)
+
+		claimed, err = relay.Relay(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, claimed)
+		require.Len(t, recorder.events, 2)
+		assert.Equal(t, "Buy milk", recorder.events[1].Todo.Title)
+	})
+
+	t.Run("error when claiming fails", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		mockRepository.On("ClaimEvents", mock.Anything, mock.Anything, mock.Anything, 10).Return(nil, ErrDefault)
+		relay := services.NewOutboxRelay(mockRepository, outboxConfig, &eventRecorder{})
+
+		_, err := relay.Relay(context.Background())
+
+		assert.ErrorIs(t, err, ErrDefault)
+		mockRepository.AssertNotCalled(t, "DeleteEvents", mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when deleting fails", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		event := models.NewEvent(ctx, models.EventTodoDeleted, primitive.NewObjectID(), nil, time.Now())
+		mockRepository.On("ClaimEvents", mock.Anything, mock.Anything, mock.Anything, 10).Return([]*models.Event{event}, nil)
+		mockRepository.On("DeleteEvents", mock.Anything, mock.Anything).Return(ErrDefault)
+		recorder := &eventRecorder{}
+		relay := services.NewOutboxRelay(mockRepository, outboxConfig, recorder)
+
+		_, err := relay.Relay(context.Background())
+
+		assert.ErrorIs(t, err, ErrDefault)
+		assert.Len(t, recorder.events, 1)
+	})
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go-distributed-tracing/pkg/log"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/utils"
+
+	"go.opentelemetry.io/otel"
+)
+
+// PurgeConfig - configuration of the job purging the trash
+type PurgeConfig struct {
+	// Retention is how long todos stay in the trash, zero disables the job
+	Retention time.Duration
+	// Interval is the time between two purges
+	Interval time.Duration
+}
+
+// PurgeConfigFromEnv - read trash purge configuration from environment
+func PurgeConfigFromEnv() (PurgeConfig, error) {
+	cfg := PurgeConfig{
+		Retention: 30 * 24 * time.Hour,
+		Interval:  time.Hour,
+	}
+
+	var err error
+	if value := os.Getenv("TRASH_RETENTION"); value != "" {
+		if cfg.Retention, err = time.ParseDuration(value); err != nil {
+			return cfg, fmt.Errorf("invalid TRASH_RETENTION %q: %w", value, err)
+		}
+	}
+	if value := os.Getenv("TRASH_PURGE_INTERVAL"); value != "" {
+		if cfg.Interval, err = time.ParseDuration(value); err != nil {
+			return cfg, fmt.Errorf("invalid TRASH_PURGE_INTERVAL %q: %w", value, err)
+		}
+		if cfg.Interval <= 0 {
+			return cfg, fmt.Errorf("invalid TRASH_PURGE_INTERVAL %q: must be positive", value)
+		}
+	}
+
+	return cfg, nil
+}
+
+// RunPurgeJob - purge the todos older than the retention from the trash every interval, until ctx is done.
+// It returns right away when the retention is zero.
+func RunPurgeJob(ctx context.Context, service TodoService, cfg PurgeConfig) {
+	if cfg.Retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		purge(ctx, service, cfg.Retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge - a single run of the purge job, traced on its own
+func purge(ctx context.Context, service TodoService, retention time.Duration) {
+	ctx, span := otel.Tracer("PurgeJob").Start(ctx, "PurgeJob.Run")
+	defer span.End()
+
+	purged, err := service.Purge(ctx, utils.GetTimeNow().Add(-retention))
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		log.FromContext(ctx).WithError(err).Error("purging the trash")
+		return
+	}
+
+	if purged > 0 {
+		log.FromContext(ctx).WithField("todo.purged", purged).Info("trash purged")
+	}
+}
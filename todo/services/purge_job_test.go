@@ -0,0 +1,83 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockServices "go-distributed-tracing/todo/mocks/services"
+	"go-distributed-tracing/todo/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPurgeConfigFromEnv(t *testing.T) {
+	t.Run("success with defaults", func(t *testing.T) {
+		t.Setenv("TRASH_RETENTION", "")
+		t.Setenv("TRASH_PURGE_INTERVAL", "")
+
+		cfg, err := services.PurgeConfigFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, services.PurgeConfig{Retention: 720 * time.Hour, Interval: time.Hour}, cfg)
+	})
+
+	t.Run("success with values", func(t *testing.T) {
+		t.Setenv("TRASH_RETENTION", "0")
+		t.Setenv("TRASH_PURGE_INTERVAL", "10m")
+
+		cfg, err := services.PurgeConfigFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, services.PurgeConfig{Retention: 0, Interval: 10 * time.Minute}, cfg)
+	})
+
+	t.Run("error when invalid", func(t *testing.T) {
+		for _, env := range [][2]string{{"TRASH_RETENTION", "month"}, {"TRASH_PURGE_INTERVAL", "0"}} {
+			t.Setenv("TRASH_RETENTION", "")
+			t.Setenv("TRASH_PURGE_INTERVAL", "")
+			t.Setenv(env[0], env[1])
+
+			_, err := services.PurgeConfigFromEnv()
+			assert.Error(t, err, env[0])
+		}
+	})
+}
+
+func TestRunPurgeJob(t *testing.T) {
+	t.Run("success when purge every interval", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calls := 0
+		mockService := new(mockServices.TodoService)
+		mockService.On("Purge", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+			return time.Since(before) >= time.Hour
+		})).Return(1, nil).Run(func(mock.Arguments) {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+		})
+
+		done := make(chan struct{})
+		go func() {
+			services.RunPurgeJob(ctx, mockService, services.PurgeConfig{Retention: time.Hour, Interval: time.Millisecond})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the purge job didn't stop")
+		}
+		mockService.AssertNumberOfCalls(t, "Purge", 2)
+	})
+
+	t.Run("success when disabled", func(t *testing.T) {
+		mockService := new(mockServices.TodoService)
+
+		services.RunPurgeJob(context.Background(), mockService, services.PurgeConfig{Interval: time.Hour})
+
+		mockService.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+	})
+}
@@ -2,19 +2,43 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"go-distributed-tracing/pkg/jsonpatch"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
 	"go-distributed-tracing/todo/models"
 	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/utils"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // TodoService represent the todo service
 type TodoService interface {
-	GetAll(ctx context.Context, keyword string, limit int, offset int) ([]*models.Todo, int, error)
+	GetAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, page models.Pagination) (*models.TodoPage, error)
 	GetByID(ctx context.Context, id string) (*models.Todo, error)
 	Create(ctx context.Context, value *models.Todo) (*models.Todo, error)
-	Update(ctx context.Context, id string, value *models.Todo) (*models.Todo, error)
-	Delete(ctx context.Context, id string) error
+	// Update, Patch and Delete fail with models.ErrPreconditionFailed unless the todo is at version,
+	// 0 matches any version
+	Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error)
+	Patch(ctx context.Context, id string, patch *models.TodoPatch, version int64) (*models.Todo, error)
+	// Complete and Reopen check version like Update
+	Complete(ctx context.Context, id string, version int64) (*models.Todo, error)
+	Reopen(ctx context.Context, id string, version int64) (*models.Todo, error)
+	// Delete moves todo to the trash, GetAll lists it with TodoFilter.Deleted until it's restored or purged
+	Delete(ctx context.Context, id string, version int64) error
+	Restore(ctx context.Context, id string) (*models.Todo, error)
+	// Purge permanently deletes the todos moved to the trash before before
+	Purge(ctx context.Context, before time.Time) (int, error)
+	// History lists the revisions of a todo oldest first, also while it is in the trash
+	History(ctx context.Context, id string) ([]*models.Revision, error)
+	// Revert sets the fields of a todo back to the ones of revision rev, recorded as a new revision
+	Revert(ctx context.Context, id string, rev int64, version int64) (*models.Todo, error)
+	// Bulk creates, updates and deletes todos with a result per operation, see models.BulkRequest
+	Bulk(ctx context.Context, operations []*models.BulkOperation, atomic bool) ([]*models.BulkResult, error)
 }
 
 type todoService struct {
@@ -28,23 +52,121 @@ func NewTodoService(a repository.TodoRepository) TodoService {
 	}
 }
 
-// GetAll - get all todo service
-func (a *todoService) GetAll(ctx context.Context, keyword string, limit int, offset int) ([]*models.Todo, int, error) {
+// checkVersion - ErrPreconditionFailed when version is set and current is at another one
+func checkVersion(op string, id string, current *models.Todo, version int64) error {
+	if version > 0 && current.Version != version {
+		return models.NewError(models.ErrPreconditionFailed, op, id, nil)
+	}
+
+	return nil
+}
+
+// newTodo - todo to store for value. New todos start as todo, any status reachable from there can be requested.
+func newTodo(op string, value *models.Todo) (*models.Todo, error) {
+	todo := &models.Todo{
+		Title:       value.Title,
+		Description: value.Description,
+		Status:      models.StatusTodo,
+		DueAt:       value.DueAt,
+		Priority:    value.Priority,
+		Tags:        models.NormalizeTags(value.Tags),
+	}
+
+	if value.Status != "" {
+		if err := todo.Transition(value.Status, utils.GetTimeNow()); err != nil {
+			return nil, models.NewError(models.ErrInvalidTransition, op, "", err)
+		}
+	}
+
+	return todo, nil
+}
+
+// updateTodo - current updated with value, the current status decides which status the todo may move to
+func updateTodo(op string, id string, current *models.Todo, value *models.Todo) (*models.Todo, error) {
+	next := *current
+	next.Title = value.Title
+	next.Description = value.Description
+	next.DueAt = value.DueAt
+	next.Priority = value.Priority
+	next.Tags = models.NormalizeTags(value.Tags)
+	if value.Status != "" {
+		if err := next.Transition(value.Status, utils.GetTimeNow()); err != nil {
+			return nil, models.NewError(models.ErrInvalidTransition, op, id, err)
+		}
+	}
+
+	return &next, nil
+}
+
+// GetAll - get a page of todo service. The page is read backwards from a Before cursor, and one todo more
+// than the limit is read to know whether there is a next page.
+func (a *todoService) GetAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, page models.Pagination) (*models.TodoPage, error) {
 	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.GetAll")
 	defer span.End()
 
-	res, err := a.todoRepo.FindAll(ctx, keyword, limit, offset)
+	backward := page.Cursor != nil && page.Cursor.Before
+	order := sort.WithID()
+	if backward {
+		order = order.Reverse()
+	}
+
+	find := filter
+	offset := page.Offset
+	if page.Cursor != nil {
+		find.After = &models.Keyset{Sort: order, Key: page.Cursor.Key}
+		offset = 0
+	}
+
+	limit := page.Limit
+	if limit > 0 {
+		limit++
+	}
+
+	res, err := a.todoRepo.FindAll(ctx, find, order, limit, offset)
 	if err != nil {
-		return nil, 0, err
+		pkg_tracing.RecordError(span, err)
+		return nil, err
 	}
 
-	// Count total
-	total, err := a.todoRepo.CountFindAll(ctx, keyword)
+	more := page.Limit > 0 && len(res) > page.Limit
+	if more {
+		res = res[:page.Limit]
+	}
+	if backward {
+		for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+			res[i], res[j] = res[j], res[i]
+		}
+	}
+
+	result := &models.TodoPage{Todos: res}
+	if len(res) > 0 {
+		// Going backwards there is a next page, the one the cursor came from
+		hasNext, hasPrev := more, offset > 0 || page.Cursor != nil
+		if backward {
+			hasNext, hasPrev = true, more
+		}
+
+		if hasNext {
+			result.Next = &models.TodoCursor{Sort: sort, Key: res[len(res)-1]}
+		}
+		if hasPrev {
+			result.Prev = &models.TodoCursor{Sort: sort, Before: true, Key: res[0]}
+		}
+	}
+
+	if page.SkipTotal {
+		return result, nil
+	}
+
+	// Count total, the whole list rather than what is after the cursor
+	total, err := a.todoRepo.CountFindAll(ctx, filter)
 	if err != nil {
-		return nil, 0, err
+		pkg_tracing.RecordError(span, err)
+		return nil, err
 	}
+	result.Total = &total
 
-	return res, total, nil
+	return result, nil
 }
 
 // GetByID - get todo by id service
@@ -54,6 +176,7 @@ func (a *todoService) GetByID(ctx context.Context, id string) (*models.Todo, err
 
 	res, err := a.todoRepo.FindById(ctx, id)
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return nil, err
 	}
 
@@ -65,47 +188,429 @@ func (a *todoService) Create(ctx context.Context, value *models.Todo) (*models.T
 	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Create")
 	defer span.End()
 
-	res, err := a.todoRepo.Store(ctx, &models.Todo{
-		Title:       value.Title,
-		Description: value.Description,
-	})
+	todo, err := newTodo("TodoService.Create", value)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	res, err := a.todoRepo.Store(ctx, todo)
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return nil, err
 	}
 
 	return res, nil
 }
 
-// Update - update todo by id service
-func (a *todoService) Update(ctx context.Context, id string, value *models.Todo) (*models.Todo, error) {
+// Update - update todo by id service, returns the updated todo
+func (a *todoService) Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
 	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Update")
 	defer span.End()
 
-	_, err := a.todoRepo.CountFindByID(ctx, id)
+	// The current status decides which status the todo may move to
+	current, err := a.todoRepo.FindById(ctx, id)
+	if err == nil {
+		err = checkVersion("TodoService.Update", id, current, version)
+	}
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return nil, err
 	}
 
-	_, err = a.todoRepo.Update(ctx, id, &models.Todo{
-		Title:       value.Title,
-		Description: value.Description,
-	})
+	next, err := updateTodo("TodoService.Update", id, current, value)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// Only written at the version the status was checked at, also without If-Match
+	res, err := a.todoRepo.Update(ctx, id, next, current.Version)
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return nil, err
 	}
 
-	return nil, nil
+	return res, nil
 }
 
-// Delete - delete todo by id service
-func (a *todoService) Delete(ctx context.Context, id string) error {
+// Patch - apply a merge patch or a JSON patch to todo by id service. The patched todo is validated
+// like a TodoRequest and only the fields that changed are written.
+func (a *todoService) Patch(ctx context.Context, id string, patch *models.TodoPatch, version int64) (*models.Todo, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Patch")
+	defer span.End()
+
+	current, err := a.todoRepo.FindById(ctx, id)
+	if err == nil {
+		err = checkVersion("TodoService.Patch", id, current, version)
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	value, err := patch.Apply(current)
+	if err != nil {
+		// A failed test operation is a precondition on the current todo
+		kind := models.ErrValidation
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			kind = models.ErrConflict
+		}
+
+		err = models.NewError(kind, "TodoService.Patch", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	if err := utils.ValidateStruct(value); err != nil {
+		err = models.NewError(models.ErrValidation, "TodoService.Patch", id, err)
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	next := *current
+	next.Title = value.Title
+	next.Description = value.Description
+	next.DueAt = value.DueAt
+	next.Priority = value.Priority
+	next.Tags = models.NormalizeTags(value.Tags)
+	if value.Status != "" {
+		if err := next.Transition(value.Status, utils.GetTimeNow()); err != nil {
+			err = models.NewError(models.ErrInvalidTransition, "TodoService.Patch", id, err)
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
+
+	fields := models.ChangedFields(current, &next)
+	if len(fields) == 0 {
+		return current, nil
+	}
+
+	// Only written at the version the patch was applied to, also without If-Match
+	res, err := a.todoRepo.Patch(ctx, id, &next, fields, current.Version)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Complete - mark todo by id as done service
+func (a *todoService) Complete(ctx context.Context, id string, version int64) (*models.Todo, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Complete")
+	defer span.End()
+
+	res, err := a.transition(ctx, "TodoService.Complete", id, models.StatusDone, version)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Reopen - move todo by id back to todo service
+func (a *todoService) Reopen(ctx context.Context, id string, version int64) (*models.Todo, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Reopen")
+	defer span.End()
+
+	res, err := a.transition(ctx, "TodoService.Reopen", id, models.StatusTodo, version)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// transition - move todo by id to status, if the workflow allows it and todo is at version
+func (a *todoService) transition(ctx context.Context, op string, id string, status models.TodoStatus, version int64) (*models.Todo, error) {
+	current, err := a.todoRepo.FindById(ctx, id)
+	if err == nil {
+		err = checkVersion(op, id, current, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	next := *current
+	if err := next.Transition(status, utils.GetTimeNow()); err != nil {
+		return nil, models.NewError(models.ErrInvalidTransition, op, id, err)
+	}
+
+	if next.Status == current.Status {
+		return &next, nil
+	}
+
+	// Only written at the version the transition was checked at
+	return a.todoRepo.Update(ctx, id, &next, current.Version)
+}
+
+// Delete - move todo by id to the trash service
+func (a *todoService) Delete(ctx context.Context, id string, version int64) error {
 	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Delete")
 	defer span.End()
 
-	err := a.todoRepo.Delete(ctx, id)
+	err := a.todoRepo.Delete(ctx, id, version)
 	if err != nil {
+		pkg_tracing.RecordError(span, err)
 		return err
 	}
 
 	return nil
 }
+
+// Restore - move todo by id out of the trash service
+func (a *todoService) Restore(ctx context.Context, id string) (*models.Todo, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Restore")
+	defer span.End()
+
+	res, err := a.todoRepo.Restore(ctx, id)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Purge - permanently delete the todos moved to the trash before before service
+func (a *todoService) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Purge")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("todo.purge.before", before.Format(time.RFC3339)))
+
+	purged, err := a.todoRepo.Purge(ctx, before)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	span.SetAttributes(attribute.Int("todo.purge.count", purged))
+
+	return purged, nil
+}
+
+// History - get the revisions of todo by id service
+func (a *todoService) History(ctx context.Context, id string) ([]*models.Revision, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.History")
+	defer span.End()
+
+	res, err := a.todoRepo.FindRevisions(ctx, id)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// Todos written before the history was recorded have none
+	if len(res) == 0 {
+		if _, err := a.todoRepo.FindById(ctx, id); err != nil {
+			pkg_tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Revert - set todo by id back to revision rev service
+func (a *todoService) Revert(ctx context.Context, id string, rev int64, version int64) (*models.Todo, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Revert")
+	defer span.End()
+
+	res, err := a.revert(ctx, id, rev, version)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *todoService) revert(ctx context.Context, id string, rev int64, version int64) (*models.Todo, error) {
+	current, err := a.todoRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion("TodoService.Revert", id, current, version); err != nil {
+		return nil, err
+	}
+
+	if rev < 1 || rev > current.Version {
+		return nil, models.NewError(models.ErrNotFound, "TodoService.Revert", id, fmt.Errorf("no revision %d", rev))
+	}
+	if rev == current.Version {
+		return current, nil
+	}
+
+	revisions, err := a.todoRepo.FindRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var after []*models.Revision
+	found := false
+	for _, revision := range revisions {
+		found = found || revision.Rev == rev
+		if revision.Rev > rev {
+			after = append(after, revision)
+		}
+	}
+	if !found {
+		return nil, models.NewError(models.ErrNotFound, "TodoService.Revert", id, fmt.Errorf("no revision %d", rev))
+	}
+
+	// Reverting over writes missing from the history would be a guess
+	next, err := models.Revert(current, after, rev)
+	if err != nil {
+		return nil, models.NewError(models.ErrConflict, "TodoService.Revert", id, err)
+	}
+
+	if len(models.ChangedFields(current, next)) == 0 {
+		return current, nil
+	}
+
+	return a.todoRepo.Update(ctx, id, next, current.Version)
+}
+
+// Bulk - create, update and delete todos service. Every operation is validated and checked like Create, Update
+// and Delete, the ones that fail are not written. An atomic bulk writes nothing when one fails.
+func (a *todoService) Bulk(ctx context.Context, operations []*models.BulkOperation, atomic bool) ([]*models.BulkResult, error) {
+	ctx, span := otel.Tracer("TodoService").Start(ctx, "TodoService.Bulk")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("todo.bulk.size", len(operations)),
+		attribute.Bool("todo.bulk.atomic", atomic),
+	)
+
+	res, err := a.bulk(ctx, operations, atomic)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("todo.bulk.failed", models.Failed(res)))
+
+	return res, nil
+}
+
+func (a *todoService) bulk(ctx context.Context, operations []*models.BulkOperation, atomic bool) ([]*models.BulkResult, error) {
+	results := make([]*models.BulkResult, len(operations))
+	docIDs := make([]primitive.ObjectID, len(operations))
+	first := map[primitive.ObjectID]int{}
+	var updateIDs []primitive.ObjectID
+	for i, operation := range operations {
+		if operation == nil {
+			results[i] = &models.BulkResult{Err: models.NewError(models.ErrValidation, "TodoService.Bulk", "", errors.New("operation is required"))}
+			continue
+		}
+
+		results[i] = &models.BulkResult{Op: operation.Op, ID: operation.ID}
+		if operation.Op == models.BulkCreate {
+			results[i].ID = ""
+		}
+
+		if err := utils.ValidateStruct(operation); err != nil {
+			results[i].Err = models.NewError(models.ErrValidation, "TodoService.Bulk", operation.ID, err)
+			continue
+		}
+		if operation.Op == models.BulkCreate {
+			continue
+		}
+
+		docID, err := primitive.ObjectIDFromHex(operation.ID)
+		if err != nil {
+			results[i].Err = models.NewError(models.ErrInvalidID, "TodoService.Bulk", operation.ID, err)
+			continue
+		}
+
+		// The writes of a batch are not ordered, so a todo is written once
+		if k, ok := first[docID]; ok {
+			err := fmt.Errorf("todo is already written by operation %d", k)
+			results[i].Err = models.NewError(models.ErrValidation, "TodoService.Bulk", operation.ID, err)
+			continue
+		}
+		first[docID] = i
+		docIDs[i] = docID
+
+		if operation.Op == models.BulkUpdate {
+			updateIDs = append(updateIDs, docID)
+		}
+	}
+
+	// The current statuses decide which status the updated todos may move to
+	current := map[primitive.ObjectID]*models.Todo{}
+	if len(updateIDs) > 0 {
+		todos, err := a.todoRepo.FindAll(ctx, models.TodoFilter{IDs: updateIDs}, models.TodoSort{}, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, todo := range todos {
+			current[todo.ID] = todo
+		}
+	}
+
+	var (
+		writes  []*models.TodoWrite
+		indexes []int
+	)
+	for i, operation := range operations {
+		if results[i].Err != nil {
+			continue
+		}
+
+		write := &models.TodoWrite{Op: operation.Op, ID: results[i].ID, Version: operation.Version}
+		var err error
+		switch operation.Op {
+		case models.BulkCreate:
+			write.Value, err = newTodo("TodoService.Bulk", operation.Todo.Todo())
+		case models.BulkUpdate:
+			todo, ok := current[docIDs[i]]
+			if !ok {
+				err = models.NewError(models.ErrNotFound, "TodoService.Bulk", operation.ID, nil)
+				break
+			}
+			if err = checkVersion("TodoService.Bulk", operation.ID, todo, operation.Version); err != nil {
+				break
+			}
+
+			// Only written at the version the status was checked at
+			write.Version = todo.Version
+			write.Value, err = updateTodo("TodoService.Bulk", operation.ID, todo, operation.Todo.Todo())
+		}
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		writes = append(writes, write)
+		indexes = append(indexes, i)
+	}
+
+	if atomic && models.Failed(results) > 0 {
+		for _, i := range indexes {
+			results[i].Err = models.NewError(models.ErrAborted, "TodoService.Bulk", results[i].ID, nil)
+		}
+
+		return results, nil
+	}
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	written, err := a.todoRepo.BulkWrite(ctx, writes, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for k, i := range indexes {
+		results[i] = written[k]
+	}
+
+	return results, nil
+}
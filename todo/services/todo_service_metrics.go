@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type instrumentedTodoService struct {
+	next    TodoService
+	metrics *pkg_tracing.OperationMetrics
+}
+
+// NewInstrumentedTodoService will wrap a TodoService and record call count, error count and latency of every operation
+func NewInstrumentedTodoService(next TodoService, meter metric.Meter) (TodoService, error) {
+	metrics, err := pkg_tracing.NewOperationMetrics(meter, "todo_service")
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedTodoService{
+		next:    next,
+		metrics: metrics,
+	}, nil
+}
+
+func (s *instrumentedTodoService) record(ctx context.Context, operation string, start time.Time, err error) {
+	s.metrics.Record(ctx, start, err, attribute.String("operation", operation))
+}
+
+// GetAll - get all todo service
+func (s *instrumentedTodoService) GetAll(ctx context.Context, filter models.TodoFilter, sort models.TodoSort, page models.Pagination) (*models.TodoPage, error) {
+	start := time.Now()
+	res, err := s.next.GetAll(ctx, filter, sort, page)
+	s.record(ctx, "GetAll", start, err)
+
+	return res, err
+}
+
+// GetByID - get todo by id service
+func (s *instrumentedTodoService) GetByID(ctx context.Context, id string) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.GetByID(ctx, id)
+	s.record(ctx, "GetByID", start, err)
+
+	return res, err
+}
+
+// Create - creating todo service
+func (s *instrumentedTodoService) Create(ctx context.Context, value *models.Todo) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.Create(ctx, value)
+	s.record(ctx, "Create", start, err)
+
+	return res, err
+}
+
+// Update - update todo by id service
+func (s *instrumentedTodoService) Update(ctx context.Context, id string, value *models.Todo, version int64) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.Update(ctx, id, value, version)
+	s.record(ctx, "Update", start, err)
+
+	return res, err
+}
+
+// Patch - patch todo by id service
+func (s *instrumentedTodoService) Patch(ctx context.Context, id string, patch *models.TodoPatch, version int64) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.Patch(ctx, id, patch, version)
+	s.record(ctx, "Patch", start, err)
+
+	return res, err
+}
+
+// Complete - mark todo by id as done service
+func (s *instrumentedTodoService) Complete(ctx context.Context, id string, version int64) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.Complete(ctx, id, version)
+	s.record(ctx, "Complete", start, err)
+
+	return res, err
+}
+
+// Reopen - move todo by id back to todo service
+func (s *instrumentedTodoService) Reopen(ctx context.Context, id string, version int64) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.Reopen(ctx, id, version)
+	s.record(ctx, "Reopen", start, err)
+
+	return res, err
+}
+
+// Delete - move todo by id to the trash service
+func (s *instrumentedTodoService) Delete(ctx context.Context, id string, version int64) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, id, version)
+	s.record(ctx, "Delete", start, err)
+
+	return err
+}
+
+// Restore - move todo by id out of the trash service
+func (s *instrumentedTodoService) Restore(ctx context.Context, id string) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.Restore(ctx, id)
+	s.record(ctx, "Restore", start, err)
+
+	return res, err
+}
+
+// Purge - permanently delete the todos moved to the trash before before service
+func (s *instrumentedTodoService) Purge(ctx context.Context, before time.Time) (int, error) {
+	start := time.Now()
+	res, err := s.next.Purge(ctx, before)
+	s.record(ctx, "Purge", start, err)
+
+	return res, err
+}
+
+// History - get the revisions of todo by id service
+func (s *instrumentedTodoService) History(ctx context.Context, id string) ([]*models.Revision, error) {
+	start := time.Now()
+	res, err := s.next.History(ctx, id)
+	s.record(ctx, "History", start, err)
+
+	return res, err
+}
+
+// Revert - set todo by id back to revision rev service
+func (s *instrumentedTodoService) Revert(ctx context.Context, id string, rev int64, version int64) (*models.Todo, error) {
+	start := time.Now()
+	res, err := s.next.Revert(ctx, id, rev, version)
+	s.record(ctx, "Revert", start, err)
+
+	return res, err
+}
+
+// Bulk - create, update and delete todos service
+func (s *instrumentedTodoService) Bulk(ctx context.Context, operations []*models.BulkOperation, atomic bool) ([]*models.BulkResult, error) {
+	start := time.Now()
+	res, err := s.next.Bulk(ctx, operations, atomic)
+	s.record(ctx, "Bulk", start, err)
+
+	return res, err
+}
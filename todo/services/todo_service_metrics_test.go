@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	mockServices "go-distributed-tracing/todo/mocks/services"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collectSum - sum of the data points of the counter named name
+func collectSum(t *testing.T, reader sdkmetric.Reader, name string) int64 {
+	rm, err := reader.Collect(context.Background())
+	assert.NoError(t, err)
+
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == name {
+				for _, dp := range sum.DataPoints {
+					total += dp.Value
+				}
+			}
+		}
+	}
+
+	return total
+}
+
+func TestInstrumentedTodoService(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	mockService := new(mockServices.TodoService)
+	mockService.On("GetByID", mock.Anything, "1").Return(&models.Todo{}, nil)
+	mockService.On("GetByID", mock.Anything, "2").Return(nil, ErrDefault)
+	mockService.On("Delete", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("int64")).Return(nil)
+
+	service, err := services.NewInstrumentedTodoService(mockService, mp.Meter("TodoService"))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	result, err := service.GetByID(ctx, "1")
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+
+	_, err = service.GetByID(ctx, "2")
+	assert.Error(t, err)
+
+	assert.NoError(t, service.Delete(ctx, DefaultID, 0))
+
+	assert.Equal(t, int64(3), collectSum(t, reader, "todo_service.calls"))
+	assert.Equal(t, int64(1), collectSum(t, reader, "todo_service.errors"))
+	mockService.AssertExpectations(t)
+}
@@ -7,9 +7,11 @@ import (
 	"go-distributed-tracing/todo/models"
 	"go-distributed-tracing/todo/services"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var ErrDefault error = errors.New("error")
@@ -26,21 +28,24 @@ func TestTodoGetAll(t *testing.T) {
 		mockRepository.On(
 			"FindAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("int"), mock.AnythingOfType("int"),
+			mock.AnythingOfType("models.TodoFilter"),
+			mock.AnythingOfType("models.TodoSort"),
+			11, 0,
 		).Return(mockList, nil)
 		mockRepository.On(
 			"CountFindAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
+			mock.AnythingOfType("models.TodoFilter"),
 		).Return(10, nil)
 
 		ctx := context.Background()
-		results, count, err := service.GetAll(ctx, "keyword", 10, 0)
+		result, err := service.GetAll(ctx, models.TodoFilter{Keyword: "keyword"}, nil, models.Pagination{Limit: 10})
 
 		assert.NoError(t, err)
-		assert.Equal(t, count, 10)
-		assert.Equal(t, mockList, results)
+		assert.Equal(t, 10, *result.Total)
+		assert.Equal(t, mockList, result.Todos)
+		assert.Nil(t, result.Next)
+		assert.Nil(t, result.Prev)
 	})
 
 	t.Run("error when find all", func(t *testing.T) {
@@ -50,21 +55,21 @@ func TestTodoGetAll(t *testing.T) {
 		mockRepository.On(
 			"FindAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
+			mock.AnythingOfType("models.TodoFilter"),
+			mock.AnythingOfType("models.TodoSort"),
 			mock.AnythingOfType("int"),
 			mock.AnythingOfType("int"),
 		).Return(nil, ErrDefault)
 		mockRepository.On(
 			"CountFindAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
+			mock.AnythingOfType("models.TodoFilter"),
 		).Return(10, nil)
 
 		ctx := context.Background()
-		results, count, err := service.GetAll(ctx, "keyword", 10, 0)
+		result, err := service.GetAll(ctx, models.TodoFilter{Keyword: "keyword"}, nil, models.Pagination{Limit: 10})
 
-		assert.Nil(t, results)
-		assert.Equal(t, 0, count)
+		assert.Nil(t, result)
 		assert.Error(t, err)
 	})
 
@@ -75,23 +80,76 @@ func TestTodoGetAll(t *testing.T) {
 		mockRepository.On(
 			"FindAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
+			mock.AnythingOfType("models.TodoFilter"),
+			mock.AnythingOfType("models.TodoSort"),
 			mock.AnythingOfType("int"),
 			mock.AnythingOfType("int"),
 		).Return(nil, nil)
 		mockRepository.On(
 			"CountFindAll",
 			mock.Anything,
-			mock.AnythingOfType("string"),
+			mock.AnythingOfType("models.TodoFilter"),
 		).Return(10, ErrDefault)
 
 		ctx := context.Background()
-		results, count, err := service.GetAll(ctx, "keyword", 10, 0)
+		result, err := service.GetAll(ctx, models.TodoFilter{Keyword: "keyword"}, nil, models.Pagination{Limit: 10})
 
-		assert.Nil(t, results)
-		assert.Equal(t, 0, count)
+		assert.Nil(t, result)
 		assert.Error(t, err)
 	})
+
+	t.Run("next cursor when there are more todos", func(t *testing.T) {
+		mockList := []*models.Todo{{Title: "a"}, {Title: "b"}, {Title: "c"}}
+		sort := models.ParseTodoSort("title")
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindAll", mock.Anything, models.TodoFilter{}, sort.WithID(), 3, 4).Return(mockList, nil)
+
+		ctx := context.Background()
+		result, err := service.GetAll(ctx, models.TodoFilter{}, sort, models.Pagination{Limit: 2, Offset: 4, SkipTotal: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockList[:2], result.Todos)
+		assert.Equal(t, &models.TodoCursor{Sort: sort, Key: mockList[1]}, result.Next)
+		assert.Equal(t, &models.TodoCursor{Sort: sort, Before: true, Key: mockList[0]}, result.Prev)
+
+		// The total is skipped
+		assert.Nil(t, result.Total)
+		mockRepository.AssertNotCalled(t, "CountFindAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reads backwards from a before cursor", func(t *testing.T) {
+		key := &models.Todo{Title: "d"}
+		mockList := []*models.Todo{{Title: "c"}, {Title: "b"}}
+		sort := models.ParseTodoSort("title")
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On(
+			"FindAll",
+			mock.Anything,
+			models.TodoFilter{Keyword: "keyword", After: &models.Keyset{Sort: sort.Reverse(), Key: key}},
+			sort.Reverse(),
+			3, 0,
+		).Return(mockList, nil)
+		mockRepository.On("CountFindAll", mock.Anything, models.TodoFilter{Keyword: "keyword"}).Return(4, nil)
+
+		ctx := context.Background()
+		result, err := service.GetAll(ctx, models.TodoFilter{Keyword: "keyword"}, sort, models.Pagination{
+			Limit:  2,
+			Offset: 6,
+			Cursor: &models.TodoCursor{Sort: sort, Before: true, Key: key},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.Todo{{Title: "b"}, {Title: "c"}}, result.Todos)
+		assert.Equal(t, 4, *result.Total)
+		assert.Equal(t, &models.TodoCursor{Sort: sort, Key: result.Todos[1]}, result.Next)
+		assert.Nil(t, result.Prev)
+	})
 }
 
 func TestTodoGetByID(t *testing.T) {
@@ -140,6 +198,47 @@ func TestTodoCreate(t *testing.T) {
 		assert.Equal(t, mockTodo, result)
 	})
 
+	t.Run("success when create with normalized tags", func(t *testing.T) {
+		dueAt := time.Now().Add(time.Hour)
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("Store", mock.Anything, &models.Todo{
+			Title:    "Buy milk",
+			Status:   models.StatusTodo,
+			DueAt:    &dueAt,
+			Priority: 2,
+			Tags:     []string{"errand", "home"},
+		}).Return(&models.Todo{}, nil)
+
+		ctx := context.Background()
+		_, err := service.Create(ctx, &models.Todo{
+			Title:    "Buy milk",
+			DueAt:    &dueAt,
+			Priority: 2,
+			Tags:     []string{"Home ", "errand", "", "HOME"},
+		})
+
+		assert.NoError(t, err)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("success when create done", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("Store", mock.Anything, mock.MatchedBy(func(todo *models.Todo) bool {
+			return todo.Status == models.StatusDone && todo.CompletedAt != nil
+		})).Return(&models.Todo{}, nil)
+
+		ctx := context.Background()
+		_, err := service.Create(ctx, &models.Todo{Status: models.StatusDone})
+
+		assert.NoError(t, err)
+		mockRepository.AssertExpectations(t)
+	})
+
 	t.Run("error when create", func(t *testing.T) {
 		mockRepository := new(mockRepositories.TodoRepository)
 		service := services.NewTodoService(mockRepository)
@@ -156,48 +255,50 @@ func TestTodoCreate(t *testing.T) {
 
 func TestTodoUpdate(t *testing.T) {
 	t.Run("success when update", func(t *testing.T) {
-		var mockTodo = &models.Todo{}
+		var mockTodo = &models.Todo{Title: "updated", Status: models.StatusTodo}
 
 		mockRepository := new(mockRepositories.TodoRepository)
 		service := services.NewTodoService(mockRepository)
 
 		mockRepository.On(
-			"CountFindByID",
+			"FindById",
 			mock.Anything,
 			mock.AnythingOfType("string"),
-		).Return(10, nil)
+		).Return(&models.Todo{Status: models.StatusTodo}, nil)
 		mockRepository.On(
 			"Update",
 			mock.Anything,
 			mock.AnythingOfType("string"),
 			mock.AnythingOfType("*models.Todo"),
+			int64(0),
 		).Return(mockTodo, nil)
 
 		ctx := context.Background()
-		result, err := service.Update(ctx, DefaultID, &models.Todo{})
+		result, err := service.Update(ctx, DefaultID, &models.Todo{}, 0)
 
 		assert.NoError(t, err)
-		assert.Nil(t, result)
+		assert.Equal(t, mockTodo, result)
 	})
 
-	t.Run("error when count find by id", func(t *testing.T) {
+	t.Run("error when find by id", func(t *testing.T) {
 		mockRepository := new(mockRepositories.TodoRepository)
 		service := services.NewTodoService(mockRepository)
 
 		mockRepository.On(
-			"CountFindByID",
+			"FindById",
 			mock.Anything,
 			mock.AnythingOfType("string"),
-		).Return(0, ErrDefault)
+		).Return(nil, ErrDefault)
 		mockRepository.On(
 			"Update",
 			mock.Anything,
 			mock.AnythingOfType("string"),
 			mock.AnythingOfType("*models.Todo"),
+			int64(0),
 		).Return(nil, nil)
 
 		ctx := context.Background()
-		result, err := service.Update(ctx, DefaultID, &models.Todo{})
+		result, err := service.Update(ctx, DefaultID, &models.Todo{}, 0)
 
 		assert.Nil(t, result)
 		assert.Error(t, err)
@@ -208,23 +309,428 @@ func TestTodoUpdate(t *testing.T) {
 		service := services.NewTodoService(mockRepository)
 
 		mockRepository.On(
-			"CountFindByID",
+			"FindById",
 			mock.Anything,
 			mock.AnythingOfType("string"),
-		).Return(10, nil)
+		).Return(&models.Todo{Status: models.StatusTodo}, nil)
 		mockRepository.On(
 			"Update",
 			mock.Anything,
 			mock.AnythingOfType("string"),
 			mock.AnythingOfType("*models.Todo"),
+			int64(0),
 		).Return(nil, ErrDefault)
 
 		ctx := context.Background()
-		result, err := service.Update(ctx, DefaultID, &models.Todo{})
+		result, err := service.Update(ctx, DefaultID, &models.Todo{}, 0)
 
 		assert.Nil(t, result)
 		assert.Error(t, err)
 	})
+
+	t.Run("keep status and completed_at when not requested", func(t *testing.T) {
+		completedAt := time.Now()
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).
+			Return(&models.Todo{Title: "old", Status: models.StatusDone, CompletedAt: &completedAt}, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, &models.Todo{
+			Title:       "new",
+			Status:      models.StatusDone,
+			CompletedAt: &completedAt,
+			Priority:    3,
+			Tags:        []string{"home", "shopping"},
+		}, int64(0)).Return(&models.Todo{}, nil)
+
+		ctx := context.Background()
+		_, err := service.Update(ctx, DefaultID, &models.Todo{
+			Title:    "new",
+			Priority: 3,
+			Tags:     []string{" Shopping", "home", "shopping"},
+		}, 0)
+
+		assert.NoError(t, err)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("error when status transition is not allowed", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusArchived}, nil)
+
+		ctx := context.Background()
+		result, err := service.Update(ctx, DefaultID, &models.Todo{Status: models.StatusDone}, 0)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrInvalidTransition))
+		mockRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("error when version does not match", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusTodo, Version: 3}, nil)
+
+		ctx := context.Background()
+		result, err := service.Update(ctx, DefaultID, &models.Todo{Title: "new"}, 2)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrPreconditionFailed))
+		mockRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("success when version matches", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusTodo, Version: 3}, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, mock.AnythingOfType("*models.Todo"), int64(3)).
+			Return(&models.Todo{Title: "new", Version: 4}, nil)
+
+		ctx := context.Background()
+		result, err := service.Update(ctx, DefaultID, &models.Todo{Title: "new"}, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), result.Version)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("written at the version read without a version", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusTodo, Version: 3}, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, mock.AnythingOfType("*models.Todo"), int64(3)).
+			Return(nil, models.NewError(models.ErrPreconditionFailed, "TodoRepository.Update", DefaultID, nil))
+
+		ctx := context.Background()
+		result, err := service.Update(ctx, DefaultID, &models.Todo{Title: "new", Status: models.StatusDone}, 0)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrPreconditionFailed), "the status was checked against another version")
+		mockRepository.AssertExpectations(t)
+	})
+}
+
+func TestTodoPatch(t *testing.T) {
+	current := func() *models.Todo {
+		return &models.Todo{
+			Title:       "Buy milk",
+			Description: "At the farm shop",
+			Status:      models.StatusTodo,
+			Tags:        []string{"errand"},
+		}
+	}
+
+	t.Run("success when merge patch", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		patched := &models.Todo{Title: "Buy oat milk"}
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(current(), nil)
+		mockRepository.On("Patch", mock.Anything, DefaultID, &models.Todo{
+			Title:       "Buy oat milk",
+			Description: "At the farm shop",
+			Status:      models.StatusTodo,
+			Priority:    2,
+			Tags:        []string{"errand"},
+		}, []string{models.FieldTitle, models.FieldPriority}, int64(0)).Return(patched, nil)
+
+		ctx := context.Background()
+		result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"title":"Buy oat milk","priority":2}`),
+		}, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, patched, result)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("success when json patch completes", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(current(), nil)
+		mockRepository.On(
+			"Patch",
+			mock.Anything,
+			DefaultID,
+			mock.MatchedBy(func(todo *models.Todo) bool {
+				return todo.Status == models.StatusDone && todo.CompletedAt != nil
+			}),
+			[]string{models.FieldStatus, models.FieldCompletedAt},
+			int64(0),
+		).Return(&models.Todo{}, nil)
+
+		ctx := context.Background()
+		_, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.JSONPatchType,
+			Document:    []byte(`[{"op":"test","path":"/status","value":"todo"},{"op":"replace","path":"/status","value":"done"}]`),
+		}, 0)
+
+		assert.NoError(t, err)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("nothing changed is a no-op", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(current(), nil)
+
+		ctx := context.Background()
+		result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"title":"Buy milk","tags":["Errand"]}`),
+		}, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, current(), result)
+		mockRepository.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when find by id", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{}`),
+		}, 0)
+
+		assert.Nil(t, result)
+		assert.Equal(t, ErrDefault, err)
+	})
+
+	t.Run("error when patched todo is invalid", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(current(), nil)
+
+		ctx := context.Background()
+		for _, document := range []string{`{"title":null}`, `{"priority":9}`, `{"owner":"me"}`, `{"title":`} {
+			result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+				ContentType: models.MergePatchType,
+				Document:    []byte(document),
+			}, 0)
+
+			assert.Nil(t, result)
+			assert.True(t, errors.Is(err, models.ErrValidation), "%s: expected ErrValidation, got %v", document, err)
+		}
+		mockRepository.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when test operation fails", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(current(), nil)
+
+		ctx := context.Background()
+		result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.JSONPatchType,
+			Document:    []byte(`[{"op":"test","path":"/title","value":"Buy bread"}]`),
+		}, 0)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrConflict))
+	})
+
+	t.Run("error when status transition is not allowed", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		archived := current()
+		archived.Status = models.StatusArchived
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(archived, nil)
+
+		ctx := context.Background()
+		result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"status":"done"}`),
+		}, 0)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrInvalidTransition))
+	})
+
+	t.Run("error when version does not match", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		stale := current()
+		stale.Version = 3
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(stale, nil)
+
+		ctx := context.Background()
+		result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"description":"Anywhere"}`),
+		}, 2)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrPreconditionFailed))
+		mockRepository.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when patch", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(current(), nil)
+		mockRepository.On("Patch", mock.Anything, DefaultID, mock.Anything, mock.Anything, int64(0)).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		result, err := service.Patch(ctx, DefaultID, &models.TodoPatch{
+			ContentType: models.MergePatchType,
+			Document:    []byte(`{"description":"Anywhere"}`),
+		}, 0)
+
+		assert.Nil(t, result)
+		assert.Equal(t, ErrDefault, err)
+	})
+}
+
+func TestTodoComplete(t *testing.T) {
+	t.Run("success when complete", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusInProgress, Version: 3}, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, mock.MatchedBy(func(todo *models.Todo) bool {
+			return todo.Status == models.StatusDone && todo.CompletedAt != nil
+		}), int64(3)).Return(func(ctx context.Context, id string, todo *models.Todo, version int64) *models.Todo {
+			updated := *todo
+			updated.Version = version + 1
+			return &updated
+		}, nil)
+
+		ctx := context.Background()
+		result, err := service.Complete(ctx, DefaultID, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusDone, result.Status)
+		assert.NotNil(t, result.CompletedAt)
+		assert.Equal(t, int64(4), result.Version, "the todo written is answered")
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("error when version does not match", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusInProgress, Version: 3}, nil)
+
+		ctx := context.Background()
+		result, err := service.Complete(ctx, DefaultID, 2)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrPreconditionFailed), "%v", err)
+		mockRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when changed since it was read", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusInProgress, Version: 3}, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, mock.AnythingOfType("*models.Todo"), int64(3)).
+			Return(nil, models.NewError(models.ErrPreconditionFailed, "TodoRepository.Update", DefaultID, nil))
+
+		ctx := context.Background()
+		result, err := service.Complete(ctx, DefaultID, 0)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrPreconditionFailed), "%v", err)
+	})
+
+	t.Run("already done is a no-op", func(t *testing.T) {
+		completedAt := time.Now()
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).
+			Return(&models.Todo{Status: models.StatusDone, CompletedAt: &completedAt}, nil)
+
+		ctx := context.Background()
+		result, err := service.Complete(ctx, DefaultID, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &completedAt, result.CompletedAt)
+		mockRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when archived", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusArchived}, nil)
+
+		ctx := context.Background()
+		result, err := service.Complete(ctx, DefaultID, 0)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrInvalidTransition))
+	})
+
+	t.Run("error when find by id", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		result, err := service.Complete(ctx, DefaultID, 0)
+
+		assert.Nil(t, result)
+		assert.Equal(t, ErrDefault, err)
+	})
+
+	t.Run("error when update", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Status: models.StatusTodo}, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, mock.AnythingOfType("*models.Todo"), int64(0)).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		result, err := service.Complete(ctx, DefaultID, 0)
+
+		assert.Nil(t, result)
+		assert.Equal(t, ErrDefault, err)
+	})
+}
+
+func TestTodoReopen(t *testing.T) {
+	t.Run("success when reopen", func(t *testing.T) {
+		completedAt := time.Now()
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).
+			Return(&models.Todo{Status: models.StatusDone, CompletedAt: &completedAt}, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, mock.MatchedBy(func(todo *models.Todo) bool {
+			return todo.Status == models.StatusTodo && todo.CompletedAt == nil
+		}), int64(0)).Return(&models.Todo{Status: models.StatusTodo}, nil)
+
+		ctx := context.Background()
+		result, err := service.Reopen(ctx, DefaultID, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusTodo, result.Status)
+		assert.Nil(t, result.CompletedAt)
+		mockRepository.AssertExpectations(t)
+	})
 }
 
 func TestTodoDelete(t *testing.T) {
@@ -232,10 +738,10 @@ func TestTodoDelete(t *testing.T) {
 		mockRepository := new(mockRepositories.TodoRepository)
 		service := services.NewTodoService(mockRepository)
 
-		mockRepository.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+		mockRepository.On("Delete", mock.Anything, mock.AnythingOfType("string"), int64(0)).Return(nil)
 
 		ctx := context.Background()
-		err := service.Delete(ctx, DefaultID)
+		err := service.Delete(ctx, DefaultID, 0)
 
 		assert.NoError(t, err)
 	})
@@ -244,11 +750,375 @@ func TestTodoDelete(t *testing.T) {
 		mockRepository := new(mockRepositories.TodoRepository)
 		service := services.NewTodoService(mockRepository)
 
-		mockRepository.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(ErrDefault)
+		mockRepository.On("Delete", mock.Anything, mock.AnythingOfType("string"), int64(0)).Return(ErrDefault)
+
+		ctx := context.Background()
+		err := service.Delete(ctx, DefaultID, 0)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTodoRestore(t *testing.T) {
+	t.Run("success when restore", func(t *testing.T) {
+		var mockTodo = &models.Todo{Title: "Buy milk", Version: 3}
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("Restore", mock.Anything, mock.AnythingOfType("string")).Return(mockTodo, nil)
+
+		ctx := context.Background()
+		result, err := service.Restore(ctx, DefaultID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockTodo, result)
+	})
+
+	t.Run("error when restore", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("Restore", mock.Anything, mock.AnythingOfType("string")).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		result, err := service.Restore(ctx, DefaultID)
+
+		assert.Nil(t, result)
+		assert.Error(t, err)
+	})
+}
+
+func TestTodoPurge(t *testing.T) {
+	before := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("success when purge", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("Purge", mock.Anything, before).Return(2, nil)
+
+		ctx := context.Background()
+		purged, err := service.Purge(ctx, before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, purged)
+	})
+
+	t.Run("error when purge", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("Purge", mock.Anything, before).Return(0, ErrDefault)
 
 		ctx := context.Background()
-		err := service.Delete(ctx, DefaultID)
+		purged, err := service.Purge(ctx, before)
+
+		assert.Zero(t, purged)
+		assert.Error(t, err)
+	})
+}
 
+func TestTodoHistory(t *testing.T) {
+	t.Run("success when history", func(t *testing.T) {
+		var mockRevisions = []*models.Revision{{Rev: 1, Action: models.ActionCreate}, {Rev: 2, Action: models.ActionUpdate}}
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindRevisions", mock.Anything, DefaultID).Return(mockRevisions, nil)
+
+		ctx := context.Background()
+		result, err := service.History(ctx, DefaultID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockRevisions, result)
+		mockRepository.AssertNotCalled(t, "FindById", mock.Anything, mock.Anything)
+	})
+
+	t.Run("success when no revision recorded", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindRevisions", mock.Anything, DefaultID).Return([]*models.Revision{}, nil)
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(&models.Todo{Version: 1}, nil)
+
+		ctx := context.Background()
+		result, err := service.History(ctx, DefaultID)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("error when todo not found", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindRevisions", mock.Anything, DefaultID).Return([]*models.Revision{}, nil)
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(nil, models.NewError(models.ErrNotFound, "FindById", DefaultID, nil))
+
+		ctx := context.Background()
+		result, err := service.History(ctx, DefaultID)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrNotFound))
+	})
+
+	t.Run("error when find revisions", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindRevisions", mock.Anything, DefaultID).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		result, err := service.History(ctx, DefaultID)
+
+		assert.Nil(t, result)
+		assert.Error(t, err)
+	})
+}
+
+func TestTodoRevert(t *testing.T) {
+	newCurrent := func() *models.Todo {
+		return &models.Todo{Title: "Buy oat milk", Status: models.StatusTodo, Priority: 3, Tags: []string{}, Version: 3}
+	}
+	mockRevisions := []*models.Revision{
+		{Rev: 1, Action: models.ActionCreate, Changes: []models.FieldChange{{Field: models.FieldTitle, To: "Buy milk"}}},
+		{Rev: 2, Action: models.ActionUpdate, Changes: []models.FieldChange{{Field: models.FieldTitle, From: "Buy milk", To: "Buy oat milk"}}},
+		{Rev: 3, Action: models.ActionPatch, Changes: []models.FieldChange{{Field: models.FieldPriority, From: float64(0), To: float64(3)}}},
+	}
+
+	t.Run("success when revert", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(newCurrent(), nil)
+		mockRepository.On("FindRevisions", mock.Anything, DefaultID).Return(mockRevisions, nil)
+		mockRepository.On("Update", mock.Anything, DefaultID, mock.MatchedBy(func(todo *models.Todo) bool {
+			return todo.Title == "Buy milk" && todo.Priority == 0 && todo.Status == models.StatusTodo
+		}), int64(3)).Return(&models.Todo{Title: "Buy milk", Version: 4}, nil)
+
+		ctx := context.Background()
+		result, err := service.Revert(ctx, DefaultID, 1, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), result.Version)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("current revision is a no-op", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(newCurrent(), nil)
+
+		ctx := context.Background()
+		result, err := service.Revert(ctx, DefaultID, 3, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), result.Version)
+		mockRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when revision does not exist", func(t *testing.T) {
+		for _, rev := range []int64{0, 4} {
+			mockRepository := new(mockRepositories.TodoRepository)
+			service := services.NewTodoService(mockRepository)
+
+			mockRepository.On("FindById", mock.Anything, DefaultID).Return(newCurrent(), nil)
+
+			ctx := context.Background()
+			_, err := service.Revert(ctx, DefaultID, rev, 0)
+
+			assert.True(t, errors.Is(err, models.ErrNotFound), "rev %d", rev)
+		}
+	})
+
+	t.Run("error when revision was not recorded", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(newCurrent(), nil)
+		mockRepository.On("FindRevisions", mock.Anything, DefaultID).Return(mockRevisions[2:], nil)
+
+		ctx := context.Background()
+		_, err := service.Revert(ctx, DefaultID, 1, 0)
+
+		assert.True(t, errors.Is(err, models.ErrNotFound))
+	})
+
+	t.Run("error when history has a gap", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(newCurrent(), nil)
+		mockRepository.On("FindRevisions", mock.Anything, DefaultID).Return([]*models.Revision{mockRevisions[0], mockRevisions[2]}, nil)
+
+		ctx := context.Background()
+		_, err := service.Revert(ctx, DefaultID, 1, 0)
+
+		assert.True(t, errors.Is(err, models.ErrConflict))
+	})
+
+	t.Run("error when version does not match", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(newCurrent(), nil)
+
+		ctx := context.Background()
+		_, err := service.Revert(ctx, DefaultID, 1, 2)
+
+		assert.True(t, errors.Is(err, models.ErrPreconditionFailed))
+	})
+
+	t.Run("error when find by id", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		result, err := service.Revert(ctx, DefaultID, 1, 0)
+
+		assert.Nil(t, result)
+		assert.Error(t, err)
+	})
+}
+
+func TestTodoBulk(t *testing.T) {
+	currentID := primitive.NewObjectID()
+	deleteID := primitive.NewObjectID().Hex()
+	newCurrent := func() *models.Todo {
+		return &models.Todo{ID: currentID, Title: "Buy milk", Status: models.StatusTodo, Tags: []string{}, Version: 3}
+	}
+	todo := &models.TodoRequest{Title: "Buy oat milk", Description: "At the farm shop", Tags: []string{"Home"}}
+
+	t.Run("success when bulk", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindAll", mock.Anything, models.TodoFilter{IDs: []primitive.ObjectID{currentID}}, mock.Anything, 0, 0).
+			Return([]*models.Todo{newCurrent()}, nil)
+		mockRepository.On("BulkWrite", mock.Anything, mock.MatchedBy(func(writes []*models.TodoWrite) bool {
+			return len(writes) == 3 &&
+				writes[0].Op == models.BulkCreate && writes[0].Value.Status == models.StatusTodo &&
+				writes[1].ID == currentID.Hex() && writes[1].Version == 3 && writes[1].Value.Title == "Buy oat milk" &&
+				assert.ObjectsAreEqual([]string{"home"}, writes[1].Value.Tags) &&
+				writes[2].Op == models.BulkDelete && writes[2].Version == 0
+		}), false).Return([]*models.BulkResult{
+			{Op: models.BulkCreate, ID: "1", Todo: &models.Todo{Title: "Buy oat milk"}},
+			{Op: models.BulkUpdate, ID: currentID.Hex(), Todo: &models.Todo{Title: "Buy oat milk", Version: 4}},
+			{Op: models.BulkDelete, ID: deleteID},
+		}, nil)
+
+		ctx := context.Background()
+		results, err := service.Bulk(ctx, []*models.BulkOperation{
+			{Op: models.BulkCreate, ID: "ignored", Todo: todo},
+			{Op: models.BulkUpdate, ID: currentID.Hex(), Todo: todo},
+			{Op: models.BulkDelete, ID: deleteID},
+		}, false)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 3)
+		assert.Zero(t, models.Failed(results))
+		assert.Equal(t, int64(4), results[1].Todo.Version)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("failed operations are not written", func(t *testing.T) {
+		archived := newCurrent()
+		archived.Status = models.StatusArchived
+
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindAll", mock.Anything, mock.Anything, mock.Anything, 0, 0).Return([]*models.Todo{archived}, nil)
+		mockRepository.On("BulkWrite", mock.Anything, mock.MatchedBy(func(writes []*models.TodoWrite) bool {
+			return len(writes) == 1 && writes[0].ID == deleteID
+		}), false).Return([]*models.BulkResult{{Op: models.BulkDelete, ID: deleteID}}, nil)
+
+		done := *todo
+		done.Status = models.StatusDone
+
+		ctx := context.Background()
+		results, err := service.Bulk(ctx, []*models.BulkOperation{
+			{Op: models.BulkCreate},
+			{Op: models.BulkDelete, ID: "abc"},
+			{Op: models.BulkDelete, ID: deleteID},
+			{Op: models.BulkUpdate, ID: deleteID, Todo: todo},
+			{Op: models.BulkUpdate, ID: primitive.NewObjectID().Hex(), Todo: todo},
+			{Op: models.BulkUpdate, ID: currentID.Hex(), Todo: &done},
+			nil,
+		}, false)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 7)
+		assert.True(t, errors.Is(results[0].Err, models.ErrValidation), "%v", results[0].Err)
+		assert.True(t, errors.Is(results[1].Err, models.ErrInvalidID), "%v", results[1].Err)
+		assert.NoError(t, results[2].Err)
+		assert.True(t, errors.Is(results[3].Err, models.ErrValidation), "a todo is written once, got %v", results[3].Err)
+		assert.True(t, errors.Is(results[4].Err, models.ErrNotFound), "%v", results[4].Err)
+		assert.True(t, errors.Is(results[5].Err, models.ErrInvalidTransition), "%v", results[5].Err)
+		assert.True(t, errors.Is(results[6].Err, models.ErrValidation), "%v", results[6].Err)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("error when version does not match", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindAll", mock.Anything, mock.Anything, mock.Anything, 0, 0).Return([]*models.Todo{newCurrent()}, nil)
+
+		ctx := context.Background()
+		results, err := service.Bulk(ctx, []*models.BulkOperation{
+			{Op: models.BulkUpdate, ID: currentID.Hex(), Todo: todo, Version: 2},
+		}, false)
+
+		assert.NoError(t, err)
+		assert.True(t, errors.Is(results[0].Err, models.ErrPreconditionFailed), "%v", results[0].Err)
+		mockRepository.AssertNotCalled(t, "BulkWrite", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("atomic writes nothing when an operation fails", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		ctx := context.Background()
+		results, err := service.Bulk(ctx, []*models.BulkOperation{
+			{Op: models.BulkCreate, Todo: todo},
+			{Op: models.BulkDelete, ID: "abc"},
+		}, true)
+
+		assert.NoError(t, err)
+		assert.True(t, errors.Is(results[0].Err, models.ErrAborted), "%v", results[0].Err)
+		assert.True(t, errors.Is(results[1].Err, models.ErrInvalidID), "%v", results[1].Err)
+		mockRepository.AssertNotCalled(t, "BulkWrite", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error when find all", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("FindAll", mock.Anything, mock.Anything, mock.Anything, 0, 0).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		results, err := service.Bulk(ctx, []*models.BulkOperation{{Op: models.BulkUpdate, ID: currentID.Hex(), Todo: todo}}, false)
+
+		assert.Nil(t, results)
+		assert.Error(t, err)
+	})
+
+	t.Run("error when bulk write", func(t *testing.T) {
+		mockRepository := new(mockRepositories.TodoRepository)
+		service := services.NewTodoService(mockRepository)
+
+		mockRepository.On("BulkWrite", mock.Anything, mock.Anything, true).Return(nil, ErrDefault)
+
+		ctx := context.Background()
+		results, err := service.Bulk(ctx, []*models.BulkOperation{{Op: models.BulkCreate, Todo: todo}}, true)
+
+		assert.Nil(t, results)
 		assert.Error(t, err)
 	})
 }
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go-distributed-tracing/pkg/log"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+
+	"go.opentelemetry.io/otel"
+)
+
+// WebhookConfig - configuration of the webhook deliveries
+type WebhookConfig struct {
+	// MaxAttempts is how many times a delivery is sent before it is dead
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt, doubled after each next one
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts
+	MaxBackoff time.Duration
+	// Timeout is how long a webhook has to respond
+	Timeout time.Duration
+	// PollInterval is the time between two looks for due deliveries, zero disables the job
+	PollInterval time.Duration
+	// BatchSize is how many deliveries are sent per run
+	BatchSize int
+}
+
+// defaultWebhookBatchSize - deliveries sent per run when the batch size isn't set
+const defaultWebhookBatchSize = 50
+
+// WebhookConfigFromEnv - read webhook delivery configuration from environment
+func WebhookConfigFromEnv() (WebhookConfig, error) {
+	cfg := WebhookConfig{
+		MaxAttempts:    8,
+		InitialBackoff: 30 * time.Second,
+		MaxBackoff:     time.Hour,
+		Timeout:        10 * time.Second,
+		PollInterval:   5 * time.Second,
+		BatchSize:      defaultWebhookBatchSize,
+	}
+
+	var err error
+	if value := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		if cfg.MaxAttempts, err = strconv.Atoi(value); err != nil || cfg.MaxAttempts <= 0 {
+			return cfg, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q: must be a positive integer", value)
+		}
+	}
+	for _, duration := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"WEBHOOK_BACKOFF", &cfg.InitialBackoff},
+		{"WEBHOOK_MAX_BACKOFF", &cfg.MaxBackoff},
+		{"WEBHOOK_TIMEOUT", &cfg.Timeout},
+	} {
+		if value := os.Getenv(duration.name); value != "" {
+			if *duration.value, err = time.ParseDuration(value); err != nil {
+				return cfg, fmt.Errorf("invalid %s %q: %w", duration.name, value, err)
+			}
+			if *duration.value <= 0 {
+				return cfg, fmt.Errorf("invalid %s %q: must be positive", duration.name, value)
+			}
+		}
+	}
+	if value := os.Getenv("WEBHOOK_POLL_INTERVAL"); value != "" {
+		if cfg.PollInterval, err = time.ParseDuration(value); err != nil {
+			return cfg, fmt.Errorf("invalid WEBHOOK_POLL_INTERVAL %q: %w", value, err)
+		}
+	}
+	if value := os.Getenv("WEBHOOK_BATCH_SIZE"); value != "" {
+		if cfg.BatchSize, err = strconv.Atoi(value); err != nil || cfg.BatchSize <= 0 {
+			return cfg, fmt.Errorf("invalid WEBHOOK_BATCH_SIZE %q: must be a positive integer", value)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Backoff - wait before the next attempt of a delivery which failed attempts times
+func (cfg WebhookConfig) Backoff(attempts int) time.Duration {
+	backoff := cfg.InitialBackoff
+	for i := 1; i < attempts && backoff < cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > cfg.MaxBackoff {
+		backoff = cfg.MaxBackoff
+	}
+
+	return backoff
+}
+
+// RunWebhookJob - send the due webhook deliveries every poll interval, until ctx is done. It returns right
+// away when the poll interval is zero, a zero batch size falls back to defaultWebhookBatchSize.
+func RunWebhookJob(ctx context.Context, service WebhookService, cfg WebhookConfig) {
+	if cfg.PollInterval <= 0 {
+		return
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWebhookBatchSize
+	}
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// A full batch means more deliveries may be due already
+		attempted := dispatch(ctx, service)
+		for attempted >= cfg.BatchSize && ctx.Err() == nil {
+			attempted = dispatch(ctx, service)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch - a single run of the webhook job, traced on its own. It returns how many deliveries were attempted.
+func dispatch(ctx context.Context, service WebhookService) int {
+	ctx, span := otel.Tracer("WebhookJob").Start(ctx, "WebhookJob.Run")
+	defer span.End()
+
+	attempted, err := service.Dispatch(ctx)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		log.FromContext(ctx).WithError(err).Error("dispatching webhook deliveries")
+		return 0
+	}
+
+	return attempted
+}
@@ -0,0 +1,171 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockServices "go-distributed-tracing/todo/mocks/services"
+	"go-distributed-tracing/todo/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWebhookConfigFromEnv(t *testing.T) {
+	envs := []string{"WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_BACKOFF", "WEBHOOK_MAX_BACKOFF", "WEBHOOK_TIMEOUT", "WEBHOOK_POLL_INTERVAL", "WEBHOOK_BATCH_SIZE"}
+	clear := func() {
+		for _, env := range envs {
+			t.Setenv(env, "")
+		}
+	}
+
+	t.Run("success with defaults", func(t *testing.T) {
+		clear()
+
+		cfg, err := services.WebhookConfigFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, services.WebhookConfig{
+			MaxAttempts:    8,
+			InitialBackoff: 30 * time.Second,
+			MaxBackoff:     time.Hour,
+			Timeout:        10 * time.Second,
+			PollInterval:   5 * time.Second,
+			BatchSize:      50,
+		}, cfg)
+	})
+
+	t.Run("success with values", func(t *testing.T) {
+		clear()
+		t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+		t.Setenv("WEBHOOK_BACKOFF", "1s")
+		t.Setenv("WEBHOOK_MAX_BACKOFF", "1m")
+		t.Setenv("WEBHOOK_TIMEOUT", "2s")
+		t.Setenv("WEBHOOK_POLL_INTERVAL", "0")
+		t.Setenv("WEBHOOK_BATCH_SIZE", "20")
+
+		cfg, err := services.WebhookConfigFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, services.WebhookConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Second,
+			MaxBackoff:     time.Minute,
+			Timeout:        2 * time.Second,
+			PollInterval:   0,
+			BatchSize:      20,
+		}, cfg)
+	})
+
+	t.Run("error when invalid", func(t *testing.T) {
+		for _, env := range [][2]string{
+			{"WEBHOOK_MAX_ATTEMPTS", "0"},
+			{"WEBHOOK_MAX_ATTEMPTS", "many"},
+			{"WEBHOOK_BACKOFF", "0"},
+			{"WEBHOOK_MAX_BACKOFF", "hour"},
+			{"WEBHOOK_TIMEOUT", "-1s"},
+			{"WEBHOOK_POLL_INTERVAL", "often"},
+			{"WEBHOOK_BATCH_SIZE", "0"},
+			{"WEBHOOK_BATCH_SIZE", "many"},
+		} {
+			clear()
+			t.Setenv(env[0], env[1])
+
+			_, err := services.WebhookConfigFromEnv()
+			assert.Error(t, err, env[0])
+		}
+	})
+}
+
+func TestWebhookConfigBackoff(t *testing.T) {
+	cfg := services.WebhookConfig{InitialBackoff: 30 * time.Second, MaxBackoff: 3 * time.Minute}
+
+	assert.Equal(t, 30*time.Second, cfg.Backoff(1))
+	assert.Equal(t, time.Minute, cfg.Backoff(2))
+	assert.Equal(t, 2*time.Minute, cfg.Backoff(3))
+	assert.Equal(t, 3*time.Minute, cfg.Backoff(4))
+	assert.Equal(t, 3*time.Minute, cfg.Backoff(100))
+}
+
+func TestRunWebhookJob(t *testing.T) {
+	t.Run("success when dispatch every poll interval", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calls := 0
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Dispatch", mock.Anything).Return(0, nil).Run(func(mock.Arguments) {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+		})
+
+		done := make(chan struct{})
+		go func() {
+			services.RunWebhookJob(ctx, mockService, services.WebhookConfig{PollInterval: time.Millisecond, BatchSize: 10})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the webhook job didn't stop")
+		}
+		mockService.AssertNumberOfCalls(t, "Dispatch", 2)
+	})
+
+	t.Run("dispatch again right away after a full batch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Dispatch", mock.Anything).Return(10, nil).Twice()
+		mockService.On("Dispatch", mock.Anything).Return(3, nil).Once().Run(func(mock.Arguments) {
+			cancel()
+		})
+
+		done := make(chan struct{})
+		go func() {
+			services.RunWebhookJob(ctx, mockService, services.WebhookConfig{PollInterval: time.Hour, BatchSize: 10})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the webhook job didn't stop")
+		}
+		mockService.AssertNumberOfCalls(t, "Dispatch", 3)
+	})
+
+	t.Run("stop after a run when the batch size isn't set", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockService := new(mockServices.WebhookService)
+		mockService.On("Dispatch", mock.Anything).Return(0, nil).Once().Run(func(mock.Arguments) {
+			cancel()
+		})
+
+		done := make(chan struct{})
+		go func() {
+			services.RunWebhookJob(ctx, mockService, services.WebhookConfig{PollInterval: time.Hour})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the webhook job didn't stop")
+		}
+		mockService.AssertNumberOfCalls(t, "Dispatch", 1)
+	})
+
+	t.Run("success when disabled", func(t *testing.T) {
+		mockService := new(mockServices.WebhookService)
+
+		services.RunWebhookJob(context.Background(), mockService, services.WebhookConfig{})
+
+		mockService.AssertNotCalled(t, "Dispatch", mock.Anything)
+	})
+}
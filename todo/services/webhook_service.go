@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-distributed-tracing/pkg/log"
+	pkg_tracing "go-distributed-tracing/pkg/tracing"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WebhookService represent the webhook service. It is an EventPublisher queuing a delivery of every event to
+// the webhooks subscribed to it, Dispatch sends them.
+type WebhookService interface {
+	GetAll(ctx context.Context) ([]*models.Webhook, error)
+	GetByID(ctx context.Context, id string) (*models.Webhook, error)
+	Create(ctx context.Context, value *models.Webhook) (*models.Webhook, error)
+	Update(ctx context.Context, id string, value *models.Webhook) (*models.Webhook, error)
+	Delete(ctx context.Context, id string) error
+	// Deliveries lists the deliveries of webhook by id newest first, all of them when status is empty
+	Deliveries(ctx context.Context, id string, status models.DeliveryStatus, limit int, offset int) ([]*models.Delivery, error)
+	// DeadLetters lists the deliveries of every webhook which ran out of attempts, newest first
+	DeadLetters(ctx context.Context, limit int, offset int) ([]*models.Delivery, error)
+	// Retry queues a dead delivery by id for one more attempt, models.ErrConflict when it isn't dead
+	Retry(ctx context.Context, id string) (*models.Delivery, error)
+	// Dispatch sends the deliveries due now, it returns how many were attempted
+	Dispatch(ctx context.Context) (int, error)
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	cfg         WebhookConfig
+	client      *http.Client
+}
+
+// NewWebhookService will create new an WebhookService object representation of WebhookService interface
+func NewWebhookService(a repository.WebhookRepository, cfg WebhookConfig) WebhookService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWebhookBatchSize
+	}
+
+	return &webhookService{
+		webhookRepo: a,
+		cfg:         cfg,
+		client:      &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// GetAll - get all webhook service
+func (a *webhookService) GetAll(ctx context.Context) ([]*models.Webhook, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.GetAll")
+	defer span.End()
+
+	res, err := a.webhookRepo.FindAll(ctx)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetByID - get webhook by id service
+func (a *webhookService) GetByID(ctx context.Context, id string) (*models.Webhook, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.GetByID")
+	defer span.End()
+
+	res, err := a.webhookRepo.FindById(ctx, id)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Create - creating webhook service
+func (a *webhookService) Create(ctx context.Context, value *models.Webhook) (*models.Webhook, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.Create")
+	defer span.End()
+
+	res, err := a.webhookRepo.Store(ctx, value)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Update - update webhook by id service, returns the updated webhook
+func (a *webhookService) Update(ctx context.Context, id string, value *models.Webhook) (*models.Webhook, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.Update")
+	defer span.End()
+
+	res, err := a.webhookRepo.Update(ctx, id, value)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Delete - delete webhook by id service, its deliveries are deleted too
+func (a *webhookService) Delete(ctx context.Context, id string) error {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.Delete")
+	defer span.End()
+
+	if err := a.webhookRepo.Delete(ctx, id); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Deliveries - get the deliveries of webhook by id service
+func (a *webhookService) Deliveries(ctx context.Context, id string, status models.DeliveryStatus, limit int, offset int) ([]*models.Delivery, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.Deliveries")
+	defer span.End()
+
+	// An unknown webhook is not found rather than without deliveries
+	webhook, err := a.webhookRepo.FindById(ctx, id)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	res, err := a.webhookRepo.FindDeliveries(ctx, models.DeliveryFilter{WebhookID: webhook.ID, Status: status}, limit, offset)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DeadLetters - get the dead deliveries service
+func (a *webhookService) DeadLetters(ctx context.Context, limit int, offset int) ([]*models.Delivery, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.DeadLetters")
+	defer span.End()
+
+	res, err := a.webhookRepo.FindDeliveries(ctx, models.DeliveryFilter{Status: models.DeliveryDead}, limit, offset)
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Retry - retry a dead delivery by id service. The attempts are kept, so the delivery is dead again after a
+// failed retry.
+func (a *webhookService) Retry(ctx context.Context, id string) (*models.Delivery, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.Retry")
+	defer span.End()
+
+	delivery, err := a.webhookRepo.FindDeliveryById(ctx, id)
+	if err == nil && delivery.Status != models.DeliveryDead {
+		err = models.NewError(models.ErrConflict, "WebhookService.Retry", id, errors.New("only dead deliveries are retried"))
+	}
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// A concurrent retry of the same delivery conflicts
+	claim := delivery.NextAttemptAt
+	now := utils.GetTimeNow()
+	delivery.Status = models.DeliveryPending
+	delivery.NextAttemptAt = &now
+	delivery.UpdatedAt = now
+	if err := a.webhookRepo.UpdateDelivery(ctx, delivery, claim); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Publish - queue a delivery of event to each webhook subscribed to it. The deliveries carry the trace context
+// of ctx, the outbox relay publishes the event again when it fails.
+func (a *webhookService) Publish(ctx context.Context, event *models.Event) error {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.Publish", trace.WithAttributes(
+		attribute.String("event.type", string(event.Type)),
+		attribute.String("todo.id", event.TodoID.Hex()),
+	))
+	defer span.End()
+
+	if err := a.queue(ctx, event); err != nil {
+		pkg_tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// queue - store the deliveries of event
+func (a *webhookService) queue(ctx context.Context, event *models.Event) error {
+	webhooks, err := a.webhookRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	now := utils.GetTimeNow()
+	deliveries := []*models.Delivery{}
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+
+		next := now
+		deliveries = append(deliveries, &models.Delivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			TraceParent:   carrier.Get("traceparent"),
+			Status:        models.DeliveryPending,
+			Attempts:      []models.DeliveryAttempt{},
+			NextAttemptAt: &next,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("webhook.delivery.queued", len(deliveries)))
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return a.webhookRepo.StoreDeliveries(ctx, deliveries)
+}
+
+// Dispatch - send the due deliveries service, up to the batch size. Each is claimed right before it is sent,
+// for twice the timeout, so another worker only sends one again when this one died while sending it. A failed
+// update doesn't hold back the other deliveries, the first error is returned once they are all attempted.
+func (a *webhookService) Dispatch(ctx context.Context) (int, error) {
+	ctx, span := otel.Tracer("WebhookService").Start(ctx, "WebhookService.Dispatch")
+	defer span.End()
+
+	webhooks := map[string]*models.Webhook{}
+	attempted := 0
+	var failed error
+	for attempted < a.cfg.BatchSize && ctx.Err() == nil {
+		now := utils.GetTimeNow()
+		claimed, err := a.webhookRepo.ClaimDeliveries(ctx, now, now.Add(2*a.cfg.Timeout), 1)
+		if err != nil {
+			pkg_tracing.RecordError(span, err)
+			return attempted, err
+		}
+		if len(claimed) == 0 {
+			break
+		}
+		delivery := claimed[0]
+		attempted++
+
+		id := delivery.WebhookID.Hex()
+		if _, ok := webhooks[id]; !ok {
+			webhook, err := a.webhookRepo.FindById(ctx, id)
+			if err != nil && !errors.Is(err, models.ErrNotFound) {
+				pkg_tracing.RecordError(span, err)
+				return attempted, err
+			}
+			webhooks[id] = webhook
+		}
+
+		// A webhook deleted since the claim took its deliveries with it
+		if webhooks[id] == nil {
+			continue
+		}
+
+		if err := a.deliver(ctx, webhooks[id], delivery); err != nil {
+			pkg_tracing.RecordError(span, err)
+			log.FromContext(ctx).WithError(err).WithField("webhook.delivery.id", delivery.ID.Hex()).Error("recording webhook delivery")
+			if failed == nil {
+				failed = err
+			}
+		}
+	}
+
+	span.SetAttributes(attribute.Int("webhook.delivery.attempted", attempted))
+
+	return attempted, failed
+}
+
+// deliver - send delivery to webhook once and record the attempt. The span continues the trace of the write
+// of the event and links to the dispatch.
+func (a *webhookService) deliver(ctx context.Context, webhook *models.Webhook, delivery *models.Delivery) error {
+	claim := delivery.NextAttemptAt
+	parent := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": delivery.TraceParent})
+	parent, span := otel.Tracer("WebhookService").Start(parent, "WebhookService.Deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(
+			attribute.String("webhook.id", webhook.ID.Hex()),
+			attribute.String("webhook.delivery.id", delivery.ID.Hex()),
+			attribute.String("event.type", string(delivery.EventType)),
+			attribute.Int("webhook.delivery.attempt", len(delivery.Attempts)+1),
+			semconv.HTTPMethodKey.String(http.MethodPost),
+			semconv.HTTPURLKey.String(webhook.URL),
+		),
+	)
+	defer span.End()
+
+	start := utils.GetTimeNow()
+	statusCode, err := a.send(parent, webhook, delivery, start)
+	attempt := models.DeliveryAttempt{
+		At:         start,
+		StatusCode: statusCode,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if statusCode != 0 {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(statusCode))
+	}
+
+	now := utils.GetTimeNow()
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.UpdatedAt = now
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case len(delivery.Attempts) >= a.cfg.MaxAttempts:
+		delivery.Attempts[len(delivery.Attempts)-1].Error = err.Error()
+		delivery.Status = models.DeliveryDead
+		delivery.NextAttemptAt = nil
+	default:
+		delivery.Attempts[len(delivery.Attempts)-1].Error = err.Error()
+		next := now.Add(a.cfg.Backoff(len(delivery.Attempts)))
+		delivery.NextAttemptAt = &next
+	}
+	span.SetAttributes(attribute.String("webhook.delivery.status", string(delivery.Status)))
+	if err != nil {
+		pkg_tracing.RecordError(span, err)
+		log.FromContext(parent).WithError(err).WithField("webhook.delivery.id", delivery.ID.Hex()).Warn("webhook delivery failed")
+	}
+
+	// The claim ends with the update, the delivery is sent again if it fails. Another worker claimed the
+	// delivery when this claim expired while sending, its attempt is recorded instead of this one.
+	err = a.webhookRepo.UpdateDelivery(ctx, delivery, claim)
+	if errors.Is(err, models.ErrConflict) {
+		log.FromContext(parent).WithField("webhook.delivery.id", delivery.ID.Hex()).Warn("webhook delivery claim lost")
+		return nil
+	}
+
+	return err
+}
+
+// send - POST the payload of delivery to webhook, signed at timestamp. Only 2xx responses are successes.
+func (a *webhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.Delivery, at time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := at.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-distributed-tracing-webhook")
+	req.Header.Set("X-Webhook-Event", string(delivery.EventType))
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.Hex())
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", models.WebhookSignature(webhook.Secret, timestamp, delivery.Payload))
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Drain a bit of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,415 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	mockRepositories "go-distributed-tracing/todo/mocks/repository"
+	"go-distributed-tracing/todo/models"
+	"go-distributed-tracing/todo/repository"
+	"go-distributed-tracing/todo/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+var webhookConfig = services.WebhookConfig{
+	MaxAttempts:    3,
+	InitialBackoff: time.Minute,
+	MaxBackoff:     time.Hour,
+	Timeout:        time.Second,
+	PollInterval:   time.Second,
+	BatchSize:      10,
+}
+
+func TestWebhookCreate(t *testing.T) {
+	t.Run("success when create", func(t *testing.T) {
+		webhook := &models.Webhook{URL: "http://localhost/hook"}
+
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		mockRepository.On("Store", mock.Anything, webhook).Return(webhook, nil)
+
+		result, err := service.Create(context.Background(), webhook)
+
+		assert.NoError(t, err)
+		assert.Equal(t, webhook, result)
+	})
+
+	t.Run("error when create", func(t *testing.T) {
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		mockRepository.On("Store", mock.Anything, mock.AnythingOfType("*models.Webhook")).Return(nil, ErrDefault)
+
+		result, err := service.Create(context.Background(), &models.Webhook{})
+
+		assert.Nil(t, result)
+		assert.Error(t, err)
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	webhook := &models.Webhook{ID: primitive.NewObjectID()}
+
+	t.Run("success when find deliveries", func(t *testing.T) {
+		deliveries := []*models.Delivery{{WebhookID: webhook.ID}}
+
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		mockRepository.On("FindById", mock.Anything, webhook.ID.Hex()).Return(webhook, nil)
+		mockRepository.On("FindDeliveries", mock.Anything, models.DeliveryFilter{WebhookID: webhook.ID, Status: models.DeliveryPending}, 10, 20).
+			Return(deliveries, nil)
+
+		result, err := service.Deliveries(context.Background(), webhook.ID.Hex(), models.DeliveryPending, 10, 20)
+
+		assert.NoError(t, err)
+		assert.Equal(t, deliveries, result)
+	})
+
+	t.Run("error when webhook not found", func(t *testing.T) {
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		mockRepository.On("FindById", mock.Anything, DefaultID).
+			Return(nil, models.NewError(models.ErrNotFound, "WebhookRepository.FindById", DefaultID, nil))
+
+		result, err := service.Deliveries(context.Background(), DefaultID, "", 10, 0)
+
+		assert.Nil(t, result)
+		assert.True(t, errors.Is(err, models.ErrNotFound))
+		mockRepository.AssertNotCalled(t, "FindDeliveries", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+	deliveries := []*models.Delivery{{Status: models.DeliveryDead}}
+
+	mockRepository := new(mockRepositories.WebhookRepository)
+	service := services.NewWebhookService(mockRepository, webhookConfig)
+
+	mockRepository.On("FindDeliveries", mock.Anything, models.DeliveryFilter{Status: models.DeliveryDead}, 10, 0).Return(deliveries, nil)
+
+	result, err := service.DeadLetters(context.Background(), 10, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, deliveries, result)
+}
+
+func TestWebhookRetry(t *testing.T) {
+	t.Run("success when dead", func(t *testing.T) {
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		mockRepository.On("FindDeliveryById", mock.Anything, DefaultID).Return(&models.Delivery{Status: models.DeliveryDead}, nil)
+		mockRepository.On("UpdateDelivery", mock.Anything, mock.MatchedBy(func(delivery *models.Delivery) bool {
+			return delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil
+		}), (*time.Time)(nil)).Return(nil)
+
+		result, err := service.Retry(context.Background(), DefaultID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.DeliveryPending, result.Status)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("error when not dead", func(t *testing.T) {
+		for _, status := range []models.DeliveryStatus{models.DeliveryPending, models.DeliverySucceeded} {
+			mockRepository := new(mockRepositories.WebhookRepository)
+			service := services.NewWebhookService(mockRepository, webhookConfig)
+
+			mockRepository.On("FindDeliveryById", mock.Anything, DefaultID).Return(&models.Delivery{Status: status}, nil)
+
+			result, err := service.Retry(context.Background(), DefaultID)
+
+			assert.Nil(t, result)
+			assert.True(t, errors.Is(err, models.ErrConflict), status)
+			mockRepository.AssertNotCalled(t, "UpdateDelivery", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+// storeWebhook - store a webhook to url subscribed to events in repo
+func storeWebhook(t *testing.T, repo repository.WebhookRepository, url string, events ...models.EventType) *models.Webhook {
+	t.Helper()
+
+	webhook, err := repo.Store(context.Background(), &models.Webhook{URL: url, Events: events, Secret: "0123456789abcdef"})
+	require.NoError(t, err)
+
+	return webhook
+}
+
+func TestWebhookPublish(t *testing.T) {
+	t.Run("error when the webhooks can't be read", func(t *testing.T) {
+		mockRepository := new(mockRepositories.WebhookRepository)
+		mockRepository.On("FindAll", mock.Anything).Return(nil, ErrDefault)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		event := models.NewEvent(context.Background(), models.EventTodoDeleted, primitive.NewObjectID(), nil, time.Now())
+		err := service.Publish(context.Background(), event)
+
+		assert.ErrorIs(t, err, ErrDefault)
+		mockRepository.AssertNotCalled(t, "StoreDeliveries", mock.Anything, mock.Anything)
+	})
+
+	t.Run("success queues a delivery per subscribed webhook", func(t *testing.T) {
+		repo := repository.NewMemoryWebhookRepository()
+		service := services.NewWebhookService(repo, webhookConfig)
+		all := storeWebhook(t, repo, "http://localhost/all")
+		storeWebhook(t, repo, "http://localhost/deleted", models.EventTodoDeleted)
+
+		ctx, span := sdktrace.NewTracerProvider().Tracer("webhook_service_test").Start(context.Background(), "todoHandler.Create")
+		defer span.End()
+
+		todo := &models.Todo{ID: primitive.NewObjectID(), Title: "Buy milk"}
+		event := models.NewEvent(ctx, models.EventTodoCreated, todo.ID, todo, time.Now())
+		require.NoError(t, service.Publish(ctx, event))
+
+		deliveries, err := repo.FindDeliveries(context.Background(), models.DeliveryFilter{}, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+
+		delivery := deliveries[0]
+		assert.Equal(t, all.ID, delivery.WebhookID)
+		assert.Equal(t, event.ID, delivery.EventID)
+		assert.Equal(t, models.EventTodoCreated, delivery.EventType)
+		assert.Equal(t, models.DeliveryPending, delivery.Status)
+		assert.NotNil(t, delivery.NextAttemptAt)
+		assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01", delivery.TraceParent)
+
+		var payload models.Event
+		require.NoError(t, json.Unmarshal(delivery.Payload, &payload))
+		assert.Equal(t, event.ID, payload.ID)
+		assert.Equal(t, "Buy milk", payload.Todo.Title)
+	})
+}
+
+// receiver - webhook receiver answering status, keeping the requests it got
+type receiver struct {
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+// queueDelivery - queue a delivery of a todo.created event to webhook, in the trace of traceParent
+func queueDelivery(t *testing.T, repo repository.WebhookRepository, webhook *models.Webhook, traceParent string) *models.Delivery {
+	t.Helper()
+
+	now := time.Now()
+	delivery := &models.Delivery{
+		WebhookID:     webhook.ID,
+		EventID:       primitive.NewObjectID(),
+		EventType:     models.EventTodoCreated,
+		Payload:       json.RawMessage(`{"type":"todo.created"}`),
+		TraceParent:   traceParent,
+		Status:        models.DeliveryPending,
+		Attempts:      []models.DeliveryAttempt{},
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	require.NoError(t, repo.StoreDeliveries(context.Background(), []*models.Delivery{delivery}))
+
+	return delivery
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	t.Run("success when 2xx", func(t *testing.T) {
+		target := &receiver{status: http.StatusNoContent}
+		server := httptest.NewServer(target)
+		defer server.Close()
+
+		repo := repository.NewMemoryWebhookRepository()
+		service := services.NewWebhookService(repo, webhookConfig)
+		webhook := storeWebhook(t, repo, server.URL)
+		delivery := queueDelivery(t, repo, webhook, traceParent)
+
+		attempted, err := service.Dispatch(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+
+		require.Len(t, target.requests, 1)
+		req := target.requests[0]
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, "todo.created", req.Header.Get("X-Webhook-Event"))
+		assert.Equal(t, delivery.ID.Hex(), req.Header.Get("X-Webhook-Delivery"))
+		assert.Equal(t, traceParent, req.Header.Get("traceparent"))
+		assert.JSONEq(t, `{"type":"todo.created"}`, string(target.bodies[0]))
+
+		timestamp, err := strconv.ParseInt(req.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, models.WebhookSignature("0123456789abcdef", timestamp, target.bodies[0]), req.Header.Get("X-Webhook-Signature"))
+
+		result, err := repo.FindDeliveryById(context.Background(), delivery.ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.DeliverySucceeded, result.Status)
+		assert.Nil(t, result.NextAttemptAt)
+		require.Len(t, result.Attempts, 1)
+		assert.Equal(t, http.StatusNoContent, result.Attempts[0].StatusCode)
+		assert.Empty(t, result.Attempts[0].Error)
+
+		// Nothing is due anymore
+		attempted, err = service.Dispatch(context.Background())
+		assert.NoError(t, err)
+		assert.Zero(t, attempted)
+	})
+
+	t.Run("retry with backoff until dead", func(t *testing.T) {
+		target := &receiver{status: http.StatusInternalServerError}
+		server := httptest.NewServer(target)
+		defer server.Close()
+
+		repo := repository.NewMemoryWebhookRepository()
+		service := services.NewWebhookService(repo, webhookConfig)
+		webhook := storeWebhook(t, repo, server.URL)
+		delivery := queueDelivery(t, repo, webhook, traceParent)
+
+		for attempt := 1; attempt <= webhookConfig.MaxAttempts; attempt++ {
+			start := time.Now()
+			attempted, err := service.Dispatch(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, attempted, "attempt %d", attempt)
+
+			result, err := repo.FindDeliveryById(context.Background(), delivery.ID.Hex())
+			require.NoError(t, err)
+			require.Len(t, result.Attempts, attempt)
+			assert.Equal(t, http.StatusInternalServerError, result.Attempts[attempt-1].StatusCode)
+			assert.Equal(t, "webhook responded 500 Internal Server Error", result.Attempts[attempt-1].Error)
+
+			if attempt == webhookConfig.MaxAttempts {
+				assert.Equal(t, models.DeliveryDead, result.Status)
+				assert.Nil(t, result.NextAttemptAt)
+				break
+			}
+
+			assert.Equal(t, models.DeliveryPending, result.Status)
+			require.NotNil(t, result.NextAttemptAt)
+			assert.WithinDuration(t, start.Add(webhookConfig.Backoff(attempt)), *result.NextAttemptAt, time.Second)
+
+			// Make it due right away
+			claim := result.NextAttemptAt
+			now := time.Now()
+			result.NextAttemptAt = &now
+			require.NoError(t, repo.UpdateDelivery(context.Background(), result, claim))
+		}
+
+		attempted, err := service.Dispatch(context.Background())
+		assert.NoError(t, err)
+		assert.Zero(t, attempted)
+		assert.Len(t, target.requests, webhookConfig.MaxAttempts)
+	})
+
+	t.Run("retry when unreachable", func(t *testing.T) {
+		server := httptest.NewServer(&receiver{status: http.StatusOK})
+		server.Close()
+
+		repo := repository.NewMemoryWebhookRepository()
+		service := services.NewWebhookService(repo, webhookConfig)
+		webhook := storeWebhook(t, repo, server.URL)
+		delivery := queueDelivery(t, repo, webhook, "")
+
+		_, err := service.Dispatch(context.Background())
+		require.NoError(t, err)
+
+		result, err := repo.FindDeliveryById(context.Background(), delivery.ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.DeliveryPending, result.Status)
+		require.Len(t, result.Attempts, 1)
+		assert.Zero(t, result.Attempts[0].StatusCode)
+		assert.NotEmpty(t, result.Attempts[0].Error)
+	})
+
+	t.Run("continue when an update fails", func(t *testing.T) {
+		target := &receiver{status: http.StatusOK}
+		server := httptest.NewServer(target)
+		defer server.Close()
+
+		webhook := &models.Webhook{ID: primitive.NewObjectID(), URL: server.URL}
+		now := time.Now()
+		first := &models.Delivery{ID: primitive.NewObjectID(), WebhookID: webhook.ID, NextAttemptAt: &now}
+		second := &models.Delivery{ID: primitive.NewObjectID(), WebhookID: webhook.ID, NextAttemptAt: &now}
+
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		// Each delivery is claimed right before it is sent
+		mockRepository.On("ClaimDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 1).
+			Return([]*models.Delivery{first}, nil).Once()
+		mockRepository.On("ClaimDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 1).
+			Return([]*models.Delivery{second}, nil).Once()
+		mockRepository.On("ClaimDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 1).
+			Return([]*models.Delivery{}, nil).Once()
+		mockRepository.On("FindById", mock.Anything, webhook.ID.Hex()).Return(webhook, nil).Once()
+		mockRepository.On("UpdateDelivery", mock.Anything, first, &now).Return(ErrDefault).Once()
+		mockRepository.On("UpdateDelivery", mock.Anything, second, &now).Return(nil).Once()
+
+		attempted, err := service.Dispatch(context.Background())
+
+		assert.ErrorIs(t, err, ErrDefault)
+		assert.Equal(t, 2, attempted)
+		assert.Len(t, target.requests, 2)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("success when the claim was lost", func(t *testing.T) {
+		target := &receiver{status: http.StatusOK}
+		server := httptest.NewServer(target)
+		defer server.Close()
+
+		webhook := &models.Webhook{ID: primitive.NewObjectID(), URL: server.URL}
+		now := time.Now()
+		delivery := &models.Delivery{ID: primitive.NewObjectID(), WebhookID: webhook.ID, NextAttemptAt: &now}
+
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		mockRepository.On("ClaimDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 1).
+			Return([]*models.Delivery{delivery}, nil).Once()
+		mockRepository.On("ClaimDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 1).
+			Return([]*models.Delivery{}, nil).Once()
+		mockRepository.On("FindById", mock.Anything, webhook.ID.Hex()).Return(webhook, nil)
+		mockRepository.On("UpdateDelivery", mock.Anything, delivery, &now).
+			Return(models.NewError(models.ErrConflict, "WebhookRepository.UpdateDelivery", delivery.ID.Hex(), nil))
+
+		attempted, err := service.Dispatch(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		mockRepository.AssertExpectations(t)
+	})
+
+	t.Run("error when claim", func(t *testing.T) {
+		mockRepository := new(mockRepositories.WebhookRepository)
+		service := services.NewWebhookService(mockRepository, webhookConfig)
+
+		mockRepository.On("ClaimDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 1).
+			Return(nil, ErrDefault)
+
+		attempted, err := service.Dispatch(context.Background())
+
+		assert.Error(t, err)
+		assert.Zero(t, attempted)
+	})
+}
@@ -1,6 +1,7 @@
 package utils_test
 
 import (
+	"context"
 	"errors"
 	"go-distributed-tracing/utils"
 	"os"
@@ -18,3 +19,15 @@ func TestCaptureError(t *testing.T) {
 		utils.CaptureError(errors.New("error"))
 	})
 }
+
+func TestCaptureErrorContext(t *testing.T) {
+	t.Run("when enabled", func(t *testing.T) {
+		os.Setenv("ENABLE_SENTRY_LOG", "true")
+		utils.CaptureErrorContext(context.Background(), errors.New("error"))
+	})
+
+	t.Run("when disabled", func(t *testing.T) {
+		os.Setenv("ENABLE_SENTRY_LOG", "false")
+		utils.CaptureErrorContext(context.Background(), errors.New("error"))
+	})
+}
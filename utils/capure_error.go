@@ -1,15 +1,26 @@
 package utils
 
 import (
+	"context"
 	"os"
 
 	"github.com/getsentry/sentry-go"
-	"github.com/sirupsen/logrus"
+
+	"go-distributed-tracing/pkg/log"
 )
 
 func CaptureError(err error) {
+	CaptureErrorContext(context.Background(), err)
+}
+
+// CaptureErrorContext - capture error with the request context so the log line carries its trace context
+func CaptureErrorContext(ctx context.Context, err error) {
 	if os.Getenv("ENABLE_SENTRY_LOG") == "true" {
-		sentry.CaptureException(err)
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub()
+		}
+		hub.CaptureException(err)
 	}
-	logrus.Error(err)
+	log.FromContext(ctx).WithError(err).Error(err)
 }
@@ -1,11 +1,11 @@
 package utils
 
 import (
-	"fmt"
 	"go-distributed-tracing/utils"
 	"net/http"
 
 	"github.com/go-chi/render"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // H is a shortcut for map[string]interface{}
@@ -16,57 +16,130 @@ type ResponseSuccessList struct {
 	Meta *Meta       `json:"meta"`
 }
 
+// Meta - pagination of a list, page is left out when paging with cursors and the counts when the total is skipped
 type Meta struct {
-	PerPage     int `json:"per_page"`
-	CurrentPage int `json:"page"`
-	TotalPage   int `json:"page_count"`
-	TotalData   int `json:"total_count"`
+	PerPage     int    `json:"per_page"`
+	CurrentPage int    `json:"page,omitempty"`
+	TotalPage   *int   `json:"page_count,omitempty"`
+	TotalData   *int   `json:"total_count,omitempty"`
+	NextCursor  string `json:"next_cursor,omitempty"`
+	PrevCursor  string `json:"prev_cursor,omitempty"`
 }
 
 type ResponseSuccess struct {
 	Data interface{} `json:"data"`
 }
 
+// withTraceID - add the trace id of the request to an error body so it can be looked up in Jaeger
+func withTraceID(r *http.Request, body H) H {
+	spanContext := trace.SpanContextFromContext(r.Context())
+	if spanContext.IsValid() {
+		body["trace_id"] = spanContext.TraceID().String()
+	}
+
+	return body
+}
+
 func ResponseErrorValidation(w http.ResponseWriter, r *http.Request, err error) {
 	render.Status(r, http.StatusBadRequest)
-	render.JSON(w, r, H{
+	render.JSON(w, r, withTraceID(r, H{
 		"success": false,
 		"code":    http.StatusBadRequest,
 		"message": "Validation errors in your request",
 		"errors":  utils.ValidatonError(err).Errors,
-	})
+	}))
 }
 
 func ResponseBodyError(w http.ResponseWriter, r *http.Request, err error) {
 	render.Status(r, http.StatusBadRequest)
-	render.JSON(w, r, H{
+	render.JSON(w, r, withTraceID(r, H{
 		"success": false,
 		"code":    http.StatusBadRequest,
 		"message": "Validation errors in your request",
 		"error":   "Check your body request",
-	})
+	}))
 }
 
 // ResponseError - send response error (500)
 func ResponseError(w http.ResponseWriter, r *http.Request, err error) {
-	utils.CaptureError(err)
+	utils.CaptureErrorContext(r.Context(), err)
 
 	render.Status(r, http.StatusInternalServerError)
-	render.JSON(w, r, H{
+	render.JSON(w, r, withTraceID(r, H{
 		"success": false,
 		"code":    http.StatusInternalServerError,
 		"message": "There is something error",
-	})
+	}))
 }
 
 // ResponseNotFound - send response not found (404)
 func ResponseNotFound(w http.ResponseWriter, r *http.Request, message string) {
 	render.Status(r, http.StatusNotFound)
-	render.JSON(w, r, H{
+	render.JSON(w, r, withTraceID(r, H{
 		"success": false,
 		"code":    http.StatusNotFound,
 		"message": message,
-	})
+	}))
+}
+
+// ResponseBadRequest - send response bad request (400)
+func ResponseBadRequest(w http.ResponseWriter, r *http.Request, message string) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, withTraceID(r, H{
+		"success": false,
+		"code":    http.StatusBadRequest,
+		"message": message,
+	}))
+}
+
+// ResponseConflict - send response conflict (409)
+func ResponseConflict(w http.ResponseWriter, r *http.Request, message string) {
+	render.Status(r, http.StatusConflict)
+	render.JSON(w, r, withTraceID(r, H{
+		"success": false,
+		"code":    http.StatusConflict,
+		"message": message,
+	}))
+}
+
+// ResponsePreconditionFailed - send response precondition failed (412)
+func ResponsePreconditionFailed(w http.ResponseWriter, r *http.Request, message string) {
+	render.Status(r, http.StatusPreconditionFailed)
+	render.JSON(w, r, withTraceID(r, H{
+		"success": false,
+		"code":    http.StatusPreconditionFailed,
+		"message": message,
+	}))
+}
+
+// ResponseRequestEntityTooLarge - send response request entity too large (413)
+func ResponseRequestEntityTooLarge(w http.ResponseWriter, r *http.Request, message string) {
+	render.Status(r, http.StatusRequestEntityTooLarge)
+	render.JSON(w, r, withTraceID(r, H{
+		"success": false,
+		"code":    http.StatusRequestEntityTooLarge,
+		"message": message,
+	}))
+}
+
+// ResponseUnsupportedMediaType - send response unsupported media type (415)
+func ResponseUnsupportedMediaType(w http.ResponseWriter, r *http.Request, message string) {
+	render.Status(r, http.StatusUnsupportedMediaType)
+	render.JSON(w, r, withTraceID(r, H{
+		"success": false,
+		"code":    http.StatusUnsupportedMediaType,
+		"message": message,
+	}))
+}
+
+// ResponseUnprocessableEntity - send response unprocessable entity (422)
+func ResponseUnprocessableEntity(w http.ResponseWriter, r *http.Request, message string) {
+	render.Status(r, http.StatusUnprocessableEntity)
+	render.JSON(w, r, withTraceID(r, H{
+		"success": false,
+		"code":    http.StatusUnprocessableEntity,
+		"message": message,
+	}))
 }
 
 func ResponseCreated(w http.ResponseWriter, r *http.Request, data *ResponseSuccess) {
@@ -103,10 +176,20 @@ func ResponseOKList(w http.ResponseWriter, r *http.Request, data *ResponseSucces
 func ResponseInternalServerError(w http.ResponseWriter, r *http.Request, err error) {
 	render.Status(r, http.StatusOK)
 
-	fmt.Println(err)
-	render.JSON(w, r, H{
+	utils.CaptureErrorContext(r.Context(), err)
+	render.JSON(w, r, withTraceID(r, H{
 		"success": false,
 		"code":    http.StatusInternalServerError,
 		"message": "Internal server error",
-	})
+	}))
+}
+
+// ResponseMultiStatus - send the results of a request made of several operations, some of which failed (207)
+func ResponseMultiStatus(w http.ResponseWriter, r *http.Request, data *ResponseSuccess) {
+	render.Status(r, http.StatusMultiStatus)
+	render.JSON(w, r, withTraceID(r, H{
+		"success": false,
+		"code":    http.StatusMultiStatus,
+		"data":    data.Data,
+	}))
 }
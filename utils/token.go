@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidToken - the token was not made by SignToken with the current secret
+var ErrInvalidToken = errors.New("invalid token")
+
+var (
+	tokenSecret     []byte
+	tokenSecretOnce sync.Once
+)
+
+// secret - TOKEN_SECRET, or a random secret when unset so tokens only outlive the process if it is configured
+func secret() []byte {
+	tokenSecretOnce.Do(func() {
+		if value := os.Getenv("TOKEN_SECRET"); value != "" {
+			tokenSecret = []byte(value)
+			return
+		}
+
+		tokenSecret = make([]byte, 32)
+		if _, err := rand.Read(tokenSecret); err != nil {
+			panic(err)
+		}
+	})
+
+	return tokenSecret
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, secret())
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignToken - opaque URL safe token holding value as JSON, signed so clients can't forge it
+func SignToken(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + sign(payload), nil
+}
+
+// VerifyToken - check the signature of a SignToken token and decode its value
+func VerifyToken(token string, value interface{}) error {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(sign(payload))) {
+		return ErrInvalidToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"go-distributed-tracing/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignToken(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	token, err := utils.SignToken(payload{Name: "value"})
+	assert.NoError(t, err)
+
+	t.Run("verify", func(t *testing.T) {
+		var value payload
+		assert.NoError(t, utils.VerifyToken(token, &value))
+		assert.Equal(t, "value", value.Name)
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		forged, err := utils.SignToken(payload{Name: "other"})
+		assert.NoError(t, err)
+
+		tampered := strings.SplitN(forged, ".", 2)[0] + "." + strings.SplitN(token, ".", 2)[1]
+		var value payload
+		assert.ErrorIs(t, utils.VerifyToken(tampered, &value), utils.ErrInvalidToken)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var value payload
+		assert.ErrorIs(t, utils.VerifyToken("", &value), utils.ErrInvalidToken)
+		assert.ErrorIs(t, utils.VerifyToken("not a token", &value), utils.ErrInvalidToken)
+	})
+}
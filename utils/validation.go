@@ -2,8 +2,10 @@ package utils
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/iancoleman/strcase"
@@ -31,6 +33,9 @@ func ValidatonError(err error) CommonError {
 		switch v.Tag() {
 		case "required":
 			res.Errors[field] = fmt.Sprintf("%v is %v", field, v.Tag())
+		case "required_unless":
+			param := strings.Fields(v.Param())
+			res.Errors[field] = fmt.Sprintf("%v is required unless %v is %v", field, strcase.ToSnake(param[0]), strings.Join(param[1:], " or "))
 		case "sinteger":
 			res.Errors[field] = fmt.Sprintf("%v is number only", field)
 		case "sgte":
@@ -41,10 +46,22 @@ func ValidatonError(err error) CommonError {
 			res.Errors[field] = fmt.Sprintf("%v must less than %v character", field, v.Param())
 		case "min":
 			res.Errors[field] = fmt.Sprintf("%v must higher than %v character", field, v.Param())
+		case "oneof":
+			res.Errors[field] = fmt.Sprintf("%v must be one of %v", field, v.Param())
+		case "boolean":
+			res.Errors[field] = fmt.Sprintf("%v must be true or false", field)
+		case "datetime":
+			res.Errors[field] = fmt.Sprintf("%v must be a date in the %v format", field, v.Param())
+		case "sortby":
+			res.Errors[field] = fmt.Sprintf("%v must be a comma separated list of %v, prefixed with - for descending order", field, strings.Join(strings.Fields(v.Param()), ", "))
+		case "excluded_with":
+			res.Errors[field] = fmt.Sprintf("%v can't be used with %v", field, strcase.ToSnake(v.Param()))
 		case "email":
 			res.Errors[field] = fmt.Sprintf("%v is not a valid email address", v.Value())
 		case "username":
 			res.Errors[field] = fmt.Sprintf("%v is not a valid username", v.Value())
+		case "httpurl":
+			res.Errors[field] = fmt.Sprintf("%v must be an http or https URL", field)
 		}
 	}
 
@@ -57,6 +74,8 @@ func ValidateStruct(i interface{}) error {
 	validate.RegisterValidation("sgte", GreaterThanEqual)
 	validate.RegisterValidation("slte", LessThanEqual)
 	validate.RegisterValidation("username", Username)
+	validate.RegisterValidation("sortby", SortBy)
+	validate.RegisterValidation("httpurl", HTTPURL)
 
 	err := validate.Struct(i)
 	if err != nil {
@@ -147,3 +166,42 @@ func Username(fl validator.FieldLevel) bool {
 	var regex = regexp.MustCompile(`^[A-Za-z0-9]+(?:[_-][A-Za-z0-9]+)*$`)
 	return regex.MatchString(fl.Field().String())
 }
+
+// SortBy - comma separated fields of the space separated param, each optionally prefixed with - and used once
+func SortBy(fl validator.FieldLevel) bool {
+	// If empty skip
+	if fl.Field().String() == "" {
+		return true
+	}
+
+	allowed := map[string]bool{}
+	for _, field := range strings.Fields(fl.Param()) {
+		allowed[field] = true
+	}
+
+	seen := map[string]bool{}
+	for _, field := range strings.Split(fl.Field().String(), ",") {
+		field = strings.TrimPrefix(strings.TrimSpace(field), "-")
+		if !allowed[field] || seen[field] {
+			return false
+		}
+		seen[field] = true
+	}
+
+	return true
+}
+
+// HTTPURL - absolute http or https URL with a host
+func HTTPURL(fl validator.FieldLevel) bool {
+	// If empty skip
+	if fl.Field().String() == "" {
+		return true
+	}
+
+	u, err := url.Parse(fl.Field().String())
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}